@@ -52,6 +52,13 @@ func BenchmarkContextInvoke(B *testing.B) {
 	}
 }
 
+func BenchmarkHelloHandle(B *testing.B) {
+	SetMode(TestMode)
+	router := New()
+	router.GET("/ping", Handle(func(*Context, struct{}) (string, error) { return "hello", nil }))
+	runRequest(B, router, "GET", "/ping")
+}
+
 func BenchmarkHelloHandlerFunc(B *testing.B) {
 	SetMode(TestMode)
 	router := New()
@@ -1,8 +1,6 @@
 package bind
 
 import (
-	"encoding/json"
-	"encoding/xml"
 	"errors"
 	"net/http"
 	"net/url"
@@ -19,6 +17,13 @@ const (
 	MIMEPlain             = "text/plain"
 	MIMEPOSTForm          = "application/x-www-form-urlencoded"
 	MIMEMultipartPOSTForm = "multipart/form-data"
+	MIMEYAML              = "application/x-yaml"
+	MIMEYAML2             = "application/yaml"
+	MIMEMsgPack           = "application/x-msgpack"
+	MIMEMsgPack2          = "application/msgpack"
+	MIMEProtoJSON         = "application/x-protobuf+json"
+	MIMEProtoBuf          = "application/x-protobuf"
+	MIMECSV               = "text/csv"
 )
 
 // Like Bind, Create a struct or structPtr  by Type t.
@@ -30,12 +35,12 @@ func GetType(t reflect.Type, r *http.Request, params map[string][]string) (refle
 		if err := bindWithParams(r, rv.Addr().Interface(), params); err != nil {
 			return reflect.Value{}, err
 		}
-		return rv, DefaultValidator.Struct(rv.Addr().Interface())
+		return rv, validate(rv.Addr().Interface(), tagNameOf(contentType(r)))
 	case t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct:
 		if err := bindWithParams(r, rv.Interface(), params); err != nil {
 			return reflect.Value{}, err
 		}
-		return rv, DefaultValidator.Struct(rv.Interface())
+		return rv, validate(rv.Interface(), tagNameOf(contentType(r)))
 	default:
 		panic("expect struct or struct pointer, got " + t.String())
 	}
@@ -54,47 +59,36 @@ func Bind(ptr interface{}, r *http.Request, params map[string][]string) error {
 		return err
 	}
 
-	return DefaultValidator.Struct(ptr)
+	return validate(ptr, tagNameOf(contentType(r)))
 }
 
 // Just bind value from http.Request and parameters.
 func bindWithParams(r *http.Request, ptr interface{}, params map[string][]string) error {
 	//bind values from http.Request
-	mime := GetContentType(r.Header)
-	if r.Method == "GET" || r.Method == "DELETE" {
-		mime = MIMEPOSTForm
-	}
-
+	mime := contentType(r)
 	switch mime {
-	case MIMEPOSTForm, MIMEMultipartPOSTForm:
-		err := r.ParseForm()
-		if err != nil {
+	case MIMEPOSTForm:
+		if err := r.ParseForm(); err != nil {
 			return err
 		}
-		// you'd better to call this by your self
-		//r.ParseMultipartForm(32 << 10) // 32 MB
-
-		//combine form and params
-		if len(r.Form) > 0 && params == nil {
-			params = make(map[string][]string, len(r.Form))
-		}
-		for k, v := range r.Form {
-			params[k] = v
-		}
-		return reflectx.FormToStruct(params, ptr)
-	case MIMEJSON:
-		decoder := json.NewDecoder(r.Body)
-		if err := decoder.Decode(ptr); err != nil {
+		return reflectx.FormToStruct(combine(params, r.Form), ptr)
+	case MIMEMultipartPOSTForm:
+		if err := r.ParseMultipartForm(DefaultMaxMemory); err != nil {
 			return err
 		}
-	case MIMEXML, MIMEXML2:
-		decoder := xml.NewDecoder(r.Body)
-		if err := decoder.Decode(ptr); err != nil {
+		if err := bindFiles(r.MultipartForm, ptr); err != nil {
 			return err
 		}
-	default:
+		return reflectx.FormToStruct(combine(params, r.Form), ptr)
+	}
+
+	decode, ok := decoders[mime]
+	if !ok {
 		return errors.New("context type not support: " + mime)
 	}
+	if err := decode(r.Body, ptr); err != nil {
+		return err
+	}
 
 	//bind params
 	if len(params) > 0 {
@@ -103,6 +97,25 @@ func bindWithParams(r *http.Request, ptr interface{}, params map[string][]string
 	return nil
 }
 
+// contentType returns the content type of r, GET and DELETE are bound by form.
+func contentType(r *http.Request) string {
+	if r.Method == "GET" || r.Method == "DELETE" {
+		return MIMEPOSTForm
+	}
+	return GetContentType(r.Header)
+}
+
+// combine form and params, the values of params are overwritten.
+func combine(params, form map[string][]string) map[string][]string {
+	if len(form) > 0 && params == nil {
+		params = make(map[string][]string, len(form))
+	}
+	for k, v := range form {
+		params[k] = v
+	}
+	return params
+}
+
 func GetContentType(h http.Header) string {
 	content := url.Values(h).Get("Content-Type")
 	for i, char := range content {
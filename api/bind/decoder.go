@@ -0,0 +1,72 @@
+package bind
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v2"
+)
+
+// Decoder decodes the request body into ptr.
+type Decoder func(body io.Reader, ptr interface{}) error
+
+// decoders of request body by content type.
+var decoders = map[string]Decoder{
+	MIMEJSON:      decodeJSON,
+	MIMEXML:       decodeXML,
+	MIMEXML2:      decodeXML,
+	MIMEYAML:      decodeYAML,
+	MIMEYAML2:     decodeYAML,
+	MIMEMsgPack:   decodeMsgPack,
+	MIMEMsgPack2:  decodeMsgPack,
+	MIMEProtoJSON: decodeProtoJSON,
+}
+
+// RegisterDecoder registers a decoder of request body for the content type,
+// it replaces the existing one of the same content type.
+// Not thread-safe.
+func RegisterDecoder(mime string, d Decoder) {
+	if d == nil {
+		panic("bind: decoder can not be nil")
+	}
+	decoders[mime] = d
+}
+
+func decodeJSON(body io.Reader, ptr interface{}) error {
+	// protobuf messages are decoded with the protobuf JSON mapping.
+	if _, ok := ptr.(proto.Message); ok {
+		return decodeProtoJSON(body, ptr)
+	}
+	return json.NewDecoder(body).Decode(ptr)
+}
+
+func decodeXML(body io.Reader, ptr interface{}) error {
+	return xml.NewDecoder(body).Decode(ptr)
+}
+
+func decodeYAML(body io.Reader, ptr interface{}) error {
+	return yaml.NewDecoder(body).Decode(ptr)
+}
+
+func decodeMsgPack(body io.Reader, ptr interface{}) error {
+	return codec.NewDecoder(body, new(codec.MsgpackHandle)).Decode(ptr)
+}
+
+func decodeProtoJSON(body io.Reader, ptr interface{}) error {
+	msg, ok := ptr.(proto.Message)
+	if !ok {
+		return errors.New("bind: " + reflect.TypeOf(ptr).String() + " is not a proto.Message")
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(b, msg)
+}
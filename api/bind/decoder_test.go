@@ -0,0 +1,78 @@
+package bind
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestDecoders(t *testing.T) {
+	newRequest := func(mime string, body []byte) *http.Request {
+		req, _ := http.NewRequest("POST", "/", bytes.NewReader(body))
+		req.Header.Set("Content-Type", mime+"; charset=utf-8")
+		return req
+	}
+
+	Convey("Bind by registered decoders", t, func() {
+		Convey("by xml", func() {
+			var fb FooBar
+			req := newRequest(MIMEXML, []byte("<FooBar><Foo>ping</Foo><Bar>pong</Bar></FooBar>"))
+			So(Bind(&fb, req, nil), ShouldBeNil)
+			So(fb.Alice+fb.Foo+fb.Bar, ShouldEqual, "alicepingpong")
+		})
+
+		Convey("by yaml", func() {
+			var fb FooBar
+			req := newRequest(MIMEYAML2, []byte("foo: ping\nbar: pong\n"))
+			So(Bind(&fb, req, nil), ShouldBeNil)
+			So(fb.Alice+fb.Foo+fb.Bar, ShouldEqual, "alicepingpong")
+		})
+
+		Convey("by msgpack", func() {
+			var buf bytes.Buffer
+			err := codec.NewEncoder(&buf, new(codec.MsgpackHandle)).Encode(FooBar{Foo: "ping", Bar: "pong"})
+			So(err, ShouldBeNil)
+
+			v, err := GetType(reflect.TypeOf(FooBar{}), newRequest(MIMEMsgPack, buf.Bytes()), nil)
+			So(err, ShouldBeNil)
+			So(v.Interface().(FooBar).Foo, ShouldEqual, "ping")
+		})
+
+		Convey("by protobuf json", func() {
+			msg := new(wrapperspb.StringValue)
+			So(Bind(msg, newRequest(MIMEProtoJSON, []byte(`"ping"`)), nil), ShouldBeNil)
+			So(msg.Value, ShouldEqual, "ping")
+
+			// proto.Message in application/json
+			msg = new(wrapperspb.StringValue)
+			So(Bind(msg, newRequest(MIMEJSON, []byte(`"pong"`)), nil), ShouldBeNil)
+			So(msg.Value, ShouldEqual, "pong")
+
+			var fb FooBar
+			So(Bind(&fb, newRequest(MIMEProtoJSON, []byte(`{}`)), nil), ShouldNotBeNil)
+		})
+
+		Convey("by custom decoder", func() {
+			RegisterDecoder("text/x-foo", func(body io.Reader, ptr interface{}) error {
+				ptr.(*FooBar).Foo, ptr.(*FooBar).Bar = "foo", "bar"
+				return nil
+			})
+			defer delete(decoders, "text/x-foo")
+
+			var fb FooBar
+			So(Bind(&fb, newRequest("text/x-foo", nil), nil), ShouldBeNil)
+			So(fb.Foo, ShouldEqual, "foo")
+		})
+
+		Convey("content type not support", func() {
+			var fb FooBar
+			So(Bind(&fb, newRequest("text/x-bar", nil), nil), ShouldNotBeNil)
+		})
+	})
+}
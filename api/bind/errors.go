@@ -0,0 +1,246 @@
+package bind
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/zltgo/reflectx"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// FieldError describes a field failed on a validation rule.
+type FieldError struct {
+	// Field is the path of the field by the names of the 'form' or 'json' tag,
+	// depends on how the request is bound, such as "owner.email" and "files[1]".
+	Field string `json:"field"`
+	// Rule is the failed tag of 'validate' or 'file', such as "required" and "max".
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors is returned by Bind, GetType and Validate if validation failed.
+type ValidationErrors []*FieldError
+
+func (ve ValidationErrors) Error() string {
+	msgs := make([]string, len(ve))
+	for i, fe := range ve {
+		msgs[i] = fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Translate returns a copy of ve with messages in the catalog of locale,
+// see RegisterCatalog.
+func (ve ValidationErrors) Translate(locale string) ValidationErrors {
+	rv := make(ValidationErrors, len(ve))
+	for i, fe := range ve {
+		rv[i] = &FieldError{
+			Field:   fe.Field,
+			Rule:    fe.Rule,
+			Param:   fe.Param,
+			Message: Message(locale, fe.Field, fe.Rule, fe.Param),
+		}
+	}
+	return rv
+}
+
+func newFieldError(field, rule, param string) *FieldError {
+	return &FieldError{
+		Field:   field,
+		Rule:    rule,
+		Param:   param,
+		Message: Message(DefaultLocale, field, rule, param),
+	}
+}
+
+// convert validator.ValidationErrors of the struct type t to ValidationErrors,
+// the names of fields are mapped by tagName. Other errors are returned as they are.
+func toValidationErrors(err error, t reflect.Type, tagName string) error {
+	errs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	ve := make(ValidationErrors, len(errs))
+	for i, fe := range errs {
+		ve[i] = newFieldError(fieldPath(t, fe.StructNamespace(), tagName), fe.Tag(), fe.Param())
+	}
+	return ve
+}
+
+// fieldPath converts the namespace of Go names like "Foo.Bar.Files[1]" to the
+// path of tag names like "bar.files[1]". Embedded structs without tags are flattened.
+func fieldPath(t reflect.Type, ns string, tagName string) string {
+	segs := strings.Split(ns, ".")[1:]
+	path := make([]string, 0, len(segs))
+	for i, seg := range segs {
+		t = reflectx.Deref(t)
+		name, index := seg, ""
+		if j := strings.IndexByte(seg, '['); j >= 0 {
+			name, index = seg[:j], seg[j:]
+		}
+
+		var f reflect.StructField
+		var ok bool
+		if t.Kind() == reflect.Struct {
+			f, ok = t.FieldByName(name)
+		}
+		if !ok {
+			// unknown field, keep the rest as it is.
+			return strings.Join(append(path, segs[i:]...), ".")
+		}
+
+		t = f.Type
+		for n := strings.Count(index, "["); n > 0; n-- {
+			t = reflectx.Deref(t).Elem()
+		}
+
+		tag, _ := reflectx.StdTagFunc(f.Name, f.Tag.Get(tagName))
+		if tag == reflectx.IgnoreThisField {
+			tag = f.Name
+		}
+		if f.Anonymous && tag == f.Name {
+			continue
+		}
+		path = append(path, tag+index)
+	}
+	return strings.Join(path, ".")
+}
+
+// tagNameOf returns the tag name of fields bound from the content type.
+func tagNameOf(mime string) string {
+	switch mime {
+	case MIMEPOSTForm, MIMEMultipartPOSTForm:
+		return "form"
+	}
+	return "json"
+}
+
+/****************message catalogs**********************/
+
+// Catalog maps validation rules to message templates,
+// "{field}", "{rule}" and "{param}" in templates are replaced.
+// The template of "" is used for the rules not in the catalog.
+type Catalog map[string]string
+
+// DefaultLocale is the locale of messages created by Bind, GetType and Validate.
+var DefaultLocale = "en"
+
+var catalogs = map[string]Catalog{
+	"en": {
+		"":          "{field} failed on the '{rule}' rule",
+		"required":  "{field} is required",
+		"len":       "{field} must be {param} in length",
+		"min":       "{field} must be at least {param}",
+		"max":       "{field} must be at most {param}",
+		"eq":        "{field} must be equal to {param}",
+		"ne":        "{field} must not be equal to {param}",
+		"gt":        "{field} must be greater than {param}",
+		"gte":       "{field} must be at least {param}",
+		"lt":        "{field} must be less than {param}",
+		"lte":       "{field} must be at most {param}",
+		"oneof":     "{field} must be one of [{param}]",
+		"email":     "{field} must be a valid email address",
+		"url":       "{field} must be a valid URL",
+		"uuid":      "{field} must be a valid UUID",
+		"alpha":     "{field} can only contain letters",
+		"alphanum":  "{field} can only contain letters and numbers",
+		"numeric":   "{field} must be a valid number",
+		"number":    "{field} must be a valid number",
+		"chinese":   "{field} can only contain Chinese characters",
+		"name":      "{field} can only contain Chinese characters, letters, numbers and underscores",
+		"path":      "{field} must be a valid path",
+		"moblie":    "{field} must be a valid mobile phone number",
+		"telephone": "{field} must be a valid telephone number",
+		"mime":      "{field} must be one of the media types [{param}]",
+	},
+	"zh": {
+		"":          "{field}未通过'{rule}'校验",
+		"required":  "{field}为必填字段",
+		"len":       "{field}长度必须为{param}",
+		"min":       "{field}最小为{param}",
+		"max":       "{field}最大为{param}",
+		"eq":        "{field}必须等于{param}",
+		"ne":        "{field}不能等于{param}",
+		"gt":        "{field}必须大于{param}",
+		"gte":       "{field}最小为{param}",
+		"lt":        "{field}必须小于{param}",
+		"lte":       "{field}最大为{param}",
+		"oneof":     "{field}必须是[{param}]中的一个",
+		"email":     "{field}必须是有效的邮箱地址",
+		"url":       "{field}必须是有效的URL",
+		"uuid":      "{field}必须是有效的UUID",
+		"alpha":     "{field}只能包含字母",
+		"alphanum":  "{field}只能包含字母和数字",
+		"numeric":   "{field}必须是有效的数值",
+		"number":    "{field}必须是有效的数值",
+		"chinese":   "{field}只能包含汉字",
+		"name":      "{field}只能包含汉字、字母、数字和下划线",
+		"path":      "{field}必须是有效的路径",
+		"moblie":    "{field}必须是有效的手机号码",
+		"telephone": "{field}必须是有效的电话号码",
+		"mime":      "{field}的文件类型必须是[{param}]中的一个",
+	},
+}
+
+// RegisterCatalog adds the messages of c to the catalog of locale,
+// the existing messages of the same rules are replaced.
+// Not thread-safe, Message reads the catalogs without locks.
+func RegisterCatalog(locale string, c Catalog) {
+	locale = strings.ToLower(locale)
+	if catalogs[locale] == nil {
+		catalogs[locale] = make(Catalog, len(c))
+	}
+	for rule, tmpl := range c {
+		catalogs[locale][rule] = tmpl
+	}
+}
+
+// Message returns the message of the rule in the catalog of locale,
+// the catalog of DefaultLocale is used if the rule is not found.
+// The generic message of rule "" is used only if neither catalog has the rule.
+func Message(locale, field, rule, param string) string {
+	cs := []Catalog{catalogs[strings.ToLower(locale)], catalogs[DefaultLocale]}
+	var tmpl string
+lookup:
+	for _, key := range []string{rule, ""} {
+		for _, c := range cs {
+			if t, ok := c[key]; ok {
+				tmpl = t
+				break lookup
+			}
+		}
+	}
+	return strings.NewReplacer("{field}", field, "{rule}", rule, "{param}", param).Replace(tmpl)
+}
+
+// MatchLocale returns the registered locale accepted by the Accept-Language
+// header with the highest quality, "zh-CN" matches the catalog of "zh".
+// It returns DefaultLocale if nothing matches.
+func MatchLocale(acceptLanguage string) string {
+	best, bestQ := DefaultLocale, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, q := part, 1.0
+		if i := strings.IndexByte(part, ';'); i >= 0 {
+			tag = part[:i]
+			if param := strings.TrimSpace(part[i+1:]); strings.HasPrefix(param, "q=") {
+				q, _ = strconv.ParseFloat(param[2:], 64)
+			}
+		}
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if q <= bestQ {
+			continue
+		}
+
+		if _, ok := catalogs[tag]; ok {
+			best, bestQ = tag, q
+		} else if i := strings.IndexByte(tag, '-'); i > 0 {
+			if _, ok := catalogs[tag[:i]]; ok {
+				best, bestQ = tag[:i], q
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,101 @@
+package bind
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type Inner struct {
+	Email string `json:"email" form:"mail" validate:"required,email"`
+}
+
+type Outer struct {
+	Inner
+	Name  string   `json:"name" form:"user_name" validate:"required,max=4"`
+	Owner *Inner   `json:"owner" form:"owner" validate:"required"`
+	Tags  []string `json:"tags" validate:"dive,alpha"`
+}
+
+func TestValidationErrors(t *testing.T) {
+	Convey("Validation errors with tag names", t, func() {
+		Convey("by json", func() {
+			body := `{"email":"bob","name":"alice","owner":{},"tags":["a","1"]}`
+			req, _ := http.NewRequest("POST", "/", bytes.NewReader([]byte(body)))
+			req.Header.Set("Content-Type", MIMEJSON)
+
+			var o Outer
+			err := Bind(&o, req, nil)
+			So(IsValidationError(err), ShouldBeTrue)
+			So(err, ShouldResemble, ValidationErrors{
+				{Field: "email", Rule: "email", Message: "email must be a valid email address"},
+				{Field: "name", Rule: "max", Param: "4", Message: "name must be at most 4"},
+				{Field: "owner.email", Rule: "required", Message: "owner.email is required"},
+				{Field: "tags[1]", Rule: "alpha", Message: "tags[1] can only contain letters"},
+			})
+		})
+
+		Convey("by form", func() {
+			req, _ := http.NewRequest("GET", "/?mail=bob&user_name=alice", nil)
+
+			var o Outer
+			err := Bind(&o, req, nil)
+			ve, ok := err.(ValidationErrors)
+			So(ok, ShouldBeTrue)
+			So(ve, ShouldHaveLength, 3)
+			So(ve[0].Field, ShouldEqual, "mail")
+			So(ve[1].Field, ShouldEqual, "user_name")
+			So(ve[2].Field, ShouldEqual, "owner")
+			So(ve.Error(), ShouldEqual, "mail must be a valid email address; user_name must be at most 4; owner is required")
+		})
+
+		Convey("by Validate", func() {
+			err := Validate(&Inner{})
+			So(err, ShouldResemble, ValidationErrors{{Field: "email", Rule: "required", Message: "email is required"}})
+			So(Validate(&Inner{Email: "bob@example.com"}), ShouldBeNil)
+		})
+	})
+}
+
+func TestTranslate(t *testing.T) {
+	Convey("Translate messages", t, func() {
+		ve := ValidationErrors{
+			newFieldError("name", "required", ""),
+			newFieldError("age", "gte", "18"),
+			newFieldError("code", "unknown", ""),
+		}
+
+		zh := ve.Translate("zh")
+		So(zh[0].Message, ShouldEqual, "name为必填字段")
+		So(zh[1].Message, ShouldEqual, "age最小为18")
+		So(zh[2].Message, ShouldEqual, "code未通过'unknown'校验")
+		So(ve[0].Message, ShouldEqual, "name is required")
+
+		RegisterCatalog("ja", Catalog{"required": "{field}は必須です"})
+		defer delete(catalogs, "ja")
+		ja := ve.Translate("JA")
+		So(ja[0].Message, ShouldEqual, "nameは必須です")
+		// fall back to the default locale
+		So(ja[1].Message, ShouldEqual, "age must be at least 18")
+
+		// the rule of the default locale comes before the generic message
+		RegisterCatalog("ja", Catalog{"": "{field}が無効です"})
+		ja = ve.Translate("ja")
+		So(ja[1].Message, ShouldEqual, "age must be at least 18")
+		So(ja[2].Message, ShouldEqual, "codeが無効です")
+
+		So(ve.Translate("fr")[0].Message, ShouldEqual, "name is required")
+	})
+}
+
+func TestMatchLocale(t *testing.T) {
+	Convey("Match locales by Accept-Language", t, func() {
+		So(MatchLocale(""), ShouldEqual, "en")
+		So(MatchLocale("zh-CN,zh;q=0.9,en;q=0.8"), ShouldEqual, "zh")
+		So(MatchLocale("fr, en;q=0.5, zh;q=0.6"), ShouldEqual, "zh")
+		So(MatchLocale("en-US;q=0.9, zh-TW;q=0.8"), ShouldEqual, "en")
+		So(MatchLocale("fr"), ShouldEqual, "en")
+	})
+}
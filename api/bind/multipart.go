@@ -0,0 +1,214 @@
+package bind
+
+import (
+	"errors"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zltgo/reflectx"
+)
+
+var (
+	// DefaultMaxMemory is the maxMemory of http.Request.ParseMultipartForm
+	// if the form is not parsed before binding, the rest of files are stored
+	// on disk in temporary files.
+	DefaultMaxMemory int64 = 32 << 20 // 32 MB
+
+	typeFileHeader  = reflect.TypeOf((*multipart.FileHeader)(nil))
+	typeFileHeaders = reflect.TypeOf([]*multipart.FileHeader(nil))
+
+	fileMapper = reflectx.NewMapper("form", nil)
+)
+
+// bindFiles sets the *multipart.FileHeader and []*multipart.FileHeader fields
+// by the form names, and checks them with the 'file' tag, for example:
+//	type Upload struct {
+//		Avatar *multipart.FileHeader   `form:"avatar" file:"max=2MB,mime=image/png image/jpeg"`
+//		Files  []*multipart.FileHeader `form:"file" file:"max=100MB" validate:"required,max=5"`
+//	}
+// "max" is the max size of each file in B, KB, MB or GB, "mime" is a space
+// separated list of media types like "image/*", the media types are detected
+// by http.DetectContentType rather than the Content-Type from client.
+// The tags are parsed once for each type, an error is returned if any of
+// them is malformed.
+func bindFiles(form *multipart.Form, ptr interface{}) error {
+	v := reflect.ValueOf(ptr).Elem()
+	fields, err := fileFieldsOf(v.Type())
+	if err != nil {
+		return err
+	}
+	for _, ff := range fields {
+		fhs := form.File[ff.path]
+		if len(fhs) == 0 {
+			continue
+		}
+		for _, fh := range fhs {
+			if err := ff.check(fh); err != nil {
+				return err
+			}
+		}
+
+		fv := reflectx.FieldByIndexes(v, ff.index)
+		if ff.multiple {
+			fv.Set(reflect.ValueOf(fhs))
+		} else {
+			fv.Set(reflect.ValueOf(fhs[0]))
+		}
+	}
+	return nil
+}
+
+// fileField is a file field of struct with the rules of its 'file' tag.
+type fileField struct {
+	path     string
+	index    []int
+	multiple bool
+	rules    []fileRule
+}
+
+type fileRule struct {
+	name, param string
+	size        int64    // max
+	patterns    []string // mime
+}
+
+type fileFieldsResult struct {
+	fields []fileField
+	err    error
+}
+
+// fileFieldsCache stores the fileFieldsResult of struct types.
+var fileFieldsCache sync.Map // map[reflect.Type]fileFieldsResult
+
+// fileFieldsOf returns the file fields of the struct type t, the 'file' tags
+// are parsed on the first call of t.
+func fileFieldsOf(t reflect.Type) ([]fileField, error) {
+	if r, ok := fileFieldsCache.Load(t); ok {
+		return r.(fileFieldsResult).fields, r.(fileFieldsResult).err
+	}
+
+	var r fileFieldsResult
+	for _, fi := range fileMapper.TypeMap(t).Fields {
+		if fi.Type != typeFileHeader && fi.Type != typeFileHeaders {
+			continue
+		}
+		rules, err := parseFileTag(fi.Path, t.FieldByIndex(fi.Index).Tag.Get("file"))
+		if err != nil {
+			r = fileFieldsResult{err: err}
+			break
+		}
+		r.fields = append(r.fields, fileField{
+			path:     fi.Path,
+			index:    fi.Index,
+			multiple: fi.Type == typeFileHeaders,
+			rules:    rules,
+		})
+	}
+	fileFieldsCache.Store(t, r)
+	return r.fields, r.err
+}
+
+// parseFileTag parses the rules of the 'file' tag of field.
+func parseFileTag(field, tag string) ([]fileRule, error) {
+	var rules []fileRule
+	for _, s := range strings.Split(tag, ",") {
+		rule := fileRule{name: s}
+		if i := strings.IndexByte(s, '='); i >= 0 {
+			rule.name, rule.param = s[:i], s[i+1:]
+		}
+
+		switch rule.name {
+		case "":
+			continue
+		case "max":
+			size, err := parseSize(rule.param)
+			if err != nil {
+				return nil, errors.New("bind: invalid file size " + strconv.Quote(rule.param) + " of field " + field)
+			}
+			rule.size = size
+		case "mime":
+			rule.patterns = strings.Fields(rule.param)
+			if len(rule.patterns) == 0 {
+				return nil, errors.New("bind: empty media types of field " + field)
+			}
+		default:
+			return nil, errors.New("bind: unknown file tag " + strconv.Quote(rule.name) + " of field " + field)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// check checks the size and media type of fh by the rules.
+func (ff *fileField) check(fh *multipart.FileHeader) error {
+	for _, rule := range ff.rules {
+		var ok bool
+		switch rule.name {
+		case "max":
+			ok = fh.Size <= rule.size
+		case "mime":
+			mt, err := detectContentType(fh)
+			if err != nil {
+				return err
+			}
+			for _, pattern := range rule.patterns {
+				if matchMIME(pattern, mt) {
+					ok = true
+					break
+				}
+			}
+		}
+
+		if !ok {
+			return ValidationErrors{newFieldError(ff.path, rule.name, rule.param)}
+		}
+	}
+	return nil
+}
+
+// detectContentType returns the media type of the first 512 bytes of the file.
+func detectContentType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, _ := f.Read(buf)
+	mt, _, err := mime.ParseMediaType(http.DetectContentType(buf[:n]))
+	return mt, err
+}
+
+// matchMIME reports whether mt matches the pattern like "image/png", "image/*" and "*/*".
+func matchMIME(pattern, mt string) bool {
+	if pattern == "*/*" || strings.EqualFold(pattern, mt) {
+		return true
+	}
+	if strings.HasSuffix(pattern, "/*") {
+		return len(mt) > len(pattern)-1 && strings.EqualFold(mt[:len(pattern)-1], pattern[:len(pattern)-1])
+	}
+	return false
+}
+
+// parseSize parses sizes like "512", "512B", "100KB", "2MB" and "1GB".
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	unit := int64(1)
+	for _, u := range []struct {
+		suffix string
+		n      int64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"B", 1}} {
+		if strings.HasSuffix(s, u.suffix) {
+			s, unit = s[:len(s)-len(u.suffix)], u.n
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n * unit, err
+}
@@ -0,0 +1,113 @@
+package bind
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var pngHeader = []byte("\x89PNG\x0D\x0A\x1A\x0A")
+
+type Upload struct {
+	Name   string                  `form:"name" validate:"required"`
+	Avatar *multipart.FileHeader   `form:"avatar" file:"max=1KB,mime=image/png image/jpeg"`
+	Files  []*multipart.FileHeader `form:"file" file:"max=16B" validate:"required,max=2"`
+}
+
+// newUploadRequest creates a multipart request with the files as name:content pairs.
+func newUploadRequest(name string, files ...string) *http.Request {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("name", name)
+	for i := 0; i < len(files); i += 2 {
+		w, _ := mw.CreateFormFile(files[i], files[i]+".dat")
+		w.Write([]byte(files[i+1]))
+	}
+	mw.Close()
+
+	req, _ := http.NewRequest("POST", "/", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestBindFiles(t *testing.T) {
+	Convey("Bind uploaded files", t, func() {
+		Convey("bind successfully", func() {
+			var up Upload
+			req := newUploadRequest("bob", "avatar", string(pngHeader), "file", "hello", "file", "world")
+			So(Bind(&up, req, nil), ShouldBeNil)
+			So(up.Name, ShouldEqual, "bob")
+			So(up.Avatar.Filename, ShouldEqual, "avatar.dat")
+			So(up.Files, ShouldHaveLength, 2)
+
+			f, err := up.Files[1].Open()
+			So(err, ShouldBeNil)
+			defer f.Close()
+			b := make([]byte, 5)
+			f.Read(b)
+			So(string(b), ShouldEqual, "world")
+		})
+
+		Convey("file is required", func() {
+			var up Upload
+			err := Bind(&up, newUploadRequest("bob"), nil)
+			So(err, ShouldResemble, ValidationErrors{{Field: "file", Rule: "required", Message: "file is required"}})
+			So(up.Avatar, ShouldBeNil)
+		})
+
+		Convey("file is too large", func() {
+			var up Upload
+			err := Bind(&up, newUploadRequest("bob", "file", "hello world, hello world"), nil)
+			So(err, ShouldResemble, ValidationErrors{{Field: "file", Rule: "max", Param: "16B", Message: "file must be at most 16B"}})
+		})
+
+		Convey("mime type is not allowed", func() {
+			var up Upload
+			err := Bind(&up, newUploadRequest("bob", "avatar", "<html></html>", "file", "hello"), nil)
+			So(err.(ValidationErrors)[0].Rule, ShouldEqual, "mime")
+			So(err.(ValidationErrors)[0].Param, ShouldEqual, "image/png image/jpeg")
+		})
+
+		Convey("malformed file tags", func() {
+			var bad struct {
+				File *multipart.FileHeader `form:"file" file:"max=16XB"`
+			}
+			err := Bind(&bad, newUploadRequest("bob", "file", "hello"), nil)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "invalid file size")
+
+			var unknown struct {
+				File *multipart.FileHeader `form:"file" file:"min=1B"`
+			}
+			// reported even if the file is not uploaded.
+			err = Bind(&unknown, newUploadRequest("bob"), nil)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "unknown file tag")
+		})
+	})
+}
+
+func TestParseSize(t *testing.T) {
+	Convey("Parse file sizes", t, func() {
+		for s, n := range map[string]int64{"512": 512, "512B": 512, "2kb": 2 << 10, "2MB": 2 << 20, " 1GB ": 1 << 30} {
+			size, err := parseSize(s)
+			So(err, ShouldBeNil)
+			So(size, ShouldEqual, n)
+		}
+		_, err := parseSize("2TB")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestMatchMIME(t *testing.T) {
+	Convey("Match media types", t, func() {
+		So(matchMIME("image/*", "image/png"), ShouldBeTrue)
+		So(matchMIME("*/*", "text/plain"), ShouldBeTrue)
+		So(matchMIME("IMAGE/PNG", "image/png"), ShouldBeTrue)
+		So(matchMIME("image/*", "text/plain"), ShouldBeFalse)
+		So(matchMIME("image/png", "image/jpeg"), ShouldBeFalse)
+	})
+}
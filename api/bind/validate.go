@@ -1,6 +1,7 @@
 package bind
 
 import (
+	"reflect"
 	"regexp"
 
 	"gopkg.in/go-playground/validator.v9"
@@ -31,8 +32,24 @@ func init() {
 	DefaultValidator = vd
 }
 
+// Validate validates the struct by the 'validate' tags, ValidationErrors is
+// returned with the names of the 'json' tags if validation failed.
 func Validate(ptr interface{}) error {
-	return DefaultValidator.Struct(ptr)
+	return validate(ptr, "json")
+}
+
+func validate(ptr interface{}, tagName string) error {
+	if err := DefaultValidator.Struct(ptr); err != nil {
+		return toValidationErrors(err, reflect.TypeOf(ptr), tagName)
+	}
+	return nil
+}
+
+// Regexp returns the regular expression registered as a validation tag,
+// such as "chinese", "name" and "moblie".
+func Regexp(tag string) (*regexp.Regexp, bool) {
+	r, ok := regexpMap[tag]
+	return r, ok
 }
 
 func match(r *regexp.Regexp) func(validator.FieldLevel) bool {
@@ -42,8 +59,6 @@ func match(r *regexp.Regexp) func(validator.FieldLevel) bool {
 }
 
 func IsValidationError(err error) (ok bool) {
-	if err != nil {
-		_, ok = err.(validator.ValidationErrors)
-	}
+	_, ok = err.(ValidationErrors)
 	return
 }
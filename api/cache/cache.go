@@ -24,5 +24,5 @@ type Cache interface {
 	Remove(id interface{}) error
 
 	// Clear purges all stored items from the cache.
-	Clear() 
+	Clear()
 }
@@ -78,6 +78,14 @@ func (m *LruMemCache) Remove(id interface{}) error {
 	return nil
 }
 
+// Clear purges all stored items from the cache.
+// OnEvicted is called for every item, so Stats reports them as evictions.
+func (m *LruMemCache) Clear() {
+	m.mu.Lock()
+	m.lc.Clear()
+	m.mu.Unlock()
+}
+
 // If id does not exist, create a new value by fn.
 // the value will insert to cache if the value is not nil.
 func (m *LruMemCache) Getsert(id interface{}, fn func() interface{}) (interface{}, error) {
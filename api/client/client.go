@@ -14,6 +14,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/zltgo/api"
 	"github.com/zltgo/api/bind"
 	"github.com/zltgo/reflectx"
 )
@@ -40,7 +41,10 @@ func (m Client) Get(urlStr string, vs url.Values, ptr interface{}) (int, error)
 //return the StatusCode and error
 //if error is nil, the ptr will changed by decode json
 //if int is zero, the request send error
+// The X-Request-ID and traceparent of api.Tracing are forwarded if r is
+// created with the request context, see api.TraceFrom.
 func (m Client) Exec(r *http.Request, ptr interface{}) (int, error) {
+	forwardTrace(r)
 	res, err := m.Do(r)
 	if err != nil {
 		return 0, err
@@ -69,6 +73,21 @@ func (m Client) Exec(r *http.Request, ptr interface{}) (int, error) {
 	return res.StatusCode, nil
 }
 
+// forwardTrace sets the trace headers of the request context if they are not set.
+func forwardTrace(r *http.Request) {
+	if t := api.TraceFrom(r.Context()); t != nil {
+		if r.Header.Get(api.HeaderRequestID) == "" {
+			r.Header.Set(api.HeaderRequestID, t.RequestID)
+		}
+		if r.Header.Get(api.HeaderTraceparent) == "" {
+			r.Header.Set(api.HeaderTraceparent, t.Traceparent())
+			if t.State != "" {
+				r.Header.Set(api.HeaderTracestate, t.State)
+			}
+		}
+	}
+}
+
 //Create a http.Request by method, urlStr and input parameter.
 //"Content-Type" will set to "application/x-www-form-urlencoded".
 func NewFormRequest(method, urlStr string, vs url.Values) (r *http.Request, err error) {
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zltgo/api"
+	"github.com/zltgo/api/render/sse"
+)
+
+func TestExecForwardsTrace(t *testing.T) {
+	var header http.Header
+	backend := api.New()
+	backend.GET("/goods", func(c *api.Context) {
+		header = c.Request.Header
+		c.Reply(http.StatusOK, map[string]string{"name": "apple"})
+	})
+	ts := httptest.NewServer(backend)
+	defer ts.Close()
+
+	var goods struct{ Name string }
+	frontend := api.New(api.Tracing())
+	frontend.GET("/goods", func(c *api.Context) {
+		r, _ := NewJsonRequest("GET", ts.URL+"/goods", nil)
+		code, err := Default.Exec(r.WithContext(c), &goods)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, code)
+
+		trace := c.Trace()
+		assert.Equal(t, trace.RequestID, header.Get(api.HeaderRequestID))
+		assert.Equal(t, trace.Traceparent(), header.Get(api.HeaderTraceparent))
+	})
+
+	req, _ := http.NewRequest("GET", "/goods", nil)
+	req.Header.Set(api.HeaderRequestID, "req-42")
+	frontend.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "apple", goods.Name)
+	assert.Equal(t, "req-42", header.Get(api.HeaderRequestID))
+}
+
+func TestEventSource(t *testing.T) {
+	var conns int32
+	lastIDs := make(chan string, 3)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastIDs <- r.Header.Get("Last-Event-ID")
+		switch atomic.AddInt32(&conns, 1) {
+		case 1:
+			w.Header().Set("Content-Type", sse.ContentType)
+			// the connection is lost after the first event.
+			io.WriteString(w, "retry: 10\nid: 1\ndata: a\n\n")
+		case 2:
+			w.Header().Set("Content-Type", sse.ContentType)
+			io.WriteString(w, ": heartbeat\n\nid: 2\nevent: update\ndata: b\n\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	r, _ := http.NewRequest("GET", ts.URL, nil)
+	es := Default.Events(r)
+	event, err := es.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, sse.Event{Event: "message", Id: "1", Retry: 10, Data: "a"}, event)
+
+	start := time.Now()
+	event, err = es.Next()
+	assert.NoError(t, err)
+	assert.True(t, time.Since(start) >= 10*time.Millisecond, "should wait for retry")
+	assert.Equal(t, sse.Event{Event: "update", Id: "2", Data: "b"}, event)
+	assert.Equal(t, "2", es.LastEventID())
+	assert.Equal(t, "", <-lastIDs)
+	assert.Equal(t, "1", <-lastIDs)
+
+	errs := make(chan error)
+	go func() {
+		_, err := es.Next()
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, es.Close())
+	assert.Equal(t, ErrSourceClosed, <-errs)
+
+	// the server stops the client by 204.
+	r.Header.Set("Last-Event-ID", "2")
+	es = Default.Events(r)
+	_, err = es.Next()
+	assert.Equal(t, ErrNoContent, err)
+	assert.Equal(t, "2", <-lastIDs)
+}
+
+func TestEventSourceCancel(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, "{}")
+	}))
+	defer ts.Close()
+
+	r, _ := http.NewRequest("GET", ts.URL, nil)
+	_, err := Default.Events(r).Next()
+	assert.EqualError(t, err, "client: unexpected content type: application/json")
+
+	// the connection is refused and retried until the context is canceled.
+	ts.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	es := Default.Events(r.WithContext(ctx))
+	_, err = es.Next()
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// the error is returned after MaxRetries.
+	DefaultRetry = time.Millisecond
+	defer func() { DefaultRetry = 3 * time.Second }()
+	var failures int
+	es = Default.Events(r)
+	es.MaxRetries = 2
+	es.OnError = func(err error) {
+		assert.Error(t, err)
+		failures++
+	}
+	_, err = es.Next()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "connection refused")
+	assert.Equal(t, 3, failures)
+}
@@ -0,0 +1,204 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/zltgo/api/render/sse"
+)
+
+// DefaultRetry is the reconnection time of EventSource before the server
+// sends a retry field.
+var DefaultRetry = 3 * time.Second
+
+var (
+	ErrNoContent    = errors.New("client: event stream is closed by the server with 204")
+	ErrSourceClosed = errors.New("client: event source is closed")
+)
+
+// EventSource reads server-sent events and reconnects when the connection
+// is lost, like the EventSource of browsers. The Last-Event-ID header is
+// sent on reconnection and the delay is set by the retry fields.
+type EventSource struct {
+	// MaxRetries limits the reconnections failed in a row, Next returns the
+	// error of the last connection if it is exceeded. 0 means retrying forever.
+	MaxRetries int
+
+	// OnError is called with the error of each failed connection before
+	// retrying, such as DNS failures or refused connections. Optional.
+	OnError func(error)
+
+	client   Client
+	req      *http.Request
+	retry    time.Duration
+	lastID   string
+	failures int // connections failed in a row
+
+	mu     sync.Mutex
+	body   io.Closer
+	dec    *sse.Decoder
+	closed bool
+	done   chan struct{}
+}
+
+// Events returns an EventSource of r, the connection is established by the
+// first call of Next. Cancel the context of r or call Close to stop it.
+// For example:
+//
+//	r, _ := http.NewRequest("GET", "http://example.com/events?topic=news", nil)
+//	es := client.Default.Events(r)
+//	defer es.Close()
+//	for {
+//		event, err := es.Next()
+//		if err != nil {
+//			return err
+//		}
+//		handle(event)
+//	}
+func (m Client) Events(r *http.Request) *EventSource {
+	return &EventSource{
+		client: m,
+		req:    r,
+		retry:  DefaultRetry,
+		lastID: r.Header.Get("Last-Event-ID"),
+		done:   make(chan struct{}),
+	}
+}
+
+// Next blocks until an event arrives, reconnecting if necessary.
+// It returns an error if the context of the request is done, the source is
+// closed, the server replies 204, or the response is not an event stream.
+// Reconnection is retried on network errors, see MaxRetries and OnError.
+func (es *EventSource) Next() (sse.Event, error) {
+	for {
+		dec, failed, err := es.connect()
+		if err != nil {
+			return sse.Event{}, err
+		}
+		if failed != nil {
+			es.failures++
+			if es.OnError != nil {
+				es.OnError(failed)
+			}
+			if es.MaxRetries > 0 && es.failures > es.MaxRetries {
+				return sse.Event{}, failed
+			}
+		}
+		if dec != nil {
+			event, err := dec.Next()
+			if retry := dec.Retry(); retry > 0 {
+				es.retry = time.Duration(retry) * time.Millisecond
+			}
+			if err == nil {
+				if event.Id != "" {
+					es.lastID = event.Id
+				}
+				return event, nil
+			}
+			es.disconnect()
+		}
+
+		// wait to reconnect.
+		timer := time.NewTimer(es.retry)
+		select {
+		case <-es.req.Context().Done():
+			timer.Stop()
+			return sse.Event{}, es.req.Context().Err()
+		case <-es.done:
+			timer.Stop()
+			return sse.Event{}, ErrSourceClosed
+		case <-timer.C:
+		}
+	}
+}
+
+// LastEventID returns the id of the last event received.
+func (es *EventSource) LastEventID() string {
+	return es.lastID
+}
+
+// Close closes the connection, the blocking Next returns ErrSourceClosed.
+func (es *EventSource) Close() error {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if es.closed {
+		return nil
+	}
+	es.closed = true
+	close(es.done)
+	if es.body != nil {
+		return es.body.Close()
+	}
+	return nil
+}
+
+// connect returns the decoder of the current connection, a new connection
+// is created if there is none. If the connection fails and should be retried,
+// the error is returned as failed.
+func (es *EventSource) connect() (dec *sse.Decoder, failed error, err error) {
+	es.mu.Lock()
+	closed, dec := es.closed, es.dec
+	es.mu.Unlock()
+	if closed {
+		return nil, nil, ErrSourceClosed
+	}
+	if dec != nil {
+		return dec, nil, nil
+	}
+	if err := es.req.Context().Err(); err != nil {
+		return nil, nil, err
+	}
+
+	r := es.req.Clone(es.req.Context())
+	r.Header.Set("Accept", sse.ContentType)
+	r.Header.Set("Cache-Control", "no-cache")
+	if es.lastID != "" {
+		r.Header.Set("Last-Event-ID", es.lastID)
+	}
+	forwardTrace(r)
+
+	res, err := es.client.Do(r)
+	if err != nil {
+		if e := es.req.Context().Err(); e != nil {
+			return nil, nil, e
+		}
+		return nil, err, nil
+	}
+	switch {
+	case res.StatusCode == http.StatusNoContent:
+		res.Body.Close()
+		return nil, nil, ErrNoContent
+	case res.StatusCode != http.StatusOK:
+		res.Body.Close()
+		return nil, nil, errors.New(res.Status)
+	}
+	if mt, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); mt != sse.ContentType {
+		res.Body.Close()
+		return nil, nil, errors.New("client: unexpected content type: " + mt)
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if es.closed {
+		res.Body.Close()
+		return nil, nil, ErrSourceClosed
+	}
+	es.failures = 0
+	es.body = res.Body
+	es.dec = sse.NewDecoder(res.Body)
+	return es.dec, nil, nil
+}
+
+// disconnect closes the current connection.
+func (es *EventSource) disconnect() {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if es.body != nil {
+		es.body.Close()
+		es.body, es.dec = nil, nil
+	}
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/zltgo/archive"
+	"github.com/zltgo/reflectx"
+)
+
+// CompressOptions configures the Compress middleware, zero fields are set to default values.
+type CompressOptions struct {
+	// Level is the compression level of gzip and deflate, from
+	// flate.BestSpeed(1) to flate.BestCompression(9), -1 is the default level.
+	Level int `default:"-1"`
+
+	// MinLength is the minimum size in bytes of bodies to compress,
+	// streams flushed before MinLength are always compressed.
+	MinLength int `default:"1024"`
+}
+
+// media types of archive.CompressedFormats not in the mime table of some systems.
+var compressedTypes = map[string]string{
+	".7z":   "application/x-7z-compressed",
+	".avi":  "video/x-msvideo",
+	".bz2":  "application/x-bzip2",
+	".gz":   "application/gzip",
+	".jar":  "application/java-archive",
+	".mov":  "video/quicktime",
+	".mp3":  "audio/mpeg",
+	".mp4":  "video/mp4",
+	".mpeg": "video/mpeg",
+	".mpg":  "video/mpeg",
+	".rar":  "application/vnd.rar",
+	".tgz":  "application/gzip",
+	".xz":   "application/x-xz",
+	".zip":  "application/zip",
+}
+
+// Compress returns a middleware compressing responses by gzip or deflate,
+// negotiated with the Accept-Encoding header. Bodies smaller than MinLength,
+// bodies already encoded and the files or media types of archive.CompressedFormats
+// are not compressed, nor are event streams which must reach the client as
+// they are written. It works with Context.Stream, the compressed data is
+// flushed on every Flush. For example:
+//	serv.Use(api.Compress(api.CompressOptions{Level: gzip.BestSpeed}))
+func Compress(opts CompressOptions) Handler {
+	reflectx.SetDefault(&opts)
+	if _, err := flate.NewWriter(nil, opts.Level); err != nil {
+		panic(err)
+	}
+
+	// media types never compressed.
+	skipped := map[string]bool{"text/event-stream": true}
+	for ext, ok := range archive.CompressedFormats {
+		if !ok {
+			continue
+		}
+		if mt, _, err := mime.ParseMediaType(mime.TypeByExtension(ext)); err == nil {
+			skipped[mt] = true
+		}
+		if mt, ok := compressedTypes[ext]; ok {
+			skipped[mt] = true
+		}
+	}
+
+	var gzipPool, flatePool sync.Pool
+	gzipPool.New = func() interface{} {
+		gw, _ := gzip.NewWriterLevel(nil, opts.Level)
+		return gw
+	}
+	flatePool.New = func() interface{} {
+		fw, _ := flate.NewWriter(nil, opts.Level)
+		return fw
+	}
+
+	return func(c *Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		if archive.CompressedFormats[strings.ToLower(path.Ext(c.Request.URL.Path))] {
+			return
+		}
+
+		var pool *sync.Pool
+		encoding := NegotiateEncoding(c.Request.Header.Get("Accept-Encoding"))
+		switch encoding {
+		case "gzip":
+			pool = &gzipPool
+		case "deflate":
+			pool = &flatePool
+		default:
+			return
+		}
+
+		w := c.Writer
+		cw := &compressWriter{
+			ResponseWriter: w,
+			encoding:       encoding,
+			pool:           pool,
+			skipped:        skipped,
+			minLength:      opts.MinLength,
+			size:           noWritten,
+		}
+		c.Writer = cw
+		defer func() {
+			c.Writer = w
+			cw.close()
+		}()
+		c.Next()
+	}
+}
+
+// NegotiateEncoding returns "gzip", "deflate" or "" accepted by the
+// Accept-Encoding header with the highest quality, gzip is preferred.
+func NegotiateEncoding(acceptEncoding string) string {
+	qs := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params := part, ""
+		if i := strings.IndexByte(part, ';'); i >= 0 {
+			coding, params = part[:i], part[i+1:]
+		}
+		qs[strings.ToLower(strings.TrimSpace(coding))] = parseQuality(params)
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range []string{"gzip", "deflate"} {
+		q, ok := qs[coding]
+		if !ok {
+			q = qs["*"]
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+var _ ResponseWriter = &compressWriter{}
+
+// compressWriter buffers the body until MinLength or Flush to decide whether
+// to compress it.
+type compressWriter struct {
+	ResponseWriter
+	encoding  string
+	pool      *sync.Pool
+	skipped   map[string]bool
+	minLength int
+
+	buf     []byte
+	decided bool
+	cw      io.WriteCloser // nil if not compressed
+	size    int
+}
+
+// WriteHeaderNow only marks the header as written, the header is written
+// when the body is compressed or not.
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.size = 0
+	}
+	if !bodyAllowedForStatus(w.Status()) {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	w.size += len(data)
+	if !w.decided {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.minLength {
+			return len(data), nil
+		}
+		return len(data), w.decide(true)
+	}
+	if w.cw != nil {
+		return w.cw.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Size() int {
+	return w.size
+}
+
+func (w *compressWriter) Written() bool {
+	return w.size != noWritten
+}
+
+// Flush compresses the buffered body regardless of MinLength. The header is
+// written by the first Flush, so it is decided by the current Content-Type
+// even if nothing is written yet.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.WriteHeaderNow()
+		w.decide(true)
+	}
+	if f, ok := w.cw.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide writes the header and the buffered body, the body is compressed
+// if compress is true and the response is compressible.
+func (w *compressWriter) decide(compress bool) error {
+	if w.decided {
+		return nil
+	}
+	w.decided = true
+
+	h := w.Header()
+	if len(w.buf) > 0 && h.Get("Content-Type") == "" {
+		// sniff the uncompressed body like http.ResponseWriter does.
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	mt, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	if compress && h.Get("Content-Encoding") == "" && !w.skipped[mt] &&
+		w.Status() != http.StatusPartialContent && bodyAllowedForStatus(w.Status()) {
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+
+		cw := w.pool.Get().(interface {
+			io.WriteCloser
+			Reset(io.Writer)
+		})
+		cw.Reset(w.ResponseWriter)
+		w.cw = cw
+	}
+
+	w.ResponseWriter.WriteHeaderNow()
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.cw != nil {
+		_, err = w.cw.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+// close writes the rest of the response after the handlers return.
+func (w *compressWriter) close() {
+	if !w.Written() {
+		return
+	}
+	w.decide(len(w.buf) >= w.minLength)
+	if w.cw != nil {
+		w.cw.Close()
+		w.pool.Put(w.cw)
+		w.cw = nil
+	}
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func performEncodedRequest(r http.Handler, method, path string, header ...string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	for i := 0; i < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	assert.Equal(t, "gzip", NegotiateEncoding("gzip, deflate, br"))
+	assert.Equal(t, "deflate", NegotiateEncoding("gzip;q=0.5, deflate"))
+	assert.Equal(t, "deflate", NegotiateEncoding("deflate"))
+	assert.Equal(t, "gzip", NegotiateEncoding("*"))
+	assert.Equal(t, "deflate", NegotiateEncoding("gzip;q=0, *"))
+	assert.Equal(t, "", NegotiateEncoding("br, identity"))
+	assert.Equal(t, "", NegotiateEncoding(""))
+}
+
+func TestCompress(t *testing.T) {
+	long := strings.Repeat("hello world ", 200)
+	router := New(Compress(CompressOptions{}))
+	router.GET("/long", func(c *Context) { c.Reply(http.StatusOK, long) })
+	router.GET("/short", func(c *Context) { c.Reply(http.StatusOK, "hello") })
+	router.GET("/png", func(c *Context) {
+		c.Writer.Header().Set("Content-Type", "image/png")
+		c.Reply(http.StatusOK, long)
+	})
+	router.GET("/files/a.zip", func(c *Context) { c.Reply(http.StatusOK, long) })
+	router.GET("/empty", func(c *Context) { c.Status(http.StatusNoContent) })
+
+	w := performEncodedRequest(router, "GET", "/long", "Accept-Encoding", "gzip, deflate")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	gr, err := gzip.NewReader(w.Body)
+	assert.NoError(t, err)
+	b, _ := ioutil.ReadAll(gr)
+	assert.Equal(t, long, string(b))
+
+	w = performEncodedRequest(router, "GET", "/long", "Accept-Encoding", "deflate")
+	assert.Equal(t, "deflate", w.Header().Get("Content-Encoding"))
+	b, _ = ioutil.ReadAll(flate.NewReader(w.Body))
+	assert.Equal(t, long, string(b))
+
+	// not compressed
+	w = performEncodedRequest(router, "GET", "/long")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, long, w.Body.String())
+
+	w = performEncodedRequest(router, "GET", "/short", "Accept-Encoding", "gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "hello", w.Body.String())
+
+	w = performEncodedRequest(router, "GET", "/png", "Accept-Encoding", "gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, long, w.Body.String())
+
+	w = performEncodedRequest(router, "GET", "/files/a.zip", "Accept-Encoding", "gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, long, w.Body.String())
+
+	w = performEncodedRequest(router, "GET", "/empty", "Accept-Encoding", "gzip")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Zero(t, w.Body.Len())
+}
+
+func TestCompressStream(t *testing.T) {
+	router := New(Compress(CompressOptions{}))
+	router.GET("/stream", func(c *Context) {
+		i := 0
+		c.Stream(func(w io.Writer) bool {
+			io.WriteString(w, "data: tick\n\n")
+			i++
+			return i < 3
+		})
+	})
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+	req, _ := http.NewRequest("GET", ts.URL+"/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res, err := (&http.Transport{DisableCompression: true}).RoundTrip(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "gzip", res.Header.Get("Content-Encoding"))
+	assert.Equal(t, []string{"chunked"}, res.TransferEncoding)
+
+	gr, err := gzip.NewReader(res.Body)
+	assert.NoError(t, err)
+	b, _ := ioutil.ReadAll(gr)
+	assert.Equal(t, strings.Repeat("data: tick\n\n", 3), string(b))
+}
+
+func TestCompressFlushFirst(t *testing.T) {
+	router := New(Compress(CompressOptions{MinLength: 1}))
+	router.GET("/stream", func(c *Context) {
+		c.Writer.Header().Set("Content-Type", "text/plain")
+		i := 0
+		c.Stream(func(w io.Writer) bool {
+			// nothing is written by the first step.
+			if i > 0 {
+				io.WriteString(w, "tick\n")
+			}
+			i++
+			return i < 3
+		})
+	})
+	router.GET("/events", func(c *Context) {
+		c.Writer.Header().Set("Content-Type", "text/event-stream")
+		c.Writer.Flush()
+		io.WriteString(c.Writer, "data: tick\n\n")
+	})
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+	get := func(path string) (*http.Response, []byte) {
+		req, _ := http.NewRequest("GET", ts.URL+path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		res, err := (&http.Transport{DisableCompression: true}).RoundTrip(req)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(res.Body)
+		return res, b
+	}
+
+	res, b := get("/stream")
+	assert.Equal(t, "gzip", res.Header.Get("Content-Encoding"))
+	gr, err := gzip.NewReader(strings.NewReader(string(b)))
+	assert.NoError(t, err)
+	b, _ = ioutil.ReadAll(gr)
+	assert.Equal(t, "tick\ntick\n", string(b))
+
+	// event streams are never compressed.
+	res, b = get("/events")
+	assert.Equal(t, "", res.Header.Get("Content-Encoding"))
+	assert.Equal(t, "data: tick\n\n", string(b))
+}
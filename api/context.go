@@ -1,11 +1,14 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"math"
+	"mime"
 	"net/http"
 	"reflect"
+	"time"
 
 	"github.com/zltgo/api/bind"
 	"github.com/zltgo/api/inject"
@@ -24,8 +27,11 @@ var (
 	TypeHttpResponseWriter = inject.InterfaceOf((*http.ResponseWriter)(nil))
 	TypeResponseWriter     = inject.InterfaceOf((*ResponseWriter)(nil))
 	TypeContext            = reflect.TypeOf(&Context{})
+	TypeStdContext         = inject.InterfaceOf((*context.Context)(nil))
 )
 
+var _ context.Context = &Context{}
+
 // Context represents the runtime context of current request of Macaron instance.
 // It is the integration of most frequently used middlewares and helper methods.
 type Context struct {
@@ -33,15 +39,25 @@ type Context struct {
 	writermem responseWriter
 	Writer    ResponseWriter
 	Request   *http.Request
+	serv      *Server
+	route     *Route
 
 	// inject
 	typePairs inject.TypePairs
 
+	// formats offered to content negotiation
+	offers []string
+	// offers are limited by Negotiate, 406 is replied if nothing matches.
+	strict bool
+
 	//handlers
 	middleware []Handler
 	handlers   []Handler
 
 	index int8
+	// probe receives the function of the handlers created by funcHandler
+	// instead of calling them, see FuncOf.
+	probe *interface{}
 	// Errors is a list of errors attached to all the handlers/middlewares who used this context.
 	Errors []error
 }
@@ -74,7 +90,7 @@ func (ctx *Context) run(handlers []Handler) {
 	ctx.Writer.WriteHeaderNow()
 }
 
-// If there is an error, it will be of type validator.ValidationErrors.
+// If there is an error, it will be of type bind.ValidationErrors.
 func (ctx *Context) Invoke(f interface{}) ([]reflect.Value, error) {
 	rv, err := inject.Invoke(f, inject.GetterFunc(ctx.GetType))
 	if err != nil && !bind.IsValidationError(err) {
@@ -111,9 +127,26 @@ func (ctx *Context) Bind(ptr interface{}) error {
 			extra[v.Key] = []string{v.Value}
 		}
 	}
+	if err := ctx.parseMultipartForm(); err != nil {
+		return err
+	}
 	return bind.Bind(ptr, ctx.Request, extra)
 }
 
+// parseMultipartForm parses the multipart form of the request with
+// Server.MaxMultipartMemory before binding, bind.DefaultMaxMemory is used
+// by bind otherwise.
+func (ctx *Context) parseMultipartForm() error {
+	r := ctx.Request
+	if ctx.serv == nil || r.MultipartForm != nil {
+		return nil
+	}
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != bind.MIMEMultipartPOSTForm {
+		return nil
+	}
+	return r.ParseMultipartForm(ctx.serv.MaxMultipartMemory)
+}
+
 //implementation of inject.GetterFunc, used for Invoke.
 func (ctx *Context) GetType(typ reflect.Type) (reflect.Value, error) {
 	// find types inside
@@ -124,6 +157,8 @@ func (ctx *Context) GetType(typ reflect.Type) (reflect.Value, error) {
 		return reflect.ValueOf(ctx.Writer), nil
 	case TypeContext:
 		return reflect.ValueOf(ctx), nil
+	case TypeStdContext:
+		return reflect.ValueOf(ctx.Request.Context()), nil
 	}
 
 	//find type in typePairs
@@ -137,6 +172,9 @@ func (ctx *Context) GetType(typ reflect.Type) (reflect.Value, error) {
 				extra[v.Key] = []string{v.Value}
 			}
 		}
+		if err = ctx.parseMultipartForm(); err != nil {
+			return reflect.Value{}, err
+		}
 		v, err = bind.GetType(typ, ctx.Request, extra)
 	}
 
@@ -170,23 +208,62 @@ func (ctx *Context) reset(w http.ResponseWriter, req *http.Request) {
 	ctx.Params = ctx.Params[0:0]
 	ctx.Errors = ctx.Errors[0:0]
 
+	ctx.route = nil
+	ctx.offers = nil
+	ctx.strict = false
 	ctx.middleware = nil
 	ctx.handlers = nil
 	ctx.index = -1
 }
 
+// FullPath returns the path of the matched route, such as "/user/:id<int>".
+// It returns "" if no route is matched, such as in the handlers of 404 and 405.
+func (ctx *Context) FullPath() string {
+	if ctx.route == nil {
+		return ""
+	}
+	return ctx.route.Url
+}
+
 // Status sets the HTTP response code.
 func (ctx *Context) Status(code int) {
-	ctx.writermem.WriteHeader(code)
+	ctx.Writer.WriteHeader(code)
 }
 
+// Error attaches err to ctx.Errors. bind.ValidationErrors is also replied
+// as 400 in JSON, the messages are translated to the locale negotiated with
+// the Accept-Language header, for example:
+//	{
+//		"code": 400,
+//		"message": "Bad Request",
+//		"errors": [{"field": "name", "rule": "required", "message": "name is required"}]
+//	}
 func (ctx *Context) Error(err error) {
-	if err != nil {
-		ctx.Errors = append(ctx.Errors, err)
+	if err == nil {
+		return
+	}
+	ctx.Errors = append(ctx.Errors, err)
+
+	if ve, ok := err.(bind.ValidationErrors); ok {
+		locale := bind.MatchLocale(ctx.Request.Header.Get("Accept-Language"))
+		ctx.Reply(http.StatusBadRequest, render.JSON{Data: ErrorBody{
+			Code:    http.StatusBadRequest,
+			Message: http.StatusText(http.StatusBadRequest),
+			Errors:  ve.Translate(locale),
+		}})
 	}
 }
 
+// ErrorBody is the body of error responses replied by Context.Error.
+type ErrorBody struct {
+	Code    int                   `json:"code"`
+	Message string                `json:"message"`
+	Errors  bind.ValidationErrors `json:"errors,omitempty"`
+}
+
 // write code and value to ResponseWriter.
+// Values other than []byte, string, stream functions, io.Reader and render.Render
+// are rendered in the format negotiated with the Accept header, see Negotiate.
 // Render prevents pending handlers from being called.
 // Let's say you have an authorization middleware that validates that the current request is authorized.
 // If the authorization fails (ex: the password does not match), call Render to ensure the remaining handlers
@@ -203,9 +280,9 @@ func (ctx *Context) Reply(code int, val interface{}) {
 	var err error
 	switch v := val.(type) {
 	case []byte:
-		_, err = ctx.writermem.Write(v)
+		_, err = ctx.Writer.Write(v)
 	case string:
-		_, err = ctx.writermem.WriteString(v)
+		_, err = ctx.Writer.WriteString(v)
 	case func(w io.Writer) bool:
 		ctx.Stream(v)
 	case io.Reader:
@@ -213,7 +290,7 @@ func (ctx *Context) Reply(code int, val interface{}) {
 	case render.Render:
 		err = v.Render(ctx.Writer)
 	default: //nil or obj
-		err = render.JSON{Data: val}.Render(ctx.Writer)
+		err = ctx.negotiate(val)
 	}
 
 	if err != nil {
@@ -231,6 +308,10 @@ func (ctx *Context) ReplyValues(values []reflect.Value) {
 		case nil:
 		case error:
 			ctx.Error(v)
+			// validation errors have been replied by ctx.Error.
+			if bind.IsValidationError(v) {
+				return
+			}
 		case int:
 			code = v
 		default:
@@ -255,13 +336,23 @@ func (ctx *Context) ReplyValues(values []reflect.Value) {
 	return
 }
 
+// Stream calls step and flushes the response until step returns false,
+// the client is gone or the server is shutting down.
 func (ctx *Context) Stream(step func(w io.Writer) bool) {
 	w := ctx.Writer
 	clientGone := w.CloseNotify()
+
+	// nil channel blocks forever if ctx is not created by a Server.
+	var shutdown <-chan struct{}
+	if ctx.serv != nil {
+		shutdown = ctx.serv.Done()
+	}
 	for {
 		select {
 		case <-clientGone:
 			return
+		case <-shutdown:
+			return
 		default:
 			keepOpen := step(w)
 			w.Flush()
@@ -272,6 +363,18 @@ func (ctx *Context) Stream(step func(w io.Writer) bool) {
 	}
 }
 
+// Push initiates an HTTP/2 server push of target, http.ErrNotSupported is
+// returned if the connection does not support it, for example:
+//	ctx.Push("/static/app.js", nil)
+// It must be called before writing the response.
+func (ctx *Context) Push(target string, opts *http.PushOptions) error {
+	p := ctx.Writer.Pusher()
+	if p == nil {
+		return http.ErrNotSupported
+	}
+	return p.Push(target, opts)
+}
+
 // SSEvent writes a Server-Sent Event into the body stream.
 func (ctx *Context) SSEvent(name string, message interface{}) {
 	sse.Event{
@@ -280,6 +383,39 @@ func (ctx *Context) SSEvent(name string, message interface{}) {
 	}.Render(ctx.Writer)
 }
 
+/****************context.Context**********************/
+
+// Deadline returns the deadline of the request context, see Timeout.
+// Note that Context is reused after the request is finished,
+// do not use it as a context.Context in goroutines outliving the request,
+// use ctx.Request.Context() instead.
+func (ctx *Context) Deadline() (deadline time.Time, ok bool) {
+	return ctx.Request.Context().Deadline()
+}
+
+// Done returns a channel that is closed when the request is canceled,
+// the client is gone or the deadline is exceeded.
+func (ctx *Context) Done() <-chan struct{} {
+	return ctx.Request.Context().Done()
+}
+
+// Err returns the error of the request context after Done is closed.
+func (ctx *Context) Err() error {
+	return ctx.Request.Context().Err()
+}
+
+// Value returns the mapped value if key is a reflect.Type,
+// otherwise the value associated with key in the request context.
+func (ctx *Context) Value(key interface{}) interface{} {
+	if typ, ok := key.(reflect.Type); ok {
+		if v, err := ctx.typePairs.Get(typ); err == nil {
+			return v.Interface()
+		}
+		return nil
+	}
+	return ctx.Request.Context().Value(key)
+}
+
 // bodyAllowedForStatus is a copy of http.bodyAllowedForStatus non-exported function.
 func bodyAllowedForStatus(status int) bool {
 	switch {
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/zltgo/reflectx"
+)
+
+// CorsOptions configures the Cors middleware, zero fields are set to default values.
+type CorsOptions struct {
+	// AllowOrigins are the origins allowed to make cross-origin requests,
+	// such as "https://example.com". "*" allows all origins, a wildcard
+	// matches any part of an origin, such as "https://*.example.com".
+	AllowOrigins []string `default:"*"`
+
+	// AllowMethods are the methods allowed by preflight requests.
+	AllowMethods []string `default:"GET,POST,PUT,PATCH,DELETE,HEAD"`
+
+	// AllowHeaders are the request headers allowed by preflight requests,
+	// "*" allows the headers requested by the client.
+	AllowHeaders []string `default:"Origin,Content-Type,Accept,Authorization,X-Request-ID"`
+
+	// ExposeHeaders are the response headers readable by the client.
+	ExposeHeaders []string
+
+	// AllowCredentials allows cookies and authorization headers, the
+	// allowed origin is echoed instead of "*" if it is true.
+	// It can not be used with "*" in AllowOrigins, which allows any site to
+	// read the responses with the credentials of users.
+	AllowCredentials bool
+
+	// MaxAge is how long in seconds the results of preflight requests can be cached.
+	MaxAge int `default:"43200"`
+}
+
+// Cors returns a middleware handling the CORS requests, see
+// https://developer.mozilla.org/en-US/docs/Web/HTTP/CORS.
+// Preflight requests are replied with 204 and the chain is aborted, so it
+// should be used as a global middleware by Server.Use to handle the
+// OPTIONS requests of all the routes. For example:
+//	serv.Use(api.Cors(api.CorsOptions{
+//		AllowOrigins:     []string{"https://*.example.com"},
+//		AllowHeaders:     []string{"Content-Type", "ACCESS-TOKEN", "REFRESH-TOKEN"},
+//		AllowCredentials: true,
+//	}))
+func Cors(opts CorsOptions) Handler {
+	reflectx.SetDefault(&opts)
+	allowAll := false
+	for _, o := range opts.AllowOrigins {
+		allowAll = allowAll || o == "*"
+	}
+	if allowAll && opts.AllowCredentials {
+		panic("api: AllowCredentials can not be used with \"*\" in AllowOrigins")
+	}
+	allowMethods := strings.Join(opts.AllowMethods, ", ")
+	allowHeaders := strings.Join(opts.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(opts.ExposeHeaders, ", ")
+	maxAge := strconv.Itoa(opts.MaxAge)
+
+	return func(c *Context) {
+		origin := c.Request.Header.Get("Origin")
+		if origin == "" {
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Add("Vary", "Origin")
+		preflight := c.Request.Method == "OPTIONS" && c.Request.Header.Get("Access-Control-Request-Method") != ""
+		if !allowAll && !matchOrigin(opts.AllowOrigins, origin) {
+			if preflight {
+				c.Reply(http.StatusForbidden, "403 origin not allowed")
+			}
+			// the response is not readable by the browser without the CORS headers.
+			return
+		}
+
+		if allowAll {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if opts.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposeHeaders != "" {
+				h.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			return
+		}
+
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+		h.Set("Access-Control-Allow-Methods", allowMethods)
+		if allowHeaders == "*" {
+			h.Set("Access-Control-Allow-Headers", c.Request.Header.Get("Access-Control-Request-Headers"))
+		} else if allowHeaders != "" {
+			h.Set("Access-Control-Allow-Headers", allowHeaders)
+		}
+		if opts.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", maxAge)
+		}
+		c.Reply(http.StatusNoContent, nil)
+	}
+}
+
+// matchOrigin reports whether the origin matches one of the patterns,
+// a '*' in patterns matches any characters.
+func matchOrigin(patterns []string, origin string) bool {
+	for _, p := range patterns {
+		if i := strings.IndexByte(p, '*'); i >= 0 {
+			prefix, suffix := p[:i], p[i+1:]
+			if len(origin) >= len(prefix)+len(suffix) &&
+				strings.EqualFold(origin[:len(prefix)], prefix) &&
+				strings.EqualFold(origin[len(origin)-len(suffix):], suffix) {
+				return true
+			}
+		} else if strings.EqualFold(p, origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func performCorsRequest(r http.Handler, method, origin string, header ...string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, "/example", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for i := 0; i < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCorsAllowAll(t *testing.T) {
+	router := New(Cors(CorsOptions{}))
+	router.GET("/example", func(c *Context) { c.Reply(http.StatusOK, "ok") })
+
+	w := performCorsRequest(router, "GET", "")
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = performCorsRequest(router, "GET", "http://example.com")
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+
+	// preflight without the OPTIONS route
+	w = performCorsRequest(router, "OPTIONS", "http://example.com", "Access-Control-Request-Method", "PUT")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST, PUT, PATCH, DELETE, HEAD", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Origin, Content-Type, Accept, Authorization, X-Request-ID", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "43200", w.Header().Get("Access-Control-Max-Age"))
+
+	// credentials must be allowed for the listed origins only.
+	assert.Panics(t, func() { Cors(CorsOptions{AllowCredentials: true}) })
+	assert.Panics(t, func() {
+		Cors(CorsOptions{AllowOrigins: []string{"https://example.com", "*"}, AllowCredentials: true})
+	})
+}
+
+func TestCorsOrigins(t *testing.T) {
+	router := New(Cors(CorsOptions{
+		AllowOrigins:     []string{"https://*.example.com", "http://localhost:8080"},
+		AllowHeaders:     []string{"*"},
+		ExposeHeaders:    []string{"X-Request-ID"},
+		AllowCredentials: true,
+	}))
+	router.POST("/example", func(c *Context) { c.Reply(http.StatusOK, "ok") })
+
+	w := performCorsRequest(router, "POST", "https://api.example.com")
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Equal(t, "https://api.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "X-Request-ID", w.Header().Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+
+	w = performCorsRequest(router, "POST", "http://localhost:8080")
+	assert.Equal(t, "http://localhost:8080", w.Header().Get("Access-Control-Allow-Origin"))
+
+	// not allowed
+	w = performCorsRequest(router, "POST", "https://example.com.evil.org")
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = performCorsRequest(router, "OPTIONS", "https://evil.org", "Access-Control-Request-Method", "POST")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// requested headers are echoed
+	w = performCorsRequest(router, "OPTIONS", "https://www.example.com",
+		"Access-Control-Request-Method", "POST",
+		"Access-Control-Request-Headers", "ACCESS-TOKEN, REFRESH-TOKEN")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "ACCESS-TOKEN, REFRESH-TOKEN", w.Header().Get("Access-Control-Allow-Headers"))
+}
+
+func TestSecure(t *testing.T) {
+	router := New(Secure(SecureOptions{}))
+	router.GET("/example", func(c *Context) {})
+
+	w := performRequest(router, "GET", "/example")
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "strict-origin-when-cross-origin", w.Header().Get("Referrer-Policy"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Empty(t, w.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, w.Header().Get("Strict-Transport-Security"))
+
+	w = performCorsRequest(router, "GET", "", "X-Forwarded-Proto", "https")
+	assert.Equal(t, "max-age=31536000", w.Header().Get("Strict-Transport-Security"))
+
+	maxAge := 600
+	router = New(Secure(SecureOptions{
+		HSTSMaxAge:            &maxAge,
+		HSTSIncludeSubdomains: true,
+		HSTSPreload:           true,
+		ContentSecurityPolicy: "default-src 'self'",
+		FrameOptions:          "-",
+	}))
+	router.GET("/example", func(c *Context) {})
+	w = performCorsRequest(router, "GET", "", "X-Forwarded-Proto", "https")
+	assert.Equal(t, "max-age=600; includeSubDomains; preload", w.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "default-src 'self'", w.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, w.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+
+	// max-age=0 is sent to expire the policy, -1 omits the header.
+	maxAge = 0
+	router = New(Secure(SecureOptions{HSTSMaxAge: &maxAge}))
+	router.GET("/example", func(c *Context) {})
+	w = performCorsRequest(router, "GET", "", "X-Forwarded-Proto", "https")
+	assert.Equal(t, "max-age=0", w.Header().Get("Strict-Transport-Security"))
+
+	maxAge = -1
+	router = New(Secure(SecureOptions{HSTSMaxAge: &maxAge}))
+	router.GET("/example", func(c *Context) {})
+	w = performCorsRequest(router, "GET", "", "X-Forwarded-Proto", "https")
+	assert.Empty(t, w.Header().Get("Strict-Transport-Security"))
+}
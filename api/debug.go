@@ -72,6 +72,9 @@ func debugPrintRoute(httpMethod, absolutePath string, handlers []Handler) {
 	if IsDebugging() {
 		nuHandlers := len(handlers)
 		handlerName := FunctionName(LastHandler(handlers))
+		if fn := FuncOf(LastHandler(handlers)); fn != nil {
+			handlerName = FunctionName(fn)
+		}
 		debugPrint("%-6s %-25s --> %s (%d handlers)\n", httpMethod, absolutePath, handlerName, nuHandlers)
 	}
 }
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ETagMaxSize is the maximum size of bodies buffered by ETag,
+// larger bodies are written without ETag.
+var ETagMaxSize = 4 << 20 // 4 MB
+
+// ETag returns a middleware buffering the 200 responses of GET requests to
+// set weak ETags, 304 is replied if the ETag matches the If-None-Match header.
+// Responses flushed by Context.Stream, larger than ETagMaxSize or with an
+// ETag already set are written as they are.
+// Use it after Compress to tag the uncompressed bodies, for example:
+//	serv.Use(api.Compress(api.CompressOptions{}), api.ETag())
+func ETag() Handler {
+	return func(c *Context) {
+		if c.Request.Method != "GET" {
+			return
+		}
+
+		w := c.Writer
+		ew := &etagWriter{ResponseWriter: w, size: noWritten}
+		c.Writer = ew
+		defer func() {
+			c.Writer = w
+			ew.close(c.Request.Header.Get("If-None-Match"))
+		}()
+		c.Next()
+	}
+}
+
+var _ ResponseWriter = &etagWriter{}
+
+// etagWriter buffers the body until the handlers return.
+type etagWriter struct {
+	ResponseWriter
+	buf         []byte
+	passThrough bool
+	size        int
+}
+
+func (w *etagWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.size = 0
+	}
+	if w.Status() != http.StatusOK || w.Header().Get("ETag") != "" {
+		w.pass()
+	}
+}
+
+func (w *etagWriter) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	w.size += len(data)
+	if !w.passThrough {
+		if len(w.buf)+len(data) <= ETagMaxSize {
+			w.buf = append(w.buf, data...)
+			return len(data), nil
+		}
+		w.pass()
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *etagWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *etagWriter) Size() int {
+	return w.size
+}
+
+func (w *etagWriter) Written() bool {
+	return w.size != noWritten
+}
+
+// Flush writes the body without ETag.
+func (w *etagWriter) Flush() {
+	if w.Written() {
+		w.pass()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// pass writes the buffered body and the rest of the body is written directly.
+func (w *etagWriter) pass() {
+	if w.passThrough {
+		return
+	}
+	w.passThrough = true
+	w.ResponseWriter.WriteHeaderNow()
+	if len(w.buf) > 0 {
+		w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+	}
+}
+
+// close sets the ETag of the buffered body and writes the response.
+func (w *etagWriter) close(ifNoneMatch string) {
+	if !w.Written() || w.passThrough {
+		return
+	}
+
+	h := fnv.New64a()
+	h.Write(w.buf)
+	etag := `W/"` + strconv.FormatUint(h.Sum64(), 36) + "-" + strconv.Itoa(len(w.buf)) + `"`
+	w.Header().Set("ETag", etag)
+	if etagMatch(ifNoneMatch, etag) {
+		w.buf = nil
+		hdr := w.Header()
+		hdr.Del("Content-Type")
+		hdr.Del("Content-Length")
+		w.ResponseWriter.WriteHeader(http.StatusNotModified)
+	}
+	w.pass()
+}
+
+// etagMatch reports whether the If-None-Match header matches etag by the
+// weak comparison, see https://tools.ietf.org/html/rfc7232#section-3.2.
+func etagMatch(ifNoneMatch, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETag(t *testing.T) {
+	body := "hello world"
+	router := New(ETag())
+	router.GET("/hello", func(c *Context) {
+		c.Writer.Header().Set("Content-Type", "text/plain")
+		c.Reply(http.StatusOK, body)
+	})
+	router.GET("/tagged", func(c *Context) {
+		c.Writer.Header().Set("ETag", `"v1"`)
+		c.Reply(http.StatusOK, body)
+	})
+	router.GET("/missing", func(c *Context) { c.Reply(http.StatusNotFound, body) })
+	router.GET("/flush", func(c *Context) {
+		io.WriteString(c.Writer, body)
+		c.Writer.Flush()
+	})
+	router.POST("/hello", func(c *Context) { c.Reply(http.StatusOK, body) })
+
+	w := performEncodedRequest(router, "GET", "/hello")
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, body, w.Body.String())
+	assert.Regexp(t, `^W/".+"$`, etag)
+
+	w = performEncodedRequest(router, "GET", "/hello", "If-None-Match", `"other", `+etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+	assert.Empty(t, w.Header().Get("Content-Type"))
+	assert.Zero(t, w.Body.Len())
+
+	// weak comparison
+	w = performEncodedRequest(router, "GET", "/hello", "If-None-Match", etag[2:])
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = performEncodedRequest(router, "GET", "/hello", "If-None-Match", `W/"other"`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, body, w.Body.String())
+
+	// written as they are
+	w = performEncodedRequest(router, "GET", "/tagged", "If-None-Match", etag)
+	assert.Equal(t, `"v1"`, w.Header().Get("ETag"))
+	assert.Equal(t, body, w.Body.String())
+
+	w = performEncodedRequest(router, "GET", "/missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+
+	w = performEncodedRequest(router, "GET", "/flush")
+	assert.Empty(t, w.Header().Get("ETag"))
+	assert.Equal(t, body, w.Body.String())
+
+	w = performEncodedRequest(router, "POST", "/hello")
+	assert.Empty(t, w.Header().Get("ETag"))
+}
+
+func TestETagWithCompress(t *testing.T) {
+	router := New(Compress(CompressOptions{MinLength: 1}), ETag())
+	router.GET("/hello", func(c *Context) { c.Reply(http.StatusOK, "hello world") })
+
+	w := performEncodedRequest(router, "GET", "/hello", "Accept-Encoding", "gzip")
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.NotEmpty(t, etag)
+
+	w = performEncodedRequest(router, "GET", "/hello", "Accept-Encoding", "gzip", "If-None-Match", etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Zero(t, w.Body.Len())
+}
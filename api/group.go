@@ -0,0 +1,157 @@
+package api
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// RouterGroup is used to configure a set of routes that share a common
+// url prefix and a common list of middleware.
+// Groups can be nested, a child group inherits the prefix and the
+// middleware of its parent.
+// For example:
+//     admin := serv.Group("/admin", se.SessionHandler, auth.AuthHandler)
+//     admin.GET("/usrs", m.GetUsrs)            // GET /admin/usrs
+//     v1 := admin.Group("/v1", ratelimiter)
+//     v1.POST("/usr", m.AddUsr)                // POST /admin/v1/usr
+type RouterGroup struct {
+	Handlers []Handler
+	basePath string
+	serv     *Server
+}
+
+// Group creates a new router group with the given url prefix and middleware.
+// The middleware will be called before the handlers of every route
+// registered through the group, but after the global middleware of the server.
+func (serv *Server) Group(prefix string, middleware ...Handler) *RouterGroup {
+	Assert(len(prefix) > 0 && prefix[0] == '/', "prefix must begin with '/'")
+	return &RouterGroup{
+		Handlers: append(([]Handler)(nil), middleware...),
+		basePath: prefix,
+		serv:     serv,
+	}
+}
+
+// Group creates a child group, the prefix and middleware of the child are
+// appended to the ones of the parent.
+func (group *RouterGroup) Group(prefix string, middleware ...Handler) *RouterGroup {
+	return &RouterGroup{
+		Handlers: group.combineHandlers(middleware),
+		basePath: group.absolutePath(prefix),
+		serv:     group.serv,
+	}
+}
+
+// BasePath returns the url prefix of the group.
+func (group *RouterGroup) BasePath() string {
+	return group.basePath
+}
+
+// Use adds middleware to the group, only routes registered after Use
+// will be affected.
+func (group *RouterGroup) Use(middleware ...Handler) {
+	group.Handlers = append(group.Handlers, middleware...)
+}
+
+// Handle registers a new request handle with the given method and the path
+// relative to the group, the middleware of the group is prepended to handlers.
+func (group *RouterGroup) Handle(method, url string, handlers ...Handler) *Route {
+	Assert(len(handlers) > 0, "there must be at least one handler")
+	return group.serv.Handle(method, group.absolutePath(url), group.combineHandlers(handlers)...)
+}
+
+// POST is a shortcut for group.Handle("POST", url, handle).
+func (group *RouterGroup) POST(url string, handlers ...Handler) *Route {
+	return group.Handle("POST", url, handlers...)
+}
+
+// GET is a shortcut for group.Handle("GET", url, handle).
+func (group *RouterGroup) GET(url string, handlers ...Handler) *Route {
+	return group.Handle("GET", url, handlers...)
+}
+
+// DELETE is a shortcut for group.Handle("DELETE", url, handle).
+func (group *RouterGroup) DELETE(url string, handlers ...Handler) *Route {
+	return group.Handle("DELETE", url, handlers...)
+}
+
+// PATCH is a shortcut for group.Handle("PATCH", url, handle).
+func (group *RouterGroup) PATCH(url string, handlers ...Handler) *Route {
+	return group.Handle("PATCH", url, handlers...)
+}
+
+// PUT is a shortcut for group.Handle("PUT", url, handle).
+func (group *RouterGroup) PUT(url string, handlers ...Handler) *Route {
+	return group.Handle("PUT", url, handlers...)
+}
+
+// OPTIONS is a shortcut for group.Handle("OPTIONS", url, handle).
+func (group *RouterGroup) OPTIONS(url string, handlers ...Handler) *Route {
+	return group.Handle("OPTIONS", url, handlers...)
+}
+
+// HEAD is a shortcut for group.Handle("HEAD", url, handle).
+func (group *RouterGroup) HEAD(url string, handlers ...Handler) *Route {
+	return group.Handle("HEAD", url, handlers...)
+}
+
+// ANY registers a route that matches all the HTTP methods.
+// GET, POST, PUT, PATCH, HEAD, OPTIONS, DELETE, CONNECT, TRACE.
+func (group *RouterGroup) ANY(url string, handlers ...Handler) *Route {
+	return group.Handle("ANY", url, handlers...)
+}
+
+// StaticFile registers a single route in order to server a single file of the local filesystem.
+// group.StaticFile("favicon.ico", "./resources/favicon.ico")
+func (group *RouterGroup) StaticFile(url, filepath string) {
+	if strings.Contains(url, ":") || strings.Contains(url, "*") {
+		panic("URL parameters can not be used when serving a static file")
+	}
+	handler := func(c *Context) {
+		http.ServeFile(c.Writer, c.Request, filepath)
+	}
+	group.GET(url, handler)
+	group.HEAD(url, handler)
+	return
+}
+
+// Static serves files from the given file system root, the middleware of
+// the group is called before serving the file.
+//     admin.Static("/static", "./tmp/files")
+func (group *RouterGroup) Static(urlPrefix, root string) {
+	if strings.Contains(urlPrefix, ":") || strings.Contains(urlPrefix, "*") {
+		panic("URL parameters can not be used when serving a static folder")
+	}
+	handler := staticHandler(group.absolutePath(urlPrefix), root)
+
+	url := path.Join(urlPrefix, "/*filepath")
+	// Register GET and HEAD handlers
+	group.GET(url, handler)
+	group.HEAD(url, handler)
+	return
+}
+
+// AddRoutes registers routes relative to the group.
+func (group *RouterGroup) AddRoutes(routes Routes) {
+	for i := range routes {
+		r := group.Handle(routes[i].Method, routes[i].Url, routes[i].Handlers...)
+		if routes[i].Name != "" {
+			r.Named(routes[i].Name)
+		}
+	}
+	return
+}
+
+func (group *RouterGroup) combineHandlers(handlers []Handler) []Handler {
+	size := len(group.Handlers) + len(handlers)
+	Assert(size < int(abortIndex), "too many handlers")
+	merged := make([]Handler, size)
+	copy(merged, group.Handlers)
+	copy(merged[len(group.Handlers):], handlers)
+	return merged
+}
+
+func (group *RouterGroup) absolutePath(relativePath string) string {
+	return joinPaths(group.basePath, relativePath)
+}
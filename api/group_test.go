@@ -0,0 +1,172 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouterGroupBasic(t *testing.T) {
+	router := New()
+	group := router.Group("/hola", func(c *Context) {})
+	group.Use(func(c *Context) {})
+
+	assert.Len(t, group.Handlers, 2)
+	assert.Equal(t, "/hola", group.BasePath())
+
+	group2 := group.Group("manu")
+	group2.Use(func(c *Context) {}, func(c *Context) {})
+
+	assert.Len(t, group2.Handlers, 4)
+	assert.Equal(t, "/hola/manu", group2.BasePath())
+}
+
+func TestRouterGroupMiddleware(t *testing.T) {
+	signature := ""
+	router := New(func(c *Context) {
+		signature += "A"
+	})
+	admin := router.Group("/admin", func(c *Context) {
+		signature += "B"
+	})
+	v1 := admin.Group("/v1", func(c *Context) {
+		signature += "C"
+	})
+	v1.GET("/usr/:id", func(c *Context) {
+		signature += "D"
+		c.Reply(http.StatusOK, c.Params.ByName("id"))
+	})
+	admin.POST("/usr", func(c *Context) {
+		signature += "E"
+	})
+
+	w := performRequest(router, "GET", "/admin/v1/usr/42")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "42", w.Body.String())
+	assert.Equal(t, "ABCD", signature)
+
+	signature = ""
+	performRequest(router, "POST", "/admin/usr")
+	assert.Equal(t, "ABE", signature)
+
+	// the middleware of a child group must not leak into the parent
+	signature = ""
+	w = performRequest(router, "GET", "/admin/usr")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "A", signature)
+}
+
+func TestRouterGroupAbort(t *testing.T) {
+	router := New()
+	passed := false
+	group := router.Group("/api", func(c *Context) {
+		c.Reply(http.StatusUnauthorized, "unauthorized")
+	})
+	group.GET("/usr", func(c *Context) {
+		passed = true
+	})
+
+	w := performRequest(router, "GET", "/api/usr")
+	assert.False(t, passed)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "unauthorized", w.Body.String())
+}
+
+func TestRouterGroupMethods(t *testing.T) {
+	router := New()
+	group := router.Group("/v1")
+	for _, method := range []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"} {
+		group.Handle(method, "/test", func(c *Context) {
+			c.Reply(http.StatusOK, c.Request.Method)
+		})
+	}
+	group.ANY("/any", func(c *Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for _, method := range []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"} {
+		w := performRequest(router, method, "/v1/test")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, method, w.Body.String())
+
+		w = performRequest(router, method, "/v1/any")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	}
+}
+
+func TestRouterGroupAddRoutes(t *testing.T) {
+	var rs Routes
+	rs.Add("GET:/usr/:id", func(c *Context) {
+		c.Reply(http.StatusOK, "usr"+c.Params.ByName("id"))
+	})
+	rs.Add("DELETE:/usr/:id", func(c *Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	router := New()
+	group := router.Group("/api", func(c *Context) {
+		c.Writer.Header().Set("X-Group", "api")
+	})
+	group.AddRoutes(rs)
+
+	w := performRequest(router, "GET", "/api/usr/1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "usr1", w.Body.String())
+	assert.Equal(t, "api", w.Header().Get("X-Group"))
+
+	w = performRequest(router, "DELETE", "/api/usr/1")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	routes := router.GetRoutes()
+	assert.Len(t, routes, 2)
+	for _, r := range routes {
+		assert.Equal(t, "/api/usr/:id", r.Url)
+		assert.Len(t, r.Handlers, 2)
+	}
+}
+
+func TestRouterGroupStatic(t *testing.T) {
+	router := New()
+	passed := false
+	group := router.Group("/files", func(c *Context) {
+		passed = true
+	})
+	group.Static("/src", "./")
+	group.StaticFile("/server", "./server.go")
+
+	w := performRequest(router, "GET", "/files/src/group.go")
+	assert.True(t, passed)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "package api")
+
+	w = performRequest(router, "GET", "/files/server")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "package api")
+
+	w = performRequest(router, "GET", "/files/src/nonexistent")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRouterGroupInvalidPrefix(t *testing.T) {
+	router := New()
+	assert.Panics(t, func() {
+		router.Group("api")
+	})
+	assert.Panics(t, func() {
+		router.Group("/api").Static("/:id", "./")
+	})
+}
+
+func TestJoinPaths(t *testing.T) {
+	assert.Equal(t, "", joinPaths("", ""))
+	assert.Equal(t, "/", joinPaths("", "/"))
+	assert.Equal(t, "/a", joinPaths("/a", ""))
+	assert.Equal(t, "/a/", joinPaths("/a/", ""))
+	assert.Equal(t, "/a/", joinPaths("/a/", "/"))
+	assert.Equal(t, "/a/", joinPaths("/a", "/"))
+	assert.Equal(t, "/a/hola", joinPaths("/a", "/hola"))
+	assert.Equal(t, "/a/hola", joinPaths("/a/", "/hola"))
+	assert.Equal(t, "/a/hola/", joinPaths("/a/", "/hola/"))
+	assert.Equal(t, "/a/hola/", joinPaths("/a/", "/hola//"))
+}
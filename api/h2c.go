@@ -0,0 +1,23 @@
+//go:build go1.24
+
+package api
+
+import "net/http"
+
+// RunH2C attaches the router to a http.Server and starts listening and serving HTTP/1 and
+// HTTP/2 cleartext (h2c) requests, which is usually used behind a proxy terminating TLS.
+// Note: this method will block the calling goroutine until Shutdown is called or an error happens.
+func (serv *Server) RunH2C(addr string) (err error) {
+	defer func() { debugPrintError(err) }()
+
+	hs, err := serv.newHTTPServer(addr)
+	if err != nil {
+		return
+	}
+	hs.Protocols = new(http.Protocols)
+	hs.Protocols.SetHTTP1(true)
+	hs.Protocols.SetUnencryptedHTTP2(true)
+	debugPrint("Listening and serving HTTP and h2c on %s\n", addr)
+	err = ignoreServerClosed(hs.ListenAndServe())
+	return
+}
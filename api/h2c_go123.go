@@ -0,0 +1,13 @@
+//go:build !go1.24
+
+package api
+
+import "errors"
+
+// RunH2C serves HTTP/2 cleartext by http.Protocols, which requires Go 1.24.
+// It returns an error on earlier versions.
+func (serv *Server) RunH2C(addr string) error {
+	err := errors.New("api: RunH2C requires Go 1.24 or later")
+	debugPrintError(err)
+	return err
+}
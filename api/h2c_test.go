@@ -0,0 +1,39 @@
+//go:build go1.24
+
+package api
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunH2C(t *testing.T) {
+	router := New()
+	router.GET("/example", func(c *Context) { c.Reply(http.StatusOK, c.Request.Proto) })
+	go func() {
+		assert.NoError(t, router.RunH2C(":5151"))
+	}()
+	time.Sleep(10 * time.Millisecond)
+	defer router.Shutdown(context.Background())
+
+	// HTTP/1 is still served.
+	resp, err := http.Get("http://localhost:5151/example")
+	assert.NoError(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "HTTP/1.1", string(body))
+
+	tr := &http.Transport{Protocols: new(http.Protocols)}
+	tr.Protocols.SetUnencryptedHTTP2(true)
+	defer tr.CloseIdleConnections()
+	resp, err = (&http.Client{Transport: tr}).Get("http://localhost:5151/example")
+	assert.NoError(t, err)
+	body, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "HTTP/2.0", string(body))
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/zltgo/api/bind"
+	"github.com/zltgo/api/render"
+)
+
+// Handle wraps a typed function to api handler without reflection on the
+// function per request, which is faster than H. In is bound by Context.Bind
+// and validated, Out is replied as 200 in the format negotiated with the
+// Accept header. For example:
+//	serv.GET("/app/goods", api.Handle(func(ctx *api.Context, in GoodsQuery) ([]Goods, error) {
+//		return m.FindGoods(in)
+//	}))
+// Validation errors are replied as 400 by Context.Error, other errors of
+// binding In are replied as 400 and the errors returned by fn are replied
+// as 500, the errors are attached to ctx.Errors for logging.
+// In can be a struct or a pointer to struct, struct{} means no input.
+func Handle[In, Out any](fn func(*Context, In) (Out, error)) Handler {
+	if fn == nil {
+		panic("input function can't be nil")
+	}
+
+	// the type of In is reflected only once.
+	inType := reflect.TypeOf((*In)(nil)).Elem()
+	isPtr := inType.Kind() == reflect.Ptr
+	if isPtr {
+		inType = inType.Elem()
+	}
+	if inType.Kind() != reflect.Struct {
+		panic("api: input type of Handle must be a struct or a pointer to struct, got " + inType.String())
+	}
+	noInput := inType.NumField() == 0
+
+	return funcHandler(fn, func(ctx *Context) {
+		var in In
+		if !noInput {
+			var ptr interface{} = &in
+			if isPtr {
+				ptr = reflect.New(inType).Interface()
+				in = ptr.(In)
+			}
+			if err := ctx.Bind(ptr); err != nil {
+				ctx.Error(err)
+				if !bind.IsValidationError(err) {
+					replyError(ctx, http.StatusBadRequest)
+				}
+				return
+			}
+		}
+
+		out, err := fn(ctx, in)
+		if err != nil {
+			ctx.Error(err)
+			if !bind.IsValidationError(err) {
+				replyError(ctx, http.StatusInternalServerError)
+			}
+			return
+		}
+		ctx.Reply(http.StatusOK, out)
+	})
+}
+
+// replyError replies ErrorBody of code in JSON.
+func replyError(ctx *Context, code int) {
+	ctx.Reply(code, render.JSON{Data: ErrorBody{
+		Code:    code,
+		Message: http.StatusText(code),
+	}})
+}
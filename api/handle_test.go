@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type goodsQuery struct {
+	Id   int    `form:"id" json:"id"`
+	Name string `form:"name" json:"name" validate:"max=5"`
+}
+
+type goods struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestHandle(t *testing.T) {
+	find := func(ctx *Context, in goodsQuery) (goods, error) {
+		if in.Name == "fail" {
+			return goods{}, errors.New("db is down")
+		}
+		return goods{in.Id, in.Name}, nil
+	}
+
+	router := New()
+	router.GET("/goods/:id", Handle(find))
+	router.POST("/goods", Handle(func(ctx *Context, in *goodsQuery) (*goods, error) {
+		return &goods{in.Id, in.Name}, nil
+	}))
+	router.GET("/ping", Handle(func(*Context, struct{}) (string, error) { return "pong", nil }))
+
+	w := performRequest(router, "GET", "/goods/12?name=apple")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":12,"name":"apple"}`, w.Body.String())
+
+	req, _ := http.NewRequest("POST", "/goods", strings.NewReader(`{"id":1,"name":"pear"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":1,"name":"pear"}`, w.Body.String())
+
+	w = performRequest(router, "GET", "/ping")
+	assert.Equal(t, "pong", w.Body.String())
+
+	// validation failed
+	var body ErrorBody
+	w = performRequest(router, "GET", "/goods/12?name=banana")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "name", body.Errors[0].Field)
+
+	// malformed body
+	req, _ = http.NewRequest("POST", "/goods", strings.NewReader(`{"id":`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// errors returned by fn
+	w = performRequest(router, "GET", "/goods/12?name=fail")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	body = ErrorBody{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, ErrorBody{Code: 500, Message: "Internal Server Error"}, body)
+	assert.NotContains(t, w.Body.String(), "db is down")
+
+	// the function is reflected by FuncOf and kept by the route
+	for _, r := range router.GetRoutes() {
+		if r.Url == "/goods/:id" {
+			assert.NotNil(t, FuncOf(LastHandler(r.Handlers)))
+			assert.NotNil(t, r.Funcs[len(r.Funcs)-1])
+		}
+	}
+	assert.Nil(t, FuncOf(func(*Context) {}))
+	assert.Nil(t, FuncOf(H(func(*Context) {})))
+	fn := func(ctx *Context) int { return 1 }
+	assert.Equal(t, reflect.ValueOf(fn).Pointer(), reflect.ValueOf(FuncOf(H(fn))).Pointer())
+
+	assert.Panics(t, func() { Handle(func(*Context, int) (int, error) { return 0, nil }) })
+}
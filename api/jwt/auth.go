@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 	"reflect"
+	"time"
 
 	"github.com/zltgo/api"
 	"github.com/zltgo/reflectx"
@@ -18,6 +19,8 @@ var (
 	KeyUserId    = "_uid"
 	KeyAgentHash = "_agh"
 	KeyGrantType = "_grt"
+	KeyFamilyId  = "_fid"
+	KeyIssuedAt  = "_iat"
 	TokenKeys    = []string{"ACCESS-TOKEN", "REFRESH-TOKEN"}
 )
 
@@ -40,6 +43,10 @@ type Auth struct {
 	// Interface for extracting a token from an HTTP request.
 	// Optional, default is HeaderGetter([]string{TokenKey})
 	tg TokenGetter
+
+	// revoker stores the revoked tokens, tokens can not be revoked if nil.
+	// Optional, see SetRevoker.
+	revoker Revoker
 }
 
 type AuthOpts struct {
@@ -145,17 +152,42 @@ func (m *Auth) LoginHandler(loginFunc interface{}) api.Handler {
 	}
 }
 
+// SetRevoker enables the revocation of tokens by rv. Tokens have the "jti",
+// KeyIssuedAt in milliseconds and KeyFamilyId values, and the refresh token is
+// rotated by RefreshHandler: the old one is revoked and reusing it revokes all
+// the tokens of the same login.
+// Both the access and refresh tokens must have a MaxAge, or the records of
+// the revoked tokens can never be purged.
+// Set it before serving, the handlers read the revoker without locks.
+func (m *Auth) SetRevoker(rv Revoker) {
+	if m.access.MaxAge() <= 0 || m.refresh.MaxAge() <= 0 {
+		panic("jwt: tokens must have a MaxAge to be revoked")
+	}
+	m.revoker = rv
+}
+
 // Create a new auth token
 func (m *Auth) NewAuthToken(uid string, r *http.Request) (*AuthToken, error) {
 	vs := values.JsonMap{}
 	vs.Set(KeyUserId, uid)
 	vs.Set(KeyAgentHash, Hash64(r.UserAgent()))
+	if m.revoker != nil {
+		// the tokens of a login are in the same family.
+		vs.Set(KeyFamilyId, RandString(16))
+	}
+	return m.newAuthToken(vs)
+}
+
+// newAuthToken creates an access token and a refresh token of vs.
+func (m *Auth) newAuthToken(vs values.JsonMap) (*AuthToken, error) {
+	m.renew(vs)
 	vs.Set(KeyGrantType, "access")
 	acc, err := m.access.CreateToken(vs)
 	if err != nil {
 		return nil, errors.New("jwt: " + err.Error())
 	}
 
+	m.renew(vs)
 	vs.Set(KeyGrantType, "refresh")
 	ref, err := m.refresh.CreateToken(vs)
 	if err != nil {
@@ -169,6 +201,14 @@ func (m *Auth) NewAuthToken(uid string, r *http.Request) (*AuthToken, error) {
 	}, nil
 }
 
+// renew sets the id and issued time of a new token if revoker is set.
+func (m *Auth) renew(vs values.JsonMap) {
+	if m.revoker != nil {
+		vs.Set(ClaimId, RandString(16))
+		vs.Set(KeyIssuedAt, unixMilli(TimeNow()))
+	}
+}
+
 // RefreshHandler can be used to refresh a token.The RefreshToken needs to be passed in
 // the Authentication header. Example: r.Header.Set("REFRESH-TOKEN", "your-refresh-token-got-by-login")
 func (m *Auth) RefreshHandler(ctx *api.Context) {
@@ -197,6 +237,21 @@ func (m *Auth) RefreshHandler(ctx *api.Context) {
 		return
 	}
 
+	if m.revoker != nil {
+		// rotate the refresh token.
+		if code, err := m.rotate(vs); err != nil {
+			ctx.Reply(code, err)
+			return
+		}
+		authToken, err := m.newAuthToken(vs)
+		if err != nil {
+			ctx.Reply(http.StatusInternalServerError, err)
+			return
+		}
+		ctx.Reply(http.StatusOK, authToken)
+		return
+	}
+
 	//create access token
 	vs.Set(KeyGrantType, "access")
 	acc, err := m.access.CreateToken(vs)
@@ -213,6 +268,75 @@ func (m *Auth) RefreshHandler(ctx *api.Context) {
 	return
 }
 
+// rotate revokes the refresh token of vs. If it is revoked already, someone
+// is reusing it, such as a stolen token, so the whole family is revoked.
+func (m *Auth) rotate(vs values.JsonMap) (int, error) {
+	uid := vs.ValueOf(KeyUserId).String()
+	fid := vs.ValueOf(KeyFamilyId).String()
+	jti := vs.ValueOf(ClaimId).String()
+	if jti == "" || fid == "" {
+		return http.StatusUnauthorized, ErrRevoked
+	}
+
+	revoked, err := m.revoker.Revoked(uid, issuedAt(vs), fid)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if revoked {
+		return http.StatusUnauthorized, ErrRevoked
+	}
+
+	// only one of the concurrent refreshes can revoke jti.
+	ok, err := m.revoker.RevokeToken(jti, expiresAt(m.refresh, issuedAt(vs)))
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if !ok {
+		if _, err = m.revoker.RevokeToken(fid, expiresAt(m.refresh, TimeNow())); err != nil {
+			return http.StatusInternalServerError, err
+		}
+		return http.StatusUnauthorized, ErrRevoked
+	}
+	return http.StatusOK, nil
+}
+
+// LogoutHandler revokes the access token in request and the tokens of the same
+// login, including the refresh token. It replies 204 on success.
+func (m *Auth) LogoutHandler(ctx *api.Context) {
+	if m.revoker == nil {
+		ctx.Reply(http.StatusInternalServerError, errors.New("jwt: revoker is not set"))
+		return
+	}
+	vs, err := m.parseAccess(ctx.Request)
+	if err != nil {
+		ctx.Reply(http.StatusUnauthorized, err)
+		return
+	}
+
+	if jti := vs.ValueOf(ClaimId).String(); jti != "" {
+		if _, err = m.revoker.RevokeToken(jti, expiresAt(m.access, issuedAt(vs))); err != nil {
+			ctx.Reply(http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if fid := vs.ValueOf(KeyFamilyId).String(); fid != "" {
+		if _, err = m.revoker.RevokeToken(fid, expiresAt(m.refresh, TimeNow())); err != nil {
+			ctx.Reply(http.StatusInternalServerError, err)
+			return
+		}
+	}
+	ctx.Reply(http.StatusNoContent, nil)
+}
+
+// RevokeUser revokes all the tokens of uid issued before now,
+// such as the user changed password or was deleted.
+func (m *Auth) RevokeUser(uid string) error {
+	if m.revoker == nil {
+		return errors.New("jwt: revoker is not set")
+	}
+	return m.revoker.RevokeUser(uid, TimeNow())
+}
+
 // AuthHandler provides a Json-Web-Token authentication implementation. On failure, a 401 HTTP response
 // is returned. On success, the wrapped middleware is called, and the userId is made available as
 // yourhandler(uid UID), note that uid can convert to string.
@@ -230,31 +354,63 @@ func (m *Auth) AuthHandler(ctx *api.Context) {
 
 // Get uid from token in http.Request.
 func (m *Auth) AuthFunc(r *http.Request) (string, error) {
+	vs, err := m.parseAccess(r)
+	if err != nil {
+		return "", err
+	}
+
+	uid := vs.ValueOf(KeyUserId).String()
+	if uid == "" {
+		return "", ErrNoUid
+	}
+
+	if m.revoker != nil {
+		revoked, err := m.revoker.Revoked(uid, issuedAt(vs),
+			vs.ValueOf(ClaimId).String(), vs.ValueOf(KeyFamilyId).String())
+		if err != nil {
+			return "", err
+		}
+		if revoked {
+			return "", ErrRevoked
+		}
+	}
+	return uid, nil
+}
+
+// parseAccess parses and validates the access token in r.
+func (m *Auth) parseAccess(r *http.Request) (values.JsonMap, error) {
 	//get access token
 	token, err := m.tg.GetToken(r)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	//parse token
 	var vs values.JsonMap
-	vs, err = m.refresh.ParseToken(token)
+	vs, err = m.access.ParseToken(token)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// validate grant type and user agent
 	grt := vs.ValueOf(KeyGrantType).String()
 	if grt != "access" {
-		return "", errors.New("jwt: grant type mismatched: expect access, got " + grt)
+		return nil, errors.New("jwt: grant type mismatched: expect access, got " + grt)
 	}
 	if vs.ValueOf(KeyAgentHash).String() != Hash64(r.UserAgent()) {
-		return "", errors.New("jwt: user agent mismatched: " + r.UserAgent())
+		return nil, errors.New("jwt: user agent mismatched: " + r.UserAgent())
 	}
+	return vs, nil
+}
 
-	uid := vs.ValueOf(KeyUserId).String()
-	if uid == "" {
-		return "", ErrNoUid
+// issuedAt returns the KeyIssuedAt value of vs, or the "iat" value if absent.
+func issuedAt(vs values.JsonMap) time.Time {
+	if ms := vs.ValueOf(KeyIssuedAt).Int64(); ms > 0 {
+		return time.Unix(ms/1000, ms%1000*int64(time.Millisecond))
 	}
+	return time.Unix(vs.ValueOf(ClaimIssuedAt).Int64(), 0)
+}
 
-	return uid, nil
+// expiresAt returns the expiry of tokens of p issued at t.
+func expiresAt(p Parser, t time.Time) time.Time {
+	return t.Add(time.Duration(p.MaxAge()) * time.Second)
 }
@@ -0,0 +1,299 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/zltgo/reflectx"
+	"github.com/zltgo/reflectx/values"
+)
+
+var (
+	ErrMalformed = errors.New("jwt: token is malformed")
+	ErrAlgorithm = errors.New("jwt: signing algorithm mismatched")
+	ErrNotBefore = errors.New("jwt: token is not valid yet")
+	ErrIssuer    = errors.New("jwt: issuer mismatched")
+	ErrAudience  = errors.New("jwt: audience mismatched")
+)
+
+// The registered claim names of RFC 7519.
+const (
+	ClaimIssuer    = "iss"
+	ClaimSubject   = "sub"
+	ClaimAudience  = "aud"
+	ClaimExpires   = "exp"
+	ClaimNotBefore = "nbf"
+	ClaimIssuedAt  = "iat"
+	ClaimId        = "jti"
+)
+
+// registeredClaims are set by JWTParser rather than the values of tokens.
+var registeredClaims = map[string]bool{
+	ClaimIssuer:    true,
+	ClaimSubject:   true,
+	ClaimAudience:  true,
+	ClaimExpires:   true,
+	ClaimNotBefore: true,
+	ClaimIssuedAt:  true,
+	ClaimId:        true,
+}
+
+// JWTOpts configures JWTParser, zero fields are set to default values.
+type JWTOpts struct {
+	// Issuer is the "iss" claim, tokens of other issuers are rejected if set.
+	Issuer string
+
+	// Audience is set to the "aud" claim, the "aud" claim of tokens must
+	// contain one of them if set.
+	Audience []string
+
+	// MaxAge>0 means the "exp" claim is MaxAge seconds after "iat".
+	MaxAge int `default:"1800"`
+
+	// Leeway in seconds is allowed for the clock skew when checking
+	// "exp", "nbf" and "iat".
+	Leeway int `default:"60"`
+}
+
+var _ Parser = &JWTParser{}
+
+// JWTParser creates and parses JSON Web Tokens (RFC 7519) signed by the
+// current key of KeySet, the tokens can be verified by third-party services
+// with the public keys of JWKSHandler. It can be used by Auth, for example:
+//	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+//	keys, _ := jwt.NewKeySet(jwt.Key{Id: "2024-01", Alg: jwt.ES256, Private: key})
+//	auth := jwt.NewAuth(
+//		jwt.NewJWTParser(keys, jwt.JWTOpts{Issuer: "api.example.com", Audience: []string{"access"}, MaxAge: 1800}),
+//		jwt.NewJWTParser(keys, jwt.JWTOpts{Issuer: "api.example.com", Audience: []string{"refresh"}, MaxAge: 30 * 86400}),
+//		nil)
+// The access and refresh parsers sharing a KeySet must have distinct Audience,
+// or a refresh token would be accepted as an access token.
+// The values of KeyUserId is also set to the "sub" claim, and the "sub" claim of
+// tokens is returned as KeyUserId if it is absent.
+type JWTParser struct {
+	keys *KeySet
+	opts JWTOpts
+}
+
+func NewJWTParser(keys *KeySet, opts JWTOpts) *JWTParser {
+	reflectx.SetDefault(&opts)
+	return &JWTParser{keys: keys, opts: opts}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// CreateToken signs the claims of mp by the current key. The registered claims
+// of mp are ignored, mp may be the claims of another token such as a refresh
+// token: "iss" and "aud" are set by the options, "sub" by KeyUserId, "iat",
+// "exp" and a random "jti" are always renewed.
+func (m *JWTParser) CreateToken(mp map[string]interface{}) (string, error) {
+	key, err := m.keys.Current()
+	if err != nil {
+		return "", err
+	}
+
+	now := TimeNow().Unix()
+	claims := make(map[string]interface{}, len(mp)+6)
+	for k, v := range mp {
+		if !registeredClaims[k] {
+			claims[k] = v
+		}
+	}
+	if m.opts.Issuer != "" {
+		claims[ClaimIssuer] = m.opts.Issuer
+	}
+	switch len(m.opts.Audience) {
+	case 0:
+	case 1:
+		claims[ClaimAudience] = m.opts.Audience[0]
+	default:
+		claims[ClaimAudience] = m.opts.Audience
+	}
+	if uid, ok := mp[KeyUserId].(string); ok {
+		claims[ClaimSubject] = uid
+	}
+	// iat, exp and jti are renewed, such as refreshing an access token.
+	claims[ClaimIssuedAt] = now
+	if m.opts.MaxAge > 0 {
+		claims[ClaimExpires] = now + int64(m.opts.MaxAge)
+	}
+	claims[ClaimId] = RandString(16)
+
+	header, err := json.Marshal(jwtHeader{Alg: key.Alg, Typ: "JWT", Kid: key.Id})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", errors.New("jwt: " + err.Error())
+	}
+	input := b64(header) + "." + b64(payload)
+	sig, err := sign(key, []byte(input))
+	if err != nil {
+		return "", err
+	}
+	return input + "." + b64(sig), nil
+}
+
+// ParseToken verifies tk by the key of its "kid" header, and validates
+// the standard claims.
+func (m *JWTParser) ParseToken(tk string) (map[string]interface{}, error) {
+	parts := strings.Split(tk, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrMalformed
+	}
+	key, err := m.keys.Get(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	// the algorithm of the key is used, "none" or switching RS256 to HS256
+	// is never accepted.
+	if header.Alg != key.Alg {
+		return nil, ErrAlgorithm
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	if !verify(key, []byte(parts[0]+"."+parts[1]), sig) {
+		return nil, ErrMacInvalid
+	}
+
+	claims := values.JsonMap{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrMalformed
+	}
+	if err := m.validate(claims); err != nil {
+		return nil, err
+	}
+	if _, ok := claims[KeyUserId]; !ok {
+		if sub, ok := claims[ClaimSubject].(string); ok {
+			claims[KeyUserId] = sub
+		}
+	}
+	return claims, nil
+}
+
+// MaxAge returns the life time of tokens in seconds.
+func (m *JWTParser) MaxAge() int {
+	return m.opts.MaxAge
+}
+
+func (m *JWTParser) validate(claims values.JsonMap) error {
+	now := TimeNow().Unix()
+	leeway := int64(m.opts.Leeway)
+	if v := claims.ValueOf(ClaimExpires); !v.IsNil() && now > v.Int64()+leeway {
+		return ErrExpired
+	}
+	if v := claims.ValueOf(ClaimNotBefore); !v.IsNil() && now+leeway < v.Int64() {
+		return ErrNotBefore
+	}
+	if v := claims.ValueOf(ClaimIssuedAt); !v.IsNil() && now+leeway < v.Int64() {
+		return ErrTimestamp
+	}
+	if m.opts.Issuer != "" && claims.ValueOf(ClaimIssuer).String() != m.opts.Issuer {
+		return ErrIssuer
+	}
+	if len(m.opts.Audience) > 0 {
+		var auds []interface{}
+		switch aud := claims[ClaimAudience].(type) {
+		case string:
+			auds = []interface{}{aud}
+		case []interface{}:
+			auds = aud
+		}
+		for _, aud := range auds {
+			for _, want := range m.opts.Audience {
+				if aud == want {
+					return nil
+				}
+			}
+		}
+		return ErrAudience
+	}
+	return nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func sign(key Key, input []byte) ([]byte, error) {
+	switch key.Alg {
+	case HS256:
+		h := hmac.New(sha256.New, key.Secret)
+		h.Write(input)
+		return h.Sum(nil), nil
+	case EdDSA:
+		return key.Private.Sign(rand.Reader, input, crypto.Hash(0))
+	}
+
+	digest := sha256.Sum256(input)
+	switch key.Alg {
+	case RS256:
+		return key.Private.Sign(rand.Reader, digest[:], crypto.SHA256)
+	case ES256:
+		priv, ok := key.Private.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, ErrKeyInvalid
+		}
+		// the signature is r and s of 32 bytes, not ASN.1.
+		r, s, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig, nil
+	}
+	return nil, ErrAlgorithm
+}
+
+func verify(key Key, input, sig []byte) bool {
+	switch key.Alg {
+	case HS256:
+		return VerifyMac(hmac.New(sha256.New, key.Secret), input, sig)
+	case EdDSA:
+		pub, ok := key.Public.(ed25519.PublicKey)
+		return ok && ed25519.Verify(pub, input, sig)
+	}
+
+	digest := sha256.Sum256(input)
+	switch key.Alg {
+	case RS256:
+		pub, ok := key.Public.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+	case ES256:
+		pub, ok := key.Public.(*ecdsa.PublicKey)
+		if !ok || len(sig) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(pub, digest[:], r, s)
+	}
+	return false
+}
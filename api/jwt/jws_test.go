@@ -0,0 +1,291 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/zltgo/api"
+)
+
+func testKeys() []Key {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	return []Key{
+		{Id: "hs", Alg: HS256, Secret: []byte("01234567890123456789012345678901")},
+		{Id: "rs", Alg: RS256, Private: rsaKey},
+		{Id: "es", Alg: ES256, Private: ecKey},
+		{Id: "ed", Alg: EdDSA, Private: edKey},
+	}
+}
+
+func TestJWTParser(t *testing.T) {
+	TimeNow = timeFunc(1000, 1000)
+	keys := testKeys()
+
+	for _, key := range keys {
+		ks, err := NewKeySet(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := NewJWTParser(ks, JWTOpts{Issuer: "api", Audience: []string{"web", "app"}, MaxAge: 100})
+
+		Convey("create and parse a token of "+key.Alg, t, func() {
+			tk, err := p.CreateToken(map[string]interface{}{KeyUserId: "000001", "role": "admin"})
+			So(err, ShouldBeNil)
+			parts := strings.Split(tk, ".")
+			So(len(parts), ShouldEqual, 3)
+
+			var header jwtHeader
+			So(decodeSegment(parts[0], &header), ShouldBeNil)
+			So(header, ShouldResemble, jwtHeader{Alg: key.Alg, Typ: "JWT", Kid: key.Id})
+
+			claims, err := p.ParseToken(tk)
+			So(err, ShouldBeNil)
+			So(claims["iss"], ShouldEqual, "api")
+			So(claims["aud"], ShouldResemble, []interface{}{"web", "app"})
+			So(claims["sub"], ShouldEqual, "000001")
+			So(claims["iat"], ShouldEqual, 1000)
+			So(claims["exp"], ShouldEqual, 1100)
+			So(claims["jti"], ShouldHaveLength, 16)
+			So(claims["role"], ShouldEqual, "admin")
+
+			// the registered claims of the values are ignored.
+			tk, _ = p.CreateToken(map[string]interface{}{KeyUserId: "000001", "iss": "other", "aud": "other", "sub": "root", "exp": 2000})
+			claims, err = p.ParseToken(tk)
+			So(err, ShouldBeNil)
+			So(claims["iss"], ShouldEqual, "api")
+			So(claims["sub"], ShouldEqual, "000001")
+			So(claims["exp"], ShouldEqual, 1100)
+
+			// tampered payload
+			_, err = p.ParseToken(parts[0] + "." + b64([]byte(`{"sub":"root"}`)) + "." + parts[2])
+			So(err, ShouldEqual, ErrMacInvalid)
+		})
+	}
+
+	ks, _ := NewKeySet(keys[2])
+	p := NewJWTParser(ks, JWTOpts{Issuer: "api", Audience: []string{"web"}, MaxAge: 100, Leeway: 10})
+
+	Convey("validate standard claims", t, func() {
+		tk, _ := p.CreateToken(map[string]interface{}{})
+		TimeNow = timeFunc(1110, 1110)
+		_, err := p.ParseToken(tk)
+		So(err, ShouldBeNil)
+		TimeNow = timeFunc(1111, 1111)
+		_, err = p.ParseToken(tk)
+		So(err, ShouldEqual, ErrExpired)
+
+		TimeNow = timeFunc(1000, 1000)
+		// nbf is never taken from the values of CreateToken.
+		tk, _ = p.CreateToken(map[string]interface{}{"nbf": 1011})
+		_, err = p.ParseToken(tk)
+		So(err, ShouldBeNil)
+		tk = signClaims(keys[2], map[string]interface{}{"iss": "api", "aud": "web", "nbf": 1011})
+		_, err = p.ParseToken(tk)
+		So(err, ShouldEqual, ErrNotBefore)
+
+		other := NewJWTParser(ks, JWTOpts{Issuer: "other", Audience: []string{"web"}})
+		tk, _ = other.CreateToken(map[string]interface{}{})
+		_, err = p.ParseToken(tk)
+		So(err, ShouldEqual, ErrIssuer)
+
+		other = NewJWTParser(ks, JWTOpts{Issuer: "api", Audience: []string{"app"}})
+		tk, _ = other.CreateToken(map[string]interface{}{})
+		claims, err := p.ParseToken(tk)
+		So(err, ShouldEqual, ErrAudience)
+		So(claims, ShouldBeNil)
+	})
+
+	Convey("refresh tokens are not accepted as access tokens", t, func() {
+		TimeNow = timeFunc(1000, 1000)
+		access := NewJWTParser(ks, JWTOpts{Issuer: "api", Audience: []string{"access"}})
+		refresh := NewJWTParser(ks, JWTOpts{Issuer: "api", Audience: []string{"refresh"}})
+		tk, _ := refresh.CreateToken(map[string]interface{}{KeyUserId: "000001"})
+		claims, err := access.ParseToken(tk)
+		So(err, ShouldEqual, ErrAudience)
+		So(claims, ShouldBeNil)
+		_, err = refresh.ParseToken(tk)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("reject algorithm confusion and unknown keys", t, func() {
+		tk, _ := p.CreateToken(map[string]interface{}{})
+		parts := strings.Split(tk, ".")
+		header := b64([]byte(`{"alg":"none","kid":"es"}`))
+		_, err := p.ParseToken(header + "." + parts[1] + ".")
+		So(err, ShouldEqual, ErrAlgorithm)
+
+		header = b64([]byte(`{"alg":"ES256","kid":"unknown"}`))
+		_, err = p.ParseToken(header + "." + parts[1] + "." + parts[2])
+		So(err, ShouldEqual, ErrKeyNotFound)
+
+		_, err = p.ParseToken("not a token")
+		So(err, ShouldEqual, ErrMalformed)
+	})
+}
+
+func TestKeySetRotation(t *testing.T) {
+	TimeNow = timeFunc(1000, 1000)
+	keys := testKeys()
+	ks, err := NewKeySet(keys[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewJWTParser(ks, JWTOpts{})
+
+	Convey("invalid keys", t, func() {
+		So(ks.Add(Key{Alg: HS256, Secret: []byte("x")}), ShouldNotBeNil)
+		So(ks.Add(Key{Id: "x", Alg: "none"}), ShouldNotBeNil)
+		So(ks.Add(Key{Id: "x", Alg: ES256, Private: keys[1].Private}), ShouldEqual, ErrKeyInvalid)
+		So(ks.Rotate(Key{Id: "x", Alg: EdDSA, Public: keys[3].Private.Public()}), ShouldNotBeNil)
+	})
+
+	Convey("old tokens are valid after rotation", t, func() {
+		old, _ := p.CreateToken(map[string]interface{}{KeyUserId: "1"})
+		So(ks.Rotate(keys[3]), ShouldBeNil)
+		tk, _ := p.CreateToken(map[string]interface{}{KeyUserId: "2"})
+		So(tk, ShouldContainSubstring, b64([]byte(`{"alg":"EdDSA","typ":"JWT","kid":"ed"}`)))
+
+		claims, err := p.ParseToken(old)
+		So(err, ShouldBeNil)
+		So(claims[KeyUserId], ShouldEqual, "1")
+
+		// the current key can not be removed.
+		ks.Remove("ed")
+		ks.Remove("rs")
+		_, err = p.ParseToken(old)
+		So(err, ShouldEqual, ErrKeyNotFound)
+		_, err = p.ParseToken(tk)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("publish public keys", t, func() {
+		So(ks.Add(keys[0]), ShouldBeNil)
+		So(ks.Add(keys[1]), ShouldBeNil)
+		So(ks.Add(keys[2]), ShouldBeNil)
+
+		serv := api.New()
+		serv.GET("/.well-known/jwks.json", ks.JWKSHandler)
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+		serv.ServeHTTP(w, r)
+		So(w.Code, ShouldEqual, http.StatusOK)
+
+		var set JWKSet
+		So(json.Unmarshal(w.Body.Bytes(), &set), ShouldBeNil)
+		So(len(set.Keys), ShouldEqual, 3)
+		So(set.Keys[0].Kid, ShouldEqual, "ed")
+		So(set.Keys[0].Kty, ShouldEqual, "OKP")
+		So(set.Keys[0].X, ShouldEqual, b64(keys[3].Private.Public().(ed25519.PublicKey)))
+		So(set.Keys[1].Kid, ShouldEqual, "es")
+		So(set.Keys[1].Crv, ShouldEqual, "P-256")
+		So(set.Keys[2].Kid, ShouldEqual, "rs")
+		So(set.Keys[2].E, ShouldEqual, "AQAB")
+	})
+}
+
+func TestAuthWithJWT(t *testing.T) {
+	TimeNow = timeFunc(1000, 1000)
+	ks, _ := NewKeySet(testKeys()[2])
+	auth := NewAuth(NewJWTParser(ks, JWTOpts{MaxAge: 10}), NewJWTParser(ks, JWTOpts{MaxAge: 100}), nil)
+
+	Convey("Auth accepts JWT parsers", t, func() {
+		r, _ := http.NewRequest("GET", "/", nil)
+		tk, err := auth.NewAuthToken("000001", r)
+		So(err, ShouldBeNil)
+		So(tk.MaxAge, ShouldEqual, 10)
+
+		r.Header.Set("ACCESS-TOKEN", tk.AccessToken)
+		uid, err := auth.AuthFunc(r)
+		So(err, ShouldBeNil)
+		So(uid, ShouldEqual, "000001")
+
+		r.Header.Set("ACCESS-TOKEN", tk.RefreshToken)
+		_, err = auth.AuthFunc(r)
+		So(err, ShouldNotBeNil)
+	})
+}
+
+// signClaims signs claims by key as they are.
+func signClaims(key Key, claims map[string]interface{}) string {
+	header, _ := json.Marshal(jwtHeader{Alg: key.Alg, Typ: "JWT", Kid: key.Id})
+	payload, _ := json.Marshal(claims)
+	input := b64(header) + "." + b64(payload)
+	sig, _ := sign(key, []byte(input))
+	return input + "." + b64(sig)
+}
+
+func TestAuthJWTRefresh(t *testing.T) {
+	TimeNow = timeFunc(1000, 0)
+	ks, _ := NewKeySet(testKeys()[2])
+	newAuth := func() *Auth {
+		return NewAuth(
+			NewJWTParser(ks, JWTOpts{Issuer: "api", Audience: []string{"access"}, MaxAge: 1800}),
+			NewJWTParser(ks, JWTOpts{Issuer: "api", Audience: []string{"refresh"}, MaxAge: 86400}),
+			nil)
+	}
+	revoked := newAuth()
+	revoked.SetRevoker(NewMemRevoker())
+
+	for name, auth := range map[string]*Auth{"": newAuth(), " with a revoker": revoked} {
+		Convey("refreshed access tokens are accepted"+name, t, func() {
+			serv := api.New()
+			serv.GET("/refresh", auth.RefreshHandler)
+			authFunc := func(tk string) error {
+				r, _ := http.NewRequest("GET", "/", nil)
+				r.Header.Set("ACCESS-TOKEN", tk)
+				_, err := auth.AuthFunc(r)
+				return err
+			}
+
+			tk, err := auth.NewAuthToken("000001", &http.Request{Header: http.Header{}})
+			So(err, ShouldBeNil)
+			So(authFunc(tk.AccessToken), ShouldBeNil)
+			So(authFunc(tk.RefreshToken), ShouldEqual, ErrAudience)
+
+			r, _ := http.NewRequest("GET", "/refresh", nil)
+			r.Header.Set("ACCESS-TOKEN", tk.RefreshToken)
+			w := httptest.NewRecorder()
+			serv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			var next AuthToken
+			So(json.Unmarshal(w.Body.Bytes(), &next), ShouldBeNil)
+			So(authFunc(next.AccessToken), ShouldBeNil)
+		})
+	}
+}
+
+func TestAuthAccessMaxAge(t *testing.T) {
+	TimeNow = timeFunc(1000, 0)
+	auth := NewAuthByOpts(AuthOpts{AccessMaxAge: 10, RefreshMaxAge: 0})
+	serv := api.New()
+	serv.GET("/auth", auth.AuthHandler, func(ctx *api.Context) {
+		ctx.Reply(http.StatusOK, "ok")
+	})
+
+	Convey("access tokens expire in the access MaxAge", t, func() {
+		r, _ := http.NewRequest("GET", "/auth", nil)
+		tk, err := auth.NewAuthToken("000001", r)
+		So(err, ShouldBeNil)
+		r.Header.Set("ACCESS-TOKEN", tk.AccessToken)
+
+		w := httptest.NewRecorder()
+		serv.ServeHTTP(w, r)
+		So(w.Code, ShouldEqual, http.StatusOK)
+
+		TimeNow = timeFunc(1011, 0)
+		w = httptest.NewRecorder()
+		serv.ServeHTTP(w, r)
+		So(w.Code, ShouldEqual, http.StatusUnauthorized)
+	})
+}
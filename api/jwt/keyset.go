@@ -0,0 +1,223 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/zltgo/api"
+	"github.com/zltgo/api/render"
+)
+
+// Signing algorithms of JWT.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+	EdDSA = "EdDSA"
+)
+
+var (
+	ErrKeyNotFound = errors.New("jwt: signing key is not found")
+	ErrKeyInvalid  = errors.New("jwt: key does not match the algorithm")
+)
+
+// Key is a signing key of JWT identified by Id, which is the "kid" header.
+type Key struct {
+	Id  string
+	Alg string
+
+	// Secret is the key of HS256, it should have at least 32 bytes.
+	Secret []byte
+
+	// Private is the key of RS256, ES256 or EdDSA, which is *rsa.PrivateKey,
+	// *ecdsa.PrivateKey of P-256, or ed25519.PrivateKey.
+	// Public is used to verify tokens, it is set to Private.Public() if nil.
+	// Keys with Public only can verify tokens but not sign them.
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// check validates k and sets Public.
+func (k *Key) check() error {
+	if k.Id == "" {
+		return errors.New("jwt: key id can not be empty")
+	}
+	if k.Public == nil && k.Private != nil {
+		k.Public = k.Private.Public()
+	}
+
+	var ok bool
+	switch k.Alg {
+	case HS256:
+		ok = len(k.Secret) > 0
+	case RS256:
+		_, ok = k.Public.(*rsa.PublicKey)
+	case ES256:
+		var pub *ecdsa.PublicKey
+		if pub, ok = k.Public.(*ecdsa.PublicKey); ok {
+			ok = pub.Curve == elliptic.P256()
+		}
+	case EdDSA:
+		_, ok = k.Public.(ed25519.PublicKey)
+	default:
+		return errors.New("jwt: unsupported algorithm: " + k.Alg)
+	}
+	if !ok {
+		return ErrKeyInvalid
+	}
+	return nil
+}
+
+// KeySet holds the keys of JWT by id, tokens are signed by the current key
+// and verified by the key of their "kid" header. Keys can be rotated without
+// invalidating live tokens: Rotate a new key in, and Remove the old one after
+// the tokens signed by it are expired. It is thread-safe.
+type KeySet struct {
+	mu      sync.RWMutex
+	keys    map[string]Key
+	current string
+}
+
+// NewKeySet returns a KeySet of keys, the last one is the current key.
+func NewKeySet(keys ...Key) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]Key)}
+	for _, k := range keys {
+		if err := ks.Rotate(k); err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+// Add adds a key for verifying tokens only, a key with the same id is replaced.
+func (ks *KeySet) Add(k Key) error {
+	if err := k.check(); err != nil {
+		return err
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[k.Id] = k
+	return nil
+}
+
+// Rotate adds k and signs the new tokens by it, the tokens signed by the
+// old keys are still valid.
+func (ks *KeySet) Rotate(k Key) error {
+	if err := k.check(); err != nil {
+		return err
+	}
+	if k.Alg != HS256 && k.Private == nil {
+		return errors.New("jwt: current key must be able to sign tokens")
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[k.Id] = k
+	ks.current = k.Id
+	return nil
+}
+
+// Remove removes the key of id, the tokens signed by it are invalid.
+// The current key can not be removed.
+func (ks *KeySet) Remove(id string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if id != ks.current {
+		delete(ks.keys, id)
+	}
+}
+
+// Current returns the key signing tokens.
+func (ks *KeySet) Current() (Key, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	k, ok := ks.keys[ks.current]
+	if !ok {
+		return Key{}, ErrKeyNotFound
+	}
+	return k, nil
+}
+
+// Get returns the key of id.
+func (ks *KeySet) Get(id string) (Key, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	k, ok := ks.keys[id]
+	if !ok {
+		return Key{}, ErrKeyNotFound
+	}
+	return k, nil
+}
+
+// JWK is a public key of JSON Web Key (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC and OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys sorted by id, HS256 keys are secret and
+// not included.
+func (ks *KeySet) JWKS() JWKSet {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, k := range ks.keys {
+		jwk := JWK{Kid: k.Id, Use: "sig", Alg: k.Alg}
+		switch pub := k.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = b64(pub.N.Bytes())
+			jwk.E = b64(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			jwk.Kty = "EC"
+			jwk.Crv = "P-256"
+			jwk.X = b64(pub.X.FillBytes(make([]byte, 32)))
+			jwk.Y = b64(pub.Y.FillBytes(make([]byte, 32)))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = b64(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// JWKSHandler publishes the public keys, which is usually served at
+// /.well-known/jwks.json, for example:
+//	serv.GET("/.well-known/jwks.json", keys.JWKSHandler)
+func (ks *KeySet) JWKSHandler(ctx *api.Context) {
+	ctx.Writer.Header().Set("Cache-Control", "public, max-age=300")
+	ctx.Reply(http.StatusOK, render.JSON{Data: ks.JWKS()})
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,225 @@
+package jwt
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zltgo/reflectx"
+)
+
+var ErrRevoked = errors.New("jwt: token is revoked")
+
+// Revoker stores the revoked tokens before they expire. Tokens are revoked
+// by id, such as the "jti" claim or the family id of refresh tokens, or by
+// user with a revoked-before time.
+type Revoker interface {
+	// RevokeToken revokes the token of id, the record can be purged after
+	// expiresAt, a zero expiresAt means never. It reports false if id is
+	// revoked already, the check and the revocation are atomic.
+	RevokeToken(id string, expiresAt time.Time) (bool, error)
+
+	// RevokeUser revokes the tokens of uid issued before or at t,
+	// in milliseconds.
+	RevokeUser(uid string, before time.Time) error
+
+	// Revoked reports whether any of ids is revoked, or the token of uid is
+	// issued before or at the revoked-before time of uid. Empty uid and ids are ignored.
+	Revoked(uid string, issuedAt time.Time, ids ...string) (bool, error)
+}
+
+var _ Revoker = &MemRevoker{}
+
+// MemRevoker is a Revoker in memory for a single process,
+// the expired records are purged every minute.
+type MemRevoker struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+	users  map[string]time.Time
+	purged time.Time
+}
+
+func NewMemRevoker() *MemRevoker {
+	return &MemRevoker{
+		tokens: make(map[string]time.Time),
+		users:  make(map[string]time.Time),
+	}
+}
+
+func (m *MemRevoker) RevokeToken(id string, expiresAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := TimeNow()
+	if now.Sub(m.purged) > time.Minute {
+		for k, exp := range m.tokens {
+			if !exp.IsZero() && !exp.After(now) {
+				delete(m.tokens, k)
+			}
+		}
+		m.purged = now
+	}
+	if exp, ok := m.tokens[id]; ok && (exp.IsZero() || exp.After(now)) {
+		return false, nil
+	}
+	m.tokens[id] = expiresAt
+	return true, nil
+}
+
+func (m *MemRevoker) RevokeUser(uid string, before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users[uid] = before
+	return nil
+}
+
+func (m *MemRevoker) Revoked(uid string, issuedAt time.Time, ids ...string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if before, ok := m.users[uid]; ok && uid != "" && unixMilli(issuedAt) <= unixMilli(before) {
+		return true, nil
+	}
+	for _, id := range ids {
+		if _, ok := m.tokens[id]; ok && id != "" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Options for SQLRevoker.
+type SQLRevokerOpts struct {
+	// Table stores the records, it is created by CreateTable if not exists:
+	//	CREATE TABLE IF NOT EXISTS revocations (
+	//		id VARCHAR(128) PRIMARY KEY,
+	//		until BIGINT NOT NULL
+	//	)
+	// The id of tokens is prefixed by "token:" and until is the unix time
+	// of expiry, the id of users is prefixed by "user:" and until is the
+	// revoked-before time in milliseconds.
+	Table       string `default:"revocations"`
+	CreateTable bool
+
+	// Placeholder is the bind parameter of the driver, "?" for mysql and
+	// sqlite, "$" for postgres which is numbered as $1, $2.
+	Placeholder string `default:"?"`
+}
+
+var _ Revoker = &SQLRevoker{}
+
+// SQLRevoker is a Revoker of database/sql shared by processes,
+// call Purge periodically to delete the expired records.
+type SQLRevoker struct {
+	db   *sql.DB
+	opts SQLRevokerOpts
+}
+
+func NewSQLRevoker(db *sql.DB, opts SQLRevokerOpts) (*SQLRevoker, error) {
+	reflectx.SetDefault(&opts)
+	if opts.CreateTable {
+		if _, err := db.Exec("CREATE TABLE IF NOT EXISTS " + opts.Table +
+			" (id VARCHAR(128) PRIMARY KEY, until BIGINT NOT NULL)"); err != nil {
+			return nil, err
+		}
+	}
+	return &SQLRevoker{db: db, opts: opts}, nil
+}
+
+func (m *SQLRevoker) RevokeToken(id string, expiresAt time.Time) (bool, error) {
+	var until int64
+	if !expiresAt.IsZero() {
+		until = expiresAt.Unix()
+	}
+	// the primary key makes it atomic.
+	_, err := m.db.Exec("INSERT INTO "+m.opts.Table+" (id, until) VALUES ("+m.arg(1)+", "+m.arg(2)+")",
+		"token:"+id, until)
+	if err == nil {
+		return true, nil
+	}
+	if revoked, e := m.Revoked("", time.Time{}, id); e == nil && revoked {
+		return false, nil
+	}
+	return false, err
+}
+
+func (m *SQLRevoker) RevokeUser(uid string, before time.Time) error {
+	return m.set("user:"+uid, unixMilli(before))
+}
+
+func (m *SQLRevoker) Revoked(uid string, issuedAt time.Time, ids ...string) (bool, error) {
+	var keys []interface{}
+	if uid != "" {
+		keys = append(keys, "user:"+uid)
+	}
+	for _, id := range ids {
+		if id != "" {
+			keys = append(keys, "token:"+id)
+		}
+	}
+	if len(keys) == 0 {
+		return false, nil
+	}
+
+	args := make([]string, len(keys))
+	for i := range keys {
+		args[i] = m.arg(i + 1)
+	}
+	rows, err := m.db.Query("SELECT id, until FROM "+m.opts.Table+
+		" WHERE id IN ("+strings.Join(args, ", ")+")", keys...)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var until int64
+		if err := rows.Scan(&id, &until); err != nil {
+			return false, err
+		}
+		if strings.HasPrefix(id, "token:") || unixMilli(issuedAt) <= until {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// Purge deletes the records of the expired tokens.
+func (m *SQLRevoker) Purge() error {
+	_, err := m.db.Exec("DELETE FROM "+m.opts.Table+" WHERE id LIKE 'token:%' AND until <> 0 AND until <= "+m.arg(1),
+		TimeNow().Unix())
+	return err
+}
+
+// set updates or inserts the record of id.
+func (m *SQLRevoker) set(id string, until int64) error {
+	update := "UPDATE " + m.opts.Table + " SET until = " + m.arg(1) + " WHERE id = " + m.arg(2)
+	res, err := m.db.Exec(update, until, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	if _, err = m.db.Exec("INSERT INTO "+m.opts.Table+" (id, until) VALUES ("+m.arg(1)+", "+m.arg(2)+")",
+		id, until); err == nil {
+		return nil
+	}
+	// the row is inserted by others, or not changed by the update of mysql.
+	_, err = m.db.Exec(update, until, id)
+	return err
+}
+
+func unixMilli(t time.Time) int64 {
+	return t.Unix()*1000 + int64(t.Nanosecond())/int64(time.Millisecond)
+}
+
+func (m *SQLRevoker) arg(i int) string {
+	if m.opts.Placeholder == "$" {
+		return "$" + strconv.Itoa(i)
+	}
+	return m.opts.Placeholder
+}
@@ -0,0 +1,184 @@
+package jwt
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/zltgo/api"
+)
+
+func testRevoker(t *testing.T, rv Revoker) {
+	TimeNow = timeFunc(1000, 0)
+
+	Convey("revoke tokens by id", t, func() {
+		ok, err := rv.Revoked("", time.Time{}, "a", "b")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+
+		ok, err = rv.RevokeToken("b", time.Unix(1200, 0))
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+		// revoked already.
+		ok, err = rv.RevokeToken("b", time.Unix(1200, 0))
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+		ok, err = rv.Revoked("", time.Time{}, "a", "b")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+		ok, _ = rv.Revoked("", time.Time{}, "a", "")
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("revoke tokens by user", t, func() {
+		So(rv.RevokeUser("u1", time.Unix(1000, 500*1e6)), ShouldBeNil)
+		ok, err := rv.Revoked("u1", time.Unix(1000, 500*1e6))
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+		// issued in the same second after the revocation.
+		ok, _ = rv.Revoked("u1", time.Unix(1000, 501*1e6))
+		So(ok, ShouldBeFalse)
+		ok, _ = rv.Revoked("u2", time.Unix(999, 0))
+		So(ok, ShouldBeFalse)
+		ok, _ = rv.Revoked("u2", time.Unix(999, 0), "b")
+		So(ok, ShouldBeTrue)
+	})
+}
+
+func TestMemRevoker(t *testing.T) {
+	testRevoker(t, NewMemRevoker())
+
+	Convey("expired records are purged", t, func() {
+		rv := NewMemRevoker()
+		TimeNow = timeFunc(1000, 0)
+		rv.RevokeToken("a", time.Unix(1050, 0))
+		rv.RevokeToken("b", time.Time{})
+		TimeNow = timeFunc(1100, 0)
+		rv.RevokeToken("c", time.Unix(1200, 0))
+		So(len(rv.tokens), ShouldEqual, 2)
+		ok, _ := rv.Revoked("", time.Time{}, "a")
+		So(ok, ShouldBeFalse)
+	})
+}
+
+func TestSQLRevoker(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// each connection of sqlite3 has its own memory database.
+	db.SetMaxOpenConns(1)
+
+	rv, err := NewSQLRevoker(db, SQLRevokerOpts{CreateTable: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testRevoker(t, rv)
+
+	Convey("expired records are purged", t, func() {
+		TimeNow = timeFunc(1300, 0)
+		_, err := rv.RevokeToken("forever", time.Time{})
+		So(err, ShouldBeNil)
+		So(rv.Purge(), ShouldBeNil)
+		ok, _ := rv.Revoked("", time.Time{}, "b")
+		So(ok, ShouldBeFalse)
+		ok, _ = rv.Revoked("u1", time.Unix(900, 0), "forever")
+		So(ok, ShouldBeTrue)
+	})
+}
+
+func TestAuthRevoke(t *testing.T) {
+	TimeNow = timeFunc(1000, 0)
+	auth := NewAuth(NewParser(10, []byte("1111111111111111"), nil), NewParser(100, []byte("1111111111111111"), nil), nil)
+	auth.SetRevoker(NewMemRevoker())
+
+	serv := api.New()
+	serv.GET("/refresh", auth.RefreshHandler)
+	serv.GET("/logout", auth.LogoutHandler)
+	do := func(path, tk string) (int, *AuthToken) {
+		r, _ := http.NewRequest("GET", path, nil)
+		r.Header.Set("ACCESS-TOKEN", tk)
+		w := httptest.NewRecorder()
+		serv.ServeHTTP(w, r)
+		var at AuthToken
+		json.Unmarshal(w.Body.Bytes(), &at)
+		return w.Code, &at
+	}
+	authFunc := func(tk string) error {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.Header.Set("ACCESS-TOKEN", tk)
+		_, err := auth.AuthFunc(r)
+		return err
+	}
+
+	Convey("tokens must expire to be revoked", t, func() {
+		So(func() { NewAuth(nil, nil, nil).SetRevoker(NewMemRevoker()) }, ShouldPanic)
+	})
+
+	Convey("refresh tokens are rotated", t, func() {
+		tk, err := auth.NewAuthToken("000001", &http.Request{Header: http.Header{}})
+		So(err, ShouldBeNil)
+		code, next := do("/refresh", tk.RefreshToken)
+		So(code, ShouldEqual, http.StatusOK)
+		So(next.RefreshToken, ShouldNotEqual, tk.RefreshToken)
+		So(authFunc(next.AccessToken), ShouldBeNil)
+
+		Convey("concurrent refreshes with the same token", func() {
+			tk, _ := auth.NewAuthToken("000001", &http.Request{Header: http.Header{}})
+			codes := make(chan int, 8)
+			for i := 0; i < cap(codes); i++ {
+				go func() {
+					code, _ := do("/refresh", tk.RefreshToken)
+					codes <- code
+				}()
+			}
+			var succeeded int
+			for i := 0; i < cap(codes); i++ {
+				if <-codes == http.StatusOK {
+					succeeded++
+				}
+			}
+			So(succeeded, ShouldEqual, 1)
+		})
+
+		Convey("reusing a refresh token revokes the family", func() {
+			code, _ = do("/refresh", tk.RefreshToken)
+			So(code, ShouldEqual, http.StatusUnauthorized)
+			code, _ = do("/refresh", next.RefreshToken)
+			So(code, ShouldEqual, http.StatusUnauthorized)
+			So(authFunc(next.AccessToken), ShouldEqual, ErrRevoked)
+			So(authFunc(tk.AccessToken), ShouldEqual, ErrRevoked)
+		})
+	})
+
+	Convey("logout revokes the access and refresh tokens", t, func() {
+		tk, _ := auth.NewAuthToken("000001", &http.Request{Header: http.Header{}})
+		other, _ := auth.NewAuthToken("000001", &http.Request{Header: http.Header{}})
+		code, _ := do("/logout", tk.AccessToken)
+		So(code, ShouldEqual, http.StatusNoContent)
+		So(authFunc(tk.AccessToken), ShouldEqual, ErrRevoked)
+		code, _ = do("/refresh", tk.RefreshToken)
+		So(code, ShouldEqual, http.StatusUnauthorized)
+
+		// other logins are not affected.
+		So(authFunc(other.AccessToken), ShouldBeNil)
+	})
+
+	Convey("revoke all the tokens of a user", t, func() {
+		tk, _ := auth.NewAuthToken("000002", &http.Request{Header: http.Header{}})
+		So(auth.RevokeUser("000002"), ShouldBeNil)
+		So(authFunc(tk.AccessToken), ShouldEqual, ErrRevoked)
+		code, _ := do("/refresh", tk.RefreshToken)
+		So(code, ShouldEqual, http.StatusUnauthorized)
+
+		TimeNow = timeFunc(1000, 1e6)
+		tk, _ = auth.NewAuthToken("000002", &http.Request{Header: http.Header{}})
+		So(authFunc(tk.AccessToken), ShouldBeNil)
+	})
+}
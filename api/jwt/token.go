@@ -1,6 +1,7 @@
 package jwt
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/hmac"
@@ -8,6 +9,7 @@ import (
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/base64"
+	"encoding/gob"
 	"encoding/json"
 	"errors"
 	"hash"
@@ -88,6 +90,9 @@ type tokenParser struct {
 	// 16, 24, or 32 bytes to select AES-128, AES-192, or AES-256.
 	// if blockKey is nil,  values will not  encrypt.
 	block cipher.Block
+
+	// encode values by gob instead of json.
+	gob bool
 }
 
 // New returns a new tokenParser for token.
@@ -132,6 +137,15 @@ func NewParser(maxAge int, hashKey, blockKey []byte) Parser {
 	return rv
 }
 
+// NewGobParser is like NewParser, but the values are encoded by gob, which
+// keeps the concrete types of values. The types stored in interface values
+// must be registered by gob.Register.
+func NewGobParser(maxAge int, hashKey, blockKey []byte) Parser {
+	rv := NewParser(maxAge, hashKey, blockKey).(*tokenParser)
+	rv.gob = true
+	return rv
+}
+
 //create a token for the given values, thread-safe
 func (m *tokenParser) CreateToken(mp map[string]interface{}) (string, error) {
 	if m.maxAge > 0 {
@@ -139,8 +153,8 @@ func (m *tokenParser) CreateToken(mp map[string]interface{}) (string, error) {
 		// Because numbers are converted to float64 when decoding json.
 		mp[CreateTimeKey] = TimeNow().Unix()
 	}
-	//encode json
-	b, err := json.Marshal(mp)
+	//encode json or gob
+	b, err := m.marshal(mp)
 	if err != nil {
 		return "", errors.New("jwt: " + err.Error())
 	}
@@ -183,9 +197,9 @@ func (m *tokenParser) ParseToken(tk string) (map[string]interface{}, error) {
 			return nil, ErrDecrypt
 		}
 	}
-	//decode json
-	jsonMap := values.JsonMap{}
-	if err = jsonMap.Decode(data); err != nil {
+	//decode json or gob
+	jsonMap, err := m.unmarshal(data)
+	if err != nil {
 		return nil, errors.New("jwt: " + err.Error())
 	}
 
@@ -211,3 +225,20 @@ func (m *tokenParser) ParseToken(tk string) (map[string]interface{}, error) {
 func (m *tokenParser) MaxAge() int {
 	return m.maxAge
 }
+
+func (m *tokenParser) marshal(mp map[string]interface{}) ([]byte, error) {
+	if !m.gob {
+		return json.Marshal(mp)
+	}
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(mp)
+	return buf.Bytes(), err
+}
+
+func (m *tokenParser) unmarshal(data []byte) (values.JsonMap, error) {
+	mp := values.JsonMap{}
+	if !m.gob {
+		return mp, mp.Decode(data)
+	}
+	return mp, gob.NewDecoder(bytes.NewReader(data)).Decode((*map[string]interface{})(&mp))
+}
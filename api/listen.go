@@ -0,0 +1,82 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"os"
+)
+
+// TLSOptions configures the servers started by RunTLS.
+type TLSOptions struct {
+	// MinVersion is the minimum TLS version accepted, such as tls.VersionTLS13.
+	// Default is tls.VersionTLS12.
+	MinVersion uint16
+
+	// ClientCAs are the PEM files of the certificate authorities verifying
+	// client certificates, client certificates are required if it is not
+	// empty, which is known as mutual TLS.
+	ClientCAs []string
+
+	// ClientAuth is the policy of client certificates, default is
+	// tls.RequireAndVerifyClientCert if ClientCAs is not empty,
+	// use tls.VerifyClientCertIfGiven to make them optional.
+	ClientAuth tls.ClientAuthType
+}
+
+// Config returns the tls.Config of opts, the certificates are loaded by RunTLS.
+func (opts TLSOptions) Config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: opts.MinVersion,
+		ClientAuth: opts.ClientAuth,
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+
+	if len(opts.ClientCAs) > 0 {
+		cfg.ClientCAs = x509.NewCertPool()
+		for _, file := range opts.ClientCAs {
+			pem, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			if !cfg.ClientCAs.AppendCertsFromPEM(pem) {
+				return nil, errors.New("api: no certificates found in " + file)
+			}
+		}
+		if cfg.ClientAuth == tls.NoClientCert {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return cfg, nil
+}
+
+// UnixSocketOptions configures the socket file created by RunUnix.
+type UnixSocketOptions struct {
+	// Mode is the permission bits of the socket file, such as 0660.
+	// Zero keeps the mode created by umask. On unix the file is created with
+	// Mode rather than changed after listening, see listenUnix.
+	Mode os.FileMode
+
+	// Uid and Gid are the numeric owner and group of the socket file,
+	// nil keeps the owner and the group of the process.
+	Uid *int
+	Gid *int
+}
+
+// chown changes the ownership of the socket file.
+func (opts UnixSocketOptions) chown(file string) error {
+	if opts.Uid == nil && opts.Gid == nil {
+		return nil
+	}
+	uid, gid := -1, -1
+	if opts.Uid != nil {
+		uid = *opts.Uid
+	}
+	if opts.Gid != nil {
+		gid = *opts.Gid
+	}
+	return os.Chown(file, uid, gid)
+}
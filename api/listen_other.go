@@ -0,0 +1,22 @@
+//go:build !unix
+
+package api
+
+import (
+	"net"
+	"os"
+)
+
+// listenUnix creates the socket file and changes its mode, there is no umask
+// on the platform.
+func listenUnix(file string, mode os.FileMode) (net.Listener, error) {
+	l, err := net.Listen("unix", file)
+	if err != nil || mode == 0 {
+		return l, err
+	}
+	if err = os.Chmod(file, mode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnixSocketMode(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "unix_mode_test")
+	router := New()
+	uid, gid := os.Getuid(), os.Getgid()
+	router.UnixSocket = UnixSocketOptions{Mode: 0600, Uid: &uid, Gid: &gid}
+	go func() {
+		assert.NoError(t, router.RunUnix(file))
+	}()
+	time.Sleep(10 * time.Millisecond)
+	defer router.Shutdown(context.Background())
+
+	fi, err := os.Stat(file)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+	assert.Equal(t, os.ModeSocket, fi.Mode().Type())
+}
+
+func TestTLSOptions(t *testing.T) {
+	cfg, err := TLSOptions{}.Config()
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+	assert.Equal(t, tls.NoClientCert, cfg.ClientAuth)
+	assert.Nil(t, cfg.ClientCAs)
+
+	_, err = TLSOptions{ClientCAs: []string{"not_exist.pem"}}.Config()
+	assert.Error(t, err)
+
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.pem")
+	ioutil.WriteFile(bad, []byte("not a pem"), 0600)
+	_, err = TLSOptions{ClientCAs: []string{bad}}.Config()
+	assert.EqualError(t, err, "api: no certificates found in "+bad)
+
+	ca := writeTestCert(t, dir, "ca", nil)
+	cfg, err = TLSOptions{MinVersion: tls.VersionTLS13, ClientCAs: []string{ca.certFile}}.Config()
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), cfg.MinVersion)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, cfg.ClientAuth)
+	assert.NotNil(t, cfg.ClientCAs)
+
+	cfg, err = TLSOptions{ClientCAs: []string{ca.certFile}, ClientAuth: tls.VerifyClientCertIfGiven}.Config()
+	assert.NoError(t, err)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, cfg.ClientAuth)
+}
+
+func TestRunTLSClientCert(t *testing.T) {
+	dir := t.TempDir()
+	ca := writeTestCert(t, dir, "ca", nil)
+	server := writeTestCert(t, dir, "server", ca)
+	client := writeTestCert(t, dir, "client", ca)
+
+	router := New()
+	router.TLS = TLSOptions{ClientCAs: []string{ca.certFile}}
+	router.GET("/example", func(c *Context) {
+		c.Reply(http.StatusOK, c.Request.TLS.PeerCertificates[0].Subject.CommonName)
+	})
+	go func() {
+		assert.NoError(t, router.RunTLS(":5152", server.certFile, server.keyFile))
+	}()
+	time.Sleep(50 * time.Millisecond)
+	defer router.Shutdown(context.Background())
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(certs ...tls.Certificate) (*http.Response, error) {
+		tr := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}
+		defer tr.CloseIdleConnections()
+		return (&http.Client{Transport: tr}).Get("https://localhost:5152/example")
+	}
+
+	// client certificate is required.
+	_, err := get()
+	assert.Error(t, err)
+
+	resp, err := get(client.tlsCert)
+	if assert.NoError(t, err) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "client", string(body))
+	}
+}
+
+func TestContextPush(t *testing.T) {
+	c := &Context{}
+	c.reset(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	assert.Nil(t, c.Writer.Pusher())
+	assert.Equal(t, http.ErrNotSupported, c.Push("/app.js", nil))
+
+	var pusher http.Pusher
+	router := New()
+	router.GET("/example", func(c *Context) {
+		pusher = c.Writer.Pusher()
+		c.Reply(http.StatusOK, "it worked")
+	})
+	ts := httptest.NewUnstartedServer(router)
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/example")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 2, resp.ProtoMajor)
+	assert.NotNil(t, pusher)
+}
+
+type testCert struct {
+	cert              *x509.Certificate
+	key               *ecdsa.PrivateKey
+	tlsCert           tls.Certificate
+	certFile, keyFile string
+}
+
+// writeTestCert writes a certificate signed by parent, it is self-signed
+// as a CA if parent is nil.
+func writeTestCert(t *testing.T, dir, name string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	tc := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".pem"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	assert.NoError(t, ioutil.WriteFile(tc.certFile, certPEM, 0600))
+	assert.NoError(t, ioutil.WriteFile(tc.keyFile, keyPEM, 0600))
+	tc.tlsCert, err = tls.X509KeyPair(certPEM, keyPEM)
+	assert.NoError(t, err)
+	return tc
+}
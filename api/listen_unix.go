@@ -0,0 +1,29 @@
+//go:build unix
+
+package api
+
+import (
+	"net"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// umaskMu serializes the changes of umask by listenUnix.
+var umaskMu sync.Mutex
+
+// listenUnix creates the socket file with the permission bits of mode by
+// setting umask while listening, so the file is never accessible with the
+// default mode. Zero mode keeps umask.
+// Note that umask is shared by the process, files created by other goroutines
+// at the same time are affected too.
+func listenUnix(file string, mode os.FileMode) (net.Listener, error) {
+	if mode == 0 {
+		return net.Listen("unix", file)
+	}
+	umaskMu.Lock()
+	defer umaskMu.Unlock()
+	old := syscall.Umask(int(^mode.Perm() & 0777))
+	defer syscall.Umask(old)
+	return net.Listen("unix", file)
+}
@@ -85,6 +85,9 @@ func LoggerWithWriter(out io.Writer, notlogged ...string) Handler {
 			if raw != "" {
 				path = path + "?" + raw
 			}
+			if t := c.Trace(); t != nil {
+				path = path + " | " + t.RequestID + " " + t.TraceID
+			}
 
 			fmt.Fprintf(out, "[Api] %v |%s %3d %s| %13v | %15s |%s %-7s %s %s\n%s",
 				end.Format("2006/01/02 - 15:04:05"),
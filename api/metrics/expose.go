@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WriteTo writes all the metrics in the Prometheus text format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	m.writeRoutes(bw)
+	m.writeFuncs(bw)
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// writeRoutes writes the metrics of requests sorted by route and method.
+func (m *Metrics) writeRoutes(w *bufio.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]routeKey, 0, len(m.routes))
+	for key := range m.routes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		return keys[i].method < keys[j].method
+	})
+
+	name := m.name("http_requests_total")
+	writeHeader(w, name, "Total number of HTTP requests by status code.", "counter")
+	for _, key := range keys {
+		rs := m.routes[key]
+		codes := make([]int, 0, len(rs.codes))
+		for code := range rs.codes {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			writeSample(w, name, float64(rs.codes[code]), "method", key.method, "route", key.route, "code", strconv.Itoa(code))
+		}
+	}
+
+	name = m.name("http_request_duration_seconds")
+	writeHeader(w, name, "Latency of HTTP requests in seconds.", "histogram")
+	for _, key := range keys {
+		rs := m.routes[key]
+		var cumulative uint64
+		for i, le := range m.Buckets {
+			cumulative += rs.buckets[i]
+			writeSample(w, name+"_bucket", float64(cumulative), "method", key.method, "route", key.route, "le", formatFloat(le))
+		}
+		writeSample(w, name+"_bucket", float64(rs.count), "method", key.method, "route", key.route, "le", "+Inf")
+		writeSample(w, name+"_sum", rs.sum, "method", key.method, "route", key.route)
+		writeSample(w, name+"_count", float64(rs.count), "method", key.method, "route", key.route)
+	}
+
+	name = m.name("http_requests_in_flight")
+	writeHeader(w, name, "Number of HTTP requests being served.", "gauge")
+	for _, key := range keys {
+		writeSample(w, name, float64(m.routes[key].inFlight), "method", key.method, "route", key.route)
+	}
+}
+
+// writeFuncs writes the metrics of registered functions, the ones of the
+// same name are grouped.
+func (m *Metrics) writeFuncs(w *bufio.Writer) {
+	written := make(map[string]bool, len(m.funcs))
+	for i, f := range m.funcs {
+		if written[f.name] {
+			continue
+		}
+		written[f.name] = true
+
+		name := m.name(f.name)
+		writeHeader(w, name, f.help, f.typ)
+		for _, g := range m.funcs[i:] {
+			if g.name == f.name {
+				writeSample(w, name, g.fn(), g.labels...)
+			}
+		}
+	}
+}
+
+func (m *Metrics) name(name string) string {
+	if m.Namespace == "" {
+		return name
+	}
+	return m.Namespace + "_" + name
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	w.WriteString("# HELP " + name + " " + helpReplacer.Replace(help) + "\n")
+	w.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+// writeSample writes a line of the metric, labels are pairs of names and values.
+func writeSample(w *bufio.Writer, name string, v float64, labels ...string) {
+	w.WriteString(name)
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i := 0; i < len(labels); i += 2 {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(labels[i] + `="` + labelReplacer.Replace(labels[i+1]) + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteString(" " + formatFloat(v) + "\n")
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
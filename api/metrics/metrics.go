@@ -0,0 +1,184 @@
+// Package metrics collects the metrics of api.Server and exposes them in the
+// Prometheus text format, for example:
+//	m := metrics.New()
+//	serv := api.New(m.Handler(), api.Logger())
+//	m.Cache("session", lru)
+//	m.Serve(serv, "/metrics")
+// Requests are labelled by the method and the route pattern like "/user/:id",
+// the requests not matching any route are labelled with an empty route.
+package metrics
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zltgo/api"
+	"github.com/zltgo/api/cache"
+	"github.com/zltgo/api/ratelimit"
+)
+
+// ContentType is the content type of the Prometheus text format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets are the default buckets of the latency histogram in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metrics records the requests handled by Handler and the values of
+// registered functions.
+type Metrics struct {
+	// Namespace is the prefix of metric names, such as "myapp" for
+	// "myapp_http_requests_total". Default is no prefix.
+	Namespace string
+
+	// Buckets are the upper bounds of the latency histogram in seconds,
+	// in increasing order. Default is DefBuckets.
+	// Do not modify it after the first request.
+	Buckets []float64
+
+	mu     sync.Mutex
+	routes map[routeKey]*routeStats
+	funcs  []*funcMetric
+}
+
+type routeKey struct {
+	method string
+	route  string
+}
+
+type routeStats struct {
+	inFlight int64
+	codes    map[int]uint64
+	buckets  []uint64 // not cumulative
+	sum      float64
+	count    uint64
+}
+
+// funcMetric is a counter or gauge reading its value from fn.
+type funcMetric struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+	fn     func() float64
+}
+
+// New returns Metrics with the rejection counter of 'zltgo/api/ratelimit'.
+func New() *Metrics {
+	m := &Metrics{
+		Buckets: DefBuckets,
+		routes:  make(map[routeKey]*routeStats),
+	}
+	m.CounterFunc("ratelimit_rejections_total", "Total number of calls rejected by rate limiters.", func() float64 {
+		return float64(ratelimit.Rejections())
+	})
+	return m
+}
+
+// Handler returns a middleware recording the request count by status code,
+// the latency histogram and the number of in-flight requests.
+// It should be the first middleware of the server to measure the others.
+func (m *Metrics) Handler() api.Handler {
+	return func(ctx *api.Context) {
+		key := routeKey{ctx.Request.Method, ctx.FullPath()}
+		start := time.Now()
+
+		m.mu.Lock()
+		rs := m.stats(key)
+		rs.inFlight++
+		m.mu.Unlock()
+
+		defer func() {
+			elapsed := time.Since(start).Seconds()
+			code := ctx.Writer.Status()
+			err := recover()
+			if err != nil {
+				// the panic is recovered by the outer Recovery middleware with 500.
+				code = http.StatusInternalServerError
+			}
+
+			m.mu.Lock()
+			rs.inFlight--
+			rs.codes[code]++
+			rs.observe(m.Buckets, elapsed)
+			m.mu.Unlock()
+
+			if err != nil {
+				panic(err)
+			}
+		}()
+		ctx.Next()
+	}
+}
+
+// stats returns the stats of key, m.mu must be held.
+func (m *Metrics) stats(key routeKey) *routeStats {
+	rs := m.routes[key]
+	if rs == nil {
+		rs = &routeStats{
+			codes:   make(map[int]uint64),
+			buckets: make([]uint64, len(m.Buckets)),
+		}
+		m.routes[key] = rs
+	}
+	return rs
+}
+
+func (rs *routeStats) observe(buckets []float64, v float64) {
+	rs.sum += v
+	rs.count++
+	if i := sort.SearchFloat64s(buckets, v); i < len(rs.buckets) {
+		rs.buckets[i]++
+	}
+}
+
+// CounterFunc registers a counter reading its value from fn when exposed,
+// labels are pairs of names and values, such as "cache", "session".
+// Register it before serving, the funcs are read by Handler without locks.
+func (m *Metrics) CounterFunc(name, help string, fn func() float64, labels ...string) {
+	m.addFunc(name, help, "counter", fn, labels)
+}
+
+// GaugeFunc registers a gauge reading its value from fn when exposed,
+// labels are pairs of names and values.
+// Not thread-safe.
+func (m *Metrics) GaugeFunc(name, help string, fn func() float64, labels ...string) {
+	m.addFunc(name, help, "gauge", fn, labels)
+}
+
+func (m *Metrics) addFunc(name, help, typ string, fn func() float64, labels []string) {
+	if len(labels)%2 != 0 {
+		panic("metrics: labels of " + name + " must be pairs of names and values")
+	}
+	m.funcs = append(m.funcs, &funcMetric{name: name, help: help, typ: typ, labels: labels, fn: fn})
+}
+
+// Cache registers the stats of c labelled by name, see LruMemCache.Stats.
+// Not thread-safe, see CounterFunc.
+func (m *Metrics) Cache(name string, c *cache.LruMemCache) {
+	m.GaugeFunc("cache_items", "Number of items in the cache.", func() float64 {
+		return float64(c.Stats().Items)
+	}, "cache", name)
+	m.CounterFunc("cache_gets_total", "Total number of cache gets.", func() float64 {
+		return float64(c.Stats().Gets)
+	}, "cache", name)
+	m.CounterFunc("cache_hits_total", "Total number of cache hits.", func() float64 {
+		return float64(c.Stats().Hits)
+	}, "cache", name)
+	m.CounterFunc("cache_evictions_total", "Total number of cache evictions.", func() float64 {
+		return float64(c.Stats().Evictions)
+	}, "cache", name)
+}
+
+// ServeHTTP writes all the metrics in the Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(http.StatusOK)
+	m.WriteTo(w)
+}
+
+// Serve registers the metrics at url of serv.
+func (m *Metrics) Serve(serv *api.Server, url string) {
+	serv.GET(url, api.H(m.ServeHTTP))
+}
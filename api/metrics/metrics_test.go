@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zltgo/api"
+	"github.com/zltgo/api/cache"
+)
+
+func performRequest(r http.Handler, method, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMetrics(t *testing.T) {
+	m := New()
+	m.Namespace = "test"
+	m.Buckets = []float64{0.1, 1}
+	lru := cache.NewLruMemCache(10)
+	lru.Set("a", 1)
+	lru.Get("a")
+	lru.Get("b")
+	m.Cache("session", lru)
+
+	serv := api.New(m.Handler(), api.Recovery())
+	serv.GET("/user/:id<int>", func(c *api.Context) {
+		c.Reply(http.StatusOK, c.Params.ByName("id"))
+	})
+	serv.POST("/panic", func(c *api.Context) {
+		panic("oops")
+	})
+	m.Serve(serv, "/metrics")
+
+	performRequest(serv, "GET", "/user/1")
+	performRequest(serv, "GET", "/user/2")
+	performRequest(serv, "POST", "/panic")
+	performRequest(serv, "GET", "/none")
+
+	w := performRequest(serv, "GET", "/metrics")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE test_http_requests_total counter",
+		`test_http_requests_total{method="GET",route="/user/:id<int>",code="200"} 2`,
+		`test_http_requests_total{method="POST",route="/panic",code="500"} 1`,
+		`test_http_requests_total{method="GET",route="",code="404"} 1`,
+		"# TYPE test_http_request_duration_seconds histogram",
+		`test_http_request_duration_seconds_bucket{method="GET",route="/user/:id<int>",le="0.1"} 2`,
+		`test_http_request_duration_seconds_bucket{method="GET",route="/user/:id<int>",le="1"} 2`,
+		`test_http_request_duration_seconds_bucket{method="GET",route="/user/:id<int>",le="+Inf"} 2`,
+		`test_http_request_duration_seconds_count{method="GET",route="/user/:id<int>"} 2`,
+		`test_http_requests_in_flight{method="GET",route="/metrics"} 1`,
+		`test_http_requests_in_flight{method="GET",route="/user/:id<int>"} 0`,
+		"# TYPE test_ratelimit_rejections_total counter",
+		`test_cache_items{cache="session"} 1`,
+		`test_cache_gets_total{cache="session"} 2`,
+		`test_cache_hits_total{cache="session"} 1`,
+		`test_cache_evictions_total{cache="session"} 0`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	// raw urls are never used as labels.
+	assert.NotContains(t, body, "/user/1")
+	assert.NotContains(t, body, "/none")
+}
+
+func TestWriteSample(t *testing.T) {
+	m := New()
+	m.GaugeFunc("temperature", "Temperature\nin celsius.", func() float64 { return -1.5 }, "room", `a "b"\c`)
+	m.GaugeFunc("temperature", "", func() float64 { return 20 }, "room", "d")
+
+	var b strings.Builder
+	_, err := m.WriteTo(&b)
+	assert.NoError(t, err)
+	assert.Contains(t, b.String(), "# HELP temperature Temperature\\nin celsius.\n# TYPE temperature gauge\n"+
+		`temperature{room="a \"b\"\\c"} -1.5`+"\n"+`temperature{room="d"} 20`+"\n")
+
+	assert.Panics(t, func() { m.CounterFunc("odd", "", func() float64 { return 0 }, "label") })
+}
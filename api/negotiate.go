@@ -0,0 +1,178 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/zltgo/api/bind"
+	"github.com/zltgo/api/render"
+)
+
+// DefaultOffers is the default server-side order of formats for content negotiation.
+// JSON comes first, so it is used if the client does not send an Accept header
+// or accepts nothing offered, such as browsers. XML is not offered by default,
+// browsers accept it before */*, use Negotiate to offer it.
+var DefaultOffers = []string{
+	bind.MIMEJSON,
+	bind.MIMEYAML,
+	bind.MIMEYAML2,
+	bind.MIMEMsgPack,
+	bind.MIMEMsgPack2,
+}
+
+// renderers creates a render.Render for the data by mime type.
+// ProtoBuf and CSV are not in DefaultOffers, they only work for some types
+// of data, use Negotiate to offer them as well as XML.
+var renderers = map[string]func(data interface{}) render.Render{
+	bind.MIMEJSON:     func(data interface{}) render.Render { return render.JSON{Data: data} },
+	bind.MIMEXML:      func(data interface{}) render.Render { return render.XML{Data: data} },
+	bind.MIMEXML2:     func(data interface{}) render.Render { return render.XML{Data: data} },
+	bind.MIMEYAML:     func(data interface{}) render.Render { return render.YAML{Data: data} },
+	bind.MIMEYAML2:    func(data interface{}) render.Render { return render.YAML{Data: data} },
+	bind.MIMEMsgPack:  func(data interface{}) render.Render { return render.MsgPack{Data: data} },
+	bind.MIMEMsgPack2: func(data interface{}) render.Render { return render.MsgPack{Data: data} },
+	bind.MIMEProtoBuf: func(data interface{}) render.Render { return render.ProtoBuf{Data: data} },
+	bind.MIMECSV:      func(data interface{}) render.Render { return render.CSV{Data: data} },
+}
+
+// RegisterRender registers a render for content negotiation, it replaces the
+// existing one of the same mime type.
+// Register renders before serving, the map is read without locks.
+func RegisterRender(mime string, fn func(data interface{}) render.Render) {
+	Assert(fn != nil, "render function can not be nil")
+	renderers[mime] = fn
+}
+
+// Negotiate returns a middleware that limits the formats offered by Context.Reply
+// for a route or a group, in the order of preference, 406 is replied if the
+// client accepts none of them. For example:
+//	serv.GET("/app/goods", api.Negotiate(bind.MIMEMsgPack, bind.MIMEJSON), m.ViewGoods)
+func Negotiate(offers ...string) Handler {
+	Assert(len(offers) > 0, "there must be at least one offer")
+	for _, mime := range offers {
+		_, ok := renderers[mime]
+		Assert(ok, "no render registered for "+mime)
+	}
+	return func(c *Context) {
+		c.offers = offers
+		c.strict = true
+	}
+}
+
+// NegotiateFormat returns the offer accepted by the client with the highest quality,
+// the former offer wins if the qualities are equal.
+// It returns offers[0] if accept is empty, and "" if nothing is acceptable.
+func NegotiateFormat(accept string, offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if accept == "" {
+		return offers[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := acceptQuality(accept, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// Offers returns the formats offered by Reply for the current request.
+func (ctx *Context) Offers() []string {
+	if len(ctx.offers) == 0 {
+		return DefaultOffers
+	}
+	return ctx.offers
+}
+
+// render val in the format negotiated with the Accept header. If nothing
+// matches, the first offer is used unless the offers are limited by Negotiate,
+// 406 is returned then.
+func (ctx *Context) negotiate(val interface{}) error {
+	offers := ctx.Offers()
+	if len(offers) > 1 {
+		ctx.Writer.Header().Add("Vary", "Accept")
+	}
+
+	mime := NegotiateFormat(ctx.Request.Header.Get("Accept"), offers...)
+	if mime == "" && !ctx.strict {
+		mime = offers[0]
+	}
+	if mime == "" {
+		ctx.Writer.WriteHeader(http.StatusNotAcceptable)
+		_, err := ctx.Writer.WriteString("406 not acceptable")
+		return err
+	}
+	return renderers[mime](val).Render(ctx.Writer)
+}
+
+// acceptQuality returns the quality of mime in the accept header,
+// the most specific media range wins.
+func acceptQuality(accept, mime string) (q float64) {
+	specificity := -1
+	for len(accept) > 0 {
+		var part string
+		if i := strings.IndexByte(accept, ','); i >= 0 {
+			part, accept = accept[:i], accept[i+1:]
+		} else {
+			part, accept = accept, ""
+		}
+
+		mediaRange, params := part, ""
+		if i := strings.IndexByte(part, ';'); i >= 0 {
+			mediaRange, params = part[:i], part[i+1:]
+		}
+
+		if s := matchMediaRange(strings.TrimSpace(mediaRange), mime); s > specificity {
+			specificity = s
+			q = parseQuality(params)
+		}
+	}
+	return q
+}
+
+// matchMediaRange returns -1 if mediaRange does not match mime,
+// 0 for */*, 1 for type/* and 2 for type/subtype.
+func matchMediaRange(mediaRange, mime string) int {
+	if mediaRange == "*/*" {
+		return 0
+	}
+	if strings.EqualFold(mediaRange, mime) {
+		return 2
+	}
+	if strings.HasSuffix(mediaRange, "/*") {
+		typ := mediaRange[:len(mediaRange)-1]
+		if len(mime) > len(typ) && strings.EqualFold(mime[:len(typ)], typ) {
+			return 1
+		}
+	}
+	return -1
+}
+
+// parseQuality returns the q parameter, default is 1.
+func parseQuality(params string) float64 {
+	for len(params) > 0 {
+		var param string
+		if i := strings.IndexByte(params, ';'); i >= 0 {
+			param, params = params[:i], params[i+1:]
+		} else {
+			param, params = params, ""
+		}
+
+		param = strings.TrimSpace(param)
+		if len(param) > 2 && (param[0] == 'q' || param[0] == 'Q') && param[1] == '=' {
+			q, err := strconv.ParseFloat(param[2:], 64)
+			if err != nil || q < 0 {
+				return 0
+			}
+			if q > 1 {
+				return 1
+			}
+			return q
+		}
+	}
+	return 1
+}
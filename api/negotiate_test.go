@@ -0,0 +1,117 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+	"github.com/zltgo/api/bind"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	offers := []string{bind.MIMEJSON, bind.MIMEXML, bind.MIMEMsgPack}
+
+	assert.Equal(t, bind.MIMEJSON, NegotiateFormat("", offers...))
+	assert.Equal(t, bind.MIMEJSON, NegotiateFormat("*/*", offers...))
+	assert.Equal(t, bind.MIMEXML, NegotiateFormat("application/xml", offers...))
+	assert.Equal(t, bind.MIMEMsgPack, NegotiateFormat("application/x-msgpack, application/json;q=0.9", offers...))
+	assert.Equal(t, bind.MIMEJSON, NegotiateFormat("application/*", offers...))
+	assert.Equal(t, bind.MIMEXML, NegotiateFormat("application/*;q=0.5, application/xml", offers...))
+	assert.Equal(t, bind.MIMEMsgPack, NegotiateFormat("application/json;q=0, application/xml; q=0.1, */*;q=0.2", offers...))
+	assert.Equal(t, bind.MIMEJSON, NegotiateFormat("APPLICATION/JSON", offers...))
+	assert.Equal(t, bind.MIMEXML, NegotiateFormat("text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", offers...))
+	// browsers get JSON from the default offers.
+	assert.Equal(t, bind.MIMEJSON, NegotiateFormat("text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", DefaultOffers...))
+	assert.Equal(t, "", NegotiateFormat("text/html", offers...))
+	assert.Equal(t, "", NegotiateFormat("application/json;q=0", bind.MIMEJSON))
+	assert.Equal(t, "", NegotiateFormat("application/json"))
+}
+
+func TestParseQuality(t *testing.T) {
+	assert.Equal(t, 1.0, parseQuality(""))
+	assert.Equal(t, 0.5, parseQuality("q=0.5"))
+	assert.Equal(t, 0.5, parseQuality("level=1; q=0.5"))
+	assert.Equal(t, 1.0, parseQuality("q=2"))
+	assert.Equal(t, 0.0, parseQuality("q=abc"))
+}
+
+func TestReplyNegotiation(t *testing.T) {
+	type Goods struct {
+		Name  string
+		Price int
+	}
+	router := New()
+	router.GET("/goods", H(func() Goods {
+		return Goods{"apple", 5}
+	}))
+	router.GET("/xml/goods", Negotiate(bind.MIMEJSON, bind.MIMEXML), H(func() Goods {
+		return Goods{"apple", 5}
+	}))
+	router.GET("/app/goods", Negotiate(bind.MIMEMsgPack), H(func() Goods {
+		return Goods{"apple", 5}
+	}))
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("GET", path, nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("/goods", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"Name":"apple","Price":5}`, w.Body.String())
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+
+	// XML is not offered by default, JSON is replied if nothing matches.
+	w = get("/goods", "application/xml")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	w = get("/goods", "text/html")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"Name":"apple","Price":5}`, w.Body.String())
+
+	w = get("/goods", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	w = get("/xml/goods", "application/xml")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/xml; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "<Goods><Name>apple</Name><Price>5</Price></Goods>", w.Body.String())
+
+	w = get("/goods", "application/x-yaml")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "name: apple\nprice: 5\n", w.Body.String())
+
+	w = get("/xml/goods", "image/png")
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+
+	// the route only offers msgpack
+	w = get("/app/goods", "application/json")
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+
+	w = get("/app/goods", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "", w.Header().Get("Vary"))
+
+	var goods Goods
+	err := codec.NewDecoder(w.Body, new(codec.MsgpackHandle)).Decode(&goods)
+	assert.NoError(t, err)
+	assert.Equal(t, Goods{"apple", 5}, goods)
+}
+
+func TestNegotiateUnknownOffer(t *testing.T) {
+	assert.Panics(t, func() {
+		Negotiate("image/png")
+	})
+	assert.Panics(t, func() {
+		Negotiate()
+	})
+}
@@ -0,0 +1,362 @@
+// Package openapi generates OpenAPI 3 documents from the routes of api.Server.
+//
+// The handlers wrapped by api.H are reflected: struct arguments bound by
+// 'zltgo/api/bind' are documented as query parameters (GET and DELETE) or
+// request bodies, path parameters come from the route, and the first return
+// value other than int and error is documented as the response.
+// Field names come from the 'form' and 'json' tags, required fields, lengths,
+// ranges and regexes come from the 'validate' tags.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zltgo/api"
+	"github.com/zltgo/api/bind"
+	"github.com/zltgo/api/tree"
+	"github.com/zltgo/reflectx"
+)
+
+// types injected by api.Context, they are never bound from request.
+var contextTypes = []reflect.Type{
+	api.TypeRequest,
+	api.TypeHttpResponseWriter,
+	api.TypeResponseWriter,
+	api.TypeContext,
+	api.TypeStdContext,
+}
+
+type Generator struct {
+	Info    Info
+	Servers []Server
+
+	// Produces are the content types of responses.
+	// Default is application/json.
+	Produces []string
+
+	// struct types mapped by middleware, see Ignore.
+	ignored map[reflect.Type]bool
+
+	once sync.Once
+	doc  *Document
+}
+
+// New returns a Generator, default title and version of info are "API" and "v1".
+func New(info Info) *Generator {
+	reflectx.SetDefault(&info)
+	g := &Generator{
+		Info:     info,
+		Produces: []string{bind.MIMEJSON},
+		ignored:  make(map[reflect.Type]bool),
+	}
+	g.Ignore(contextTypes...)
+	return g
+}
+
+// Ignore marks the struct types mapped by middleware, such as *session.Session
+// and *mgo.Database, they are not documented as request parameters.
+// Types other than struct are never bound from request, there is no need to ignore them.
+func (g *Generator) Ignore(types ...reflect.Type) {
+	for _, t := range types {
+		g.ignored[t] = true
+	}
+}
+
+// Generate creates a document for the routes.
+func (g *Generator) Generate(routes api.Routes) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    g.Info,
+		Servers: g.Servers,
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+		},
+	}
+	jsonBuilder := newSchemaBuilder("json", doc.Components.Schemas)
+	formBuilder := newSchemaBuilder("form", nil)
+
+	ids := make(map[string]bool)
+	for _, route := range routes {
+		methods := []string{route.Method}
+		if route.Method == "ANY" {
+			methods = anyMethods
+		}
+		path, params := convertPath(route.Url)
+		for _, method := range methods {
+			item := doc.Paths[path]
+			if item == nil {
+				item = &PathItem{}
+			}
+			op := item.Operation(method)
+			if op == nil {
+				// CONNECT or custom methods are not described by OpenAPI.
+				continue
+			}
+			doc.Paths[path] = item
+			route.Method = method
+			*op = g.operation(route, params, jsonBuilder, formBuilder)
+			(*op).OperationId = uniqueId(ids, (*op).OperationId, method)
+		}
+	}
+	return doc
+}
+
+// anyMethods are the methods of the routes registered by ANY, which can be
+// described by OpenAPI.
+var anyMethods = []string{"GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS", "TRACE"}
+
+// uniqueId returns id if it is not used, or id suffixed by the method and
+// a number if necessary, such as "main.addGoods_post". The id is marked used.
+func uniqueId(ids map[string]bool, id, method string) string {
+	if id == "" {
+		return ""
+	}
+	if ids[id] {
+		base := id + "_" + strings.ToLower(method)
+		id = base
+		for i := 2; ids[id]; i++ {
+			id = base + strconv.Itoa(i)
+		}
+	}
+	ids[id] = true
+	return id
+}
+
+// Handler returns a handler that replies the document of the routes of serv.
+// The document is generated on the first request, all the routes should be
+// registered before that.
+func (g *Generator) Handler(serv *api.Server) api.Handler {
+	return func(ctx *api.Context) {
+		g.once.Do(func() {
+			g.doc = g.Generate(serv.GetRoutes())
+		})
+		ctx.Reply(http.StatusOK, g.doc)
+	}
+}
+
+// Serve registers the document at url of serv in JSON or YAML, negotiated
+// with the Accept header. For example:
+//
+//	openapi.New(openapi.Info{Title: "fileserver"}).Serve(serv, "/api/openapi")
+func (g *Generator) Serve(serv *api.Server, url string) {
+	serv.GET(url, api.Negotiate(bind.MIMEJSON, bind.MIMEYAML, bind.MIMEYAML2), g.Handler(serv))
+}
+
+// operation reflects on the functions wrapped by api.H of the route.
+func (g *Generator) operation(route api.Route, pathParams []*Parameter, jsonBuilder, formBuilder *schemaBuilder) *Operation {
+	op := &Operation{Responses: make(map[string]*Response)}
+	found := make(map[string]bool, len(pathParams))
+
+	var bodies, forms []*Schema
+	var out reflect.Type
+	for i, fn := range route.Funcs {
+		if fn == nil {
+			continue
+		}
+		t := reflect.TypeOf(fn)
+		for j := 0; j < t.NumIn(); j++ {
+			in := t.In(j)
+			if g.ignored[in] || reflectx.Deref(in).Kind() != reflect.Struct {
+				continue
+			}
+
+			if route.Method == "GET" || route.Method == "DELETE" {
+				op.Parameters = append(op.Parameters, queryParameters(formBuilder, in, pathParams, found)...)
+			} else {
+				bodies = append(bodies, jsonBuilder.schemaOf(in))
+				forms = append(forms, formSchema(formBuilder, in))
+			}
+		}
+
+		// responses are described by the last handler.
+		if i == len(route.Funcs)-1 {
+			op.OperationId = operationId(fn)
+			out = outType(t)
+		}
+	}
+
+	// path parameters not bound to struct fields are described by constraints.
+	params := make([]*Parameter, len(pathParams), len(pathParams)+len(op.Parameters))
+	for i, pp := range pathParams {
+		params[i] = pp
+		for _, p := range op.Parameters {
+			if p.In == "path" && p.Name == pp.Name {
+				params[i] = p
+			}
+		}
+	}
+	for _, p := range op.Parameters {
+		if p.In != "path" {
+			params = append(params, p)
+		}
+	}
+	op.Parameters = params
+
+	if len(bodies) > 0 {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]*MediaType{
+				bind.MIMEJSON:              {Schema: allOf(bodies)},
+				bind.MIMEPOSTForm:          {Schema: allOf(forms)},
+				bind.MIMEMultipartPOSTForm: {Schema: allOf(forms)},
+			},
+		}
+	}
+	if len(bodies) > 0 || len(op.Parameters) > len(pathParams) {
+		op.Responses["400"] = &Response{
+			Description: http.StatusText(http.StatusBadRequest),
+			Content: map[string]*MediaType{
+				bind.MIMEJSON: {Schema: jsonBuilder.schemaOf(typeErrorBody)},
+			},
+		}
+	}
+	op.Responses["200"] = g.response(jsonBuilder, out)
+	return op
+}
+
+// response describes the return value of type t.
+func (g *Generator) response(b *schemaBuilder, t reflect.Type) *Response {
+	res := &Response{Description: http.StatusText(http.StatusOK)}
+	switch {
+	case t == nil:
+	case t.Kind() == reflect.String:
+		res.Content = map[string]*MediaType{bind.MIMEPlain: {Schema: &Schema{Type: "string"}}}
+	case t == typeBytes:
+		res.Content = map[string]*MediaType{"application/octet-stream": {Schema: &Schema{Type: "string", Format: "binary"}}}
+	default:
+		s := b.schemaOf(t)
+		res.Content = make(map[string]*MediaType, len(g.Produces))
+		for _, mime := range g.Produces {
+			res.Content[mime] = &MediaType{Schema: s}
+		}
+	}
+	return res
+}
+
+// queryParameters returns the leaves of the form mapping and the uploaded
+// files as parameters, the leaves named as path parameters are in path.
+func queryParameters(b *schemaBuilder, t reflect.Type, pathParams []*Parameter, found map[string]bool) []*Parameter {
+	t = reflectx.Deref(t)
+	sm := b.mapper.TypeMap(t)
+
+	var params []*Parameter
+	for _, fi := range sm.Fields {
+		if _, ok := sm.Leaves[fi.Path]; (!ok && !isFile(fi.Type)) || isFile(fi.Parent.Type) || found[fi.Path] {
+			continue
+		}
+		found[fi.Path] = true
+
+		field := t.FieldByIndex(fi.Index)
+		p := &Parameter{Name: fi.Path, In: "query", Schema: b.schemaOf(fi.Type)}
+		p.Schema.Default = field.Tag.Get("default")
+		p.Required = applyValidateTag(p.Schema, field.Type, field.Tag.Get("validate"))
+		for _, pp := range pathParams {
+			if pp.Name == fi.Path {
+				p.In = "path"
+				p.Required = true
+			}
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+// formSchema returns an object schema with the leaves of the form mapping.
+func formSchema(b *schemaBuilder, t reflect.Type) *Schema {
+	t = reflectx.Deref(t)
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, p := range queryParameters(b, t, nil, map[string]bool{}) {
+		s.Properties[p.Name] = p.Schema
+		if p.Required {
+			s.Required = append(s.Required, p.Name)
+		}
+	}
+	return s
+}
+
+// isFile reports whether t is *multipart.FileHeader or []*multipart.FileHeader.
+func isFile(t reflect.Type) bool {
+	t = reflectx.Deref(t)
+	if t.Kind() == reflect.Slice {
+		t = reflectx.Deref(t.Elem())
+	}
+	return t == typeFile
+}
+
+func allOf(schemas []*Schema) *Schema {
+	if len(schemas) == 1 {
+		return schemas[0]
+	}
+	return &Schema{AllOf: schemas}
+}
+
+// outType returns the first return type other than int and error.
+func outType(t reflect.Type) reflect.Type {
+	for i := 0; i < t.NumOut(); i++ {
+		out := t.Out(i)
+		if out.Kind() == reflect.Int || out.Implements(typeError) {
+			continue
+		}
+		return out
+	}
+	return nil
+}
+
+// convertPath converts :param, :param<constraint> and *catchAll to {param},
+// and returns the path parameters with the schemas of constraints.
+func convertPath(url string) (string, []*Parameter) {
+	segments := strings.Split(url, "/")
+	var params []*Parameter
+	for i, seg := range segments {
+		if len(seg) > 1 && (seg[0] == ':' || seg[0] == '*') {
+			name, constraint := tree.SplitWildcard(seg)
+			params = append(params, &Parameter{Name: name, In: "path", Required: true, Schema: constraintSchema(constraint)})
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// constraintSchema returns the schema of a param constraint like "int" and "[0-9a-f]{24}".
+func constraintSchema(constraint string) *Schema {
+	switch constraint {
+	case "":
+		return &Schema{Type: "string"}
+	case "int":
+		return &Schema{Type: "integer", Format: "int64"}
+	case "uint":
+		return &Schema{Type: "integer", Format: "int64", Minimum: new(float64)}
+	case "uuid":
+		return &Schema{Type: "string", Format: "uuid"}
+	case "alpha":
+		return &Schema{Type: "string", Pattern: "^[a-zA-Z]+$"}
+	case "hex":
+		return &Schema{Type: "string", Pattern: "^[0-9a-fA-F]+$"}
+	}
+	if _, ok := tree.ParamTypes[constraint]; ok {
+		// custom types can not be described.
+		return &Schema{Type: "string"}
+	}
+	return &Schema{Type: "string", Pattern: "^(?:" + constraint + ")$"}
+}
+
+// operationId returns the name of the function without package path,
+// such as "main.(*Model).ViewGoods".
+func operationId(fn interface{}) string {
+	name := api.FunctionName(fn)
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	// suffix of method values.
+	return strings.TrimSuffix(name, "-fm")
+}
+
+var (
+	typeError     = reflect.TypeOf((*error)(nil)).Elem()
+	typeErrorBody = reflect.TypeOf(api.ErrorBody{})
+)
@@ -0,0 +1,172 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zltgo/api"
+)
+
+type Goods struct {
+	Id    int      `json:"id" form:"id"`
+	Name  string   `json:"name" form:"name" validate:"required,min=2,max=32"`
+	Price float64  `json:"price" form:"price" validate:"gt=0"`
+	Tags  []string `json:"tags,omitempty" form:"tags" validate:"max=5,dive,alpha"`
+	Owner *Owner   `json:"owner,omitempty" form:"-"`
+}
+
+type Owner struct {
+	Email string `json:"email" validate:"required,email"`
+	Next  *Owner `json:"next,omitempty"`
+}
+
+type ListForm struct {
+	Page  int    `form:"page" default:"1" validate:"min=1"`
+	Order string `form:"order" validate:"oneof=asc desc"`
+}
+
+type IdForm struct {
+	Id int `form:"id" validate:"required"`
+}
+
+func newTestServer() *api.Server {
+	serv := api.New()
+	serv.GET("/goods", api.H(func(lf ListForm) []Goods { return nil }))
+	serv.GET("/goods/:id", api.H(func(f IdForm, r *http.Request) (Goods, error) { return Goods{}, nil }))
+	serv.POST("/goods", api.H(func(g *Goods) int { return http.StatusCreated }))
+	serv.DELETE("/goods/:id", api.H(func(ctx *api.Context) {}))
+	serv.GET("/files/*filepath", api.H(func() []byte { return nil }))
+	return serv
+}
+
+func TestGenerate(t *testing.T) {
+	doc := New(Info{Title: "goods"}).Generate(newTestServer().GetRoutes())
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Equal(t, "goods", doc.Info.Title)
+	assert.Equal(t, "v1", doc.Info.Version)
+	assert.Len(t, doc.Paths, 3)
+
+	// query parameters
+	list := doc.Paths["/goods"].Get
+	assert.Len(t, list.Parameters, 2)
+	page := list.Parameters[0]
+	assert.Equal(t, "page", page.Name)
+	assert.Equal(t, "query", page.In)
+	assert.Equal(t, "1", page.Schema.Default)
+	assert.Equal(t, 1.0, *page.Schema.Minimum)
+	assert.Equal(t, []string{"asc", "desc"}, list.Parameters[1].Schema.Enum)
+	assert.Equal(t, "array", list.Responses["200"].Content["application/json"].Schema.Type)
+	assert.Equal(t, "#/components/schemas/Goods", list.Responses["200"].Content["application/json"].Schema.Items.Ref)
+	assert.Equal(t, "#/components/schemas/ErrorBody", list.Responses["400"].Content["application/json"].Schema.Ref)
+
+	// path parameter bound to the struct field
+	view := doc.Paths["/goods/{id}"].Get
+	assert.Len(t, view.Parameters, 1)
+	assert.Equal(t, "path", view.Parameters[0].In)
+	assert.Equal(t, "integer", view.Parameters[0].Schema.Type)
+	assert.True(t, view.Parameters[0].Required)
+
+	// path parameter not bound
+	del := doc.Paths["/goods/{id}"].Delete
+	assert.Len(t, del.Parameters, 1)
+	assert.Equal(t, "string", del.Parameters[0].Schema.Type)
+	assert.Nil(t, del.Responses["400"])
+	assert.Nil(t, del.Responses["200"].Content)
+
+	// request body
+	add := doc.Paths["/goods"].Post
+	assert.True(t, add.RequestBody.Required)
+	assert.Equal(t, "#/components/schemas/Goods", add.RequestBody.Content["application/json"].Schema.Ref)
+	form := add.RequestBody.Content["application/x-www-form-urlencoded"].Schema
+	assert.Equal(t, []string{"name"}, form.Required)
+	assert.Nil(t, form.Properties["owner"])
+	assert.Nil(t, add.Responses["200"].Content)
+
+	files := doc.Paths["/files/{filepath}"].Get
+	assert.Equal(t, "filepath", files.Parameters[0].Name)
+	assert.Equal(t, "binary", files.Responses["200"].Content["application/octet-stream"].Schema.Format)
+
+	// components
+	goods := doc.Components.Schemas["Goods"]
+	assert.Equal(t, []string{"name"}, goods.Required)
+	assert.Equal(t, uint64(2), *goods.Properties["name"].MinLength)
+	assert.Equal(t, uint64(32), *goods.Properties["name"].MaxLength)
+	assert.Equal(t, 0.0, *goods.Properties["price"].Minimum)
+	assert.True(t, goods.Properties["price"].ExclusiveMinimum)
+	assert.Equal(t, uint64(5), *goods.Properties["tags"].MaxItems)
+	assert.Equal(t, "^[a-zA-Z]+$", goods.Properties["tags"].Items.Pattern)
+	assert.Equal(t, "#/components/schemas/Owner", goods.Properties["owner"].Ref)
+
+	owner := doc.Components.Schemas["Owner"]
+	assert.Equal(t, "email", owner.Properties["email"].Format)
+	assert.Equal(t, "#/components/schemas/Owner", owner.Properties["next"].Ref)
+}
+
+func TestGenerateAny(t *testing.T) {
+	echo := api.H(func(f IdForm) Goods { return Goods{} })
+	routes := api.Routes{
+		{Method: "ANY", Url: "/echo", Handlers: []api.Handler{echo}, Funcs: []interface{}{api.FuncOf(echo)}},
+		{Method: "CONNECT", Url: "/tunnel", Handlers: []api.Handler{echo}, Funcs: []interface{}{api.FuncOf(echo)}},
+	}
+	doc := New(Info{Title: "echo"}).Generate(routes)
+	assert.Len(t, doc.Paths, 1)
+	item := doc.Paths["/echo"]
+	ids := make(map[string]bool)
+	for _, op := range []*Operation{item.Get, item.Post, item.Put, item.Delete, item.Patch, item.Head, item.Options, item.Trace} {
+		if assert.NotNil(t, op) {
+			assert.False(t, ids[op.OperationId], op.OperationId)
+			ids[op.OperationId] = true
+		}
+	}
+	assert.Len(t, item.Get.Parameters, 1)
+	assert.NotNil(t, item.Post.RequestBody)
+	assert.Equal(t, item.Get.OperationId+"_post", item.Post.OperationId)
+
+	// the routes of ANY are reported by the server for each method.
+	serv := api.New()
+	serv.ANY("/echo", echo)
+	doc = New(Info{Title: "echo"}).Generate(serv.GetRoutes())
+	assert.NotEqual(t, doc.Paths["/echo"].Get.OperationId, doc.Paths["/echo"].Post.OperationId)
+}
+
+func TestConvertPath(t *testing.T) {
+	path, params := convertPath("/user/:name/files/*filepath")
+	assert.Equal(t, "/user/{name}/files/{filepath}", path)
+	assert.Len(t, params, 2)
+	assert.Equal(t, "name", params[0].Name)
+	assert.Equal(t, "filepath", params[1].Name)
+
+	path, params = convertPath("/user/:id<uint>/doc/:uuid<[0-9a-f]{24}>")
+	assert.Equal(t, "/user/{id}/doc/{uuid}", path)
+	assert.Equal(t, "integer", params[0].Schema.Type)
+	assert.Equal(t, 0.0, *params[0].Schema.Minimum)
+	assert.Equal(t, "^(?:[0-9a-f]{24})$", params[1].Schema.Pattern)
+
+	path, params = convertPath("/")
+	assert.Equal(t, "/", path)
+	assert.Nil(t, params)
+}
+
+func TestServe(t *testing.T) {
+	serv := newTestServer()
+	New(Info{}).Serve(serv, "/openapi")
+
+	r, _ := http.NewRequest("GET", "/openapi", nil)
+	w := httptest.NewRecorder()
+	serv.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var doc Document
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, "API", doc.Info.Title)
+	assert.NotNil(t, doc.Paths["/openapi"].Get)
+
+	r.Header.Set("Accept", "application/x-yaml")
+	w = httptest.NewRecorder()
+	serv.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "openapi: 3.0.3")
+}
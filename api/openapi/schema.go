@@ -0,0 +1,242 @@
+package openapi
+
+import (
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zltgo/api/bind"
+	"github.com/zltgo/reflectx"
+)
+
+var (
+	typeTime  = reflect.TypeOf(time.Time{})
+	typeBytes = reflect.TypeOf([]byte(nil))
+	typeFile  = reflect.TypeOf(multipart.FileHeader{})
+
+	// patterns of the validation tags of validator.v9.
+	tagPatterns = map[string]string{
+		"alpha":        "^[a-zA-Z]+$",
+		"alphanum":     "^[a-zA-Z0-9]+$",
+		"alphaunicode": "^[\\p{L}]+$",
+		"numeric":      "^[-+]?[0-9]+(?:\\.[0-9]+)?$",
+		"number":       "^[0-9]+$",
+		"hexadecimal":  "^(0[xX])?[0-9a-fA-F]+$",
+	}
+
+	// formats of the validation tags of validator.v9.
+	tagFormats = map[string]string{
+		"email":    "email",
+		"url":      "uri",
+		"uri":      "uri",
+		"uuid":     "uuid",
+		"uuid4":    "uuid",
+		"ip":       "ip",
+		"ipv4":     "ipv4",
+		"ipv6":     "ipv6",
+		"hostname": "hostname",
+		"datetime": "date-time",
+	}
+)
+
+// schemaBuilder creates schemas of types by the field names of a reflectx.Mapper.
+// Named struct types are stored in schemas and referenced by $ref.
+type schemaBuilder struct {
+	mapper  *reflectx.Mapper
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaBuilder(tagName string, schemas map[string]*Schema) *schemaBuilder {
+	return &schemaBuilder{
+		mapper:  reflectx.NewMapper(tagName, nil),
+		schemas: schemas,
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// schemaOf returns the schema of t, a $ref is returned for named struct types.
+func (b *schemaBuilder) schemaOf(t reflect.Type) *Schema {
+	t = reflectx.Deref(t)
+	switch t {
+	case typeTime:
+		return &Schema{Type: "string", Format: "date-time"}
+	case typeBytes:
+		return &Schema{Type: "string", Format: "byte"}
+	case typeFile:
+		return &Schema{Type: "string", Format: "binary"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32", Minimum: new(float64)}
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Format: "int64", Minimum: new(float64)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		return b.refOf(t)
+	}
+	// interface{} and others, any type is allowed.
+	return &Schema{}
+}
+
+// refOf returns a $ref of the struct type, anonymous struct is inlined.
+func (b *schemaBuilder) refOf(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return b.structSchema(t)
+	}
+
+	name, ok := b.names[t]
+	if !ok {
+		name = t.Name()
+		// types with the same name in different packages.
+		for i := 2; b.schemas[name] != nil; i++ {
+			name = t.Name() + strconv.Itoa(i)
+		}
+		b.names[t] = name
+		// placeholder for recursive types.
+		b.schemas[name] = &Schema{}
+		*b.schemas[name] = *b.structSchema(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// structSchema returns the object schema of the struct type.
+func (b *schemaBuilder) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	sm := b.mapper.TypeMap(t)
+	for _, fi := range sm.Tree.Children {
+		field := t.FieldByIndex(fi.Index)
+		fs := b.schemaOf(fi.Type)
+		if fs.Ref == "" {
+			fs.Default = field.Tag.Get("default")
+		}
+		if applyValidateTag(fs, field.Type, field.Tag.Get("validate")) {
+			s.Required = append(s.Required, fi.Name)
+		}
+		s.Properties[fi.Name] = fs
+	}
+	return s
+}
+
+// applyValidateTag sets the constraints of the validation tag to s,
+// and reports whether the field is required.
+// Constraints are ignored if s is a $ref, siblings of $ref are not allowed.
+func applyValidateTag(s *Schema, t reflect.Type, tag string) (required bool) {
+	t = reflectx.Deref(t)
+	for _, rule := range strings.Split(tag, ",") {
+		if rule == "dive" {
+			// the remaining rules are applied to the elements.
+			if s.Items != nil && t.Kind() != reflect.Map {
+				applyValidateTag(s.Items, t.Elem(), tag[strings.Index(tag, "dive")+len("dive"):])
+			}
+			return
+		}
+		// skip or-rules, they can not be described simply.
+		if rule == "" || strings.Contains(rule, "|") {
+			continue
+		}
+
+		name, param := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		if name == "required" {
+			required = true
+			continue
+		}
+		if s.Ref != "" {
+			continue
+		}
+
+		switch name {
+		case "min", "gte":
+			setMin(s, t, param, false)
+		case "gt":
+			setMin(s, t, param, true)
+		case "max", "lte":
+			setMax(s, t, param, false)
+		case "lt":
+			setMax(s, t, param, true)
+		case "len", "eq":
+			setMin(s, t, param, false)
+			setMax(s, t, param, false)
+		case "oneof":
+			s.Enum = strings.Fields(param)
+		default:
+			if pattern, ok := tagPatterns[name]; ok {
+				s.Pattern = pattern
+			} else if format, ok := tagFormats[name]; ok {
+				s.Format = format
+			} else if r, ok := bind.Regexp(name); ok {
+				s.Pattern = r.String()
+			}
+		}
+	}
+	return
+}
+
+// setMin sets minimum, minLength or minItems by the kind of t.
+func setMin(s *Schema, t reflect.Type, param string, exclusive bool) {
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		n, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return
+		}
+		if exclusive {
+			n++
+		}
+		if t.Kind() == reflect.String {
+			s.MinLength = &n
+		} else {
+			s.MinItems = &n
+		}
+	default:
+		if f, err := strconv.ParseFloat(param, 64); err == nil {
+			s.Minimum = &f
+			s.ExclusiveMinimum = exclusive
+		}
+	}
+}
+
+// setMax sets maximum, maxLength or maxItems by the kind of t.
+func setMax(s *Schema, t reflect.Type, param string, exclusive bool) {
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		n, err := strconv.ParseUint(param, 10, 64)
+		if err != nil || (exclusive && n == 0) {
+			return
+		}
+		if exclusive {
+			n--
+		}
+		if t.Kind() == reflect.String {
+			s.MaxLength = &n
+		} else {
+			s.MaxItems = &n
+		}
+	default:
+		if f, err := strconv.ParseFloat(param, 64); err == nil {
+			s.Maximum = &f
+			s.ExclusiveMaximum = exclusive
+		}
+	}
+}
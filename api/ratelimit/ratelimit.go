@@ -5,6 +5,15 @@ import (
 	"time"
 )
 
+// number of calls rejected by all the limiters.
+var rejections int64
+
+// Rejections returns the number of calls rejected by all the limiters,
+// it is exported as a counter by 'zltgo/api/metrics'.
+func Rejections() int64 {
+	return atomic.LoadInt64(&rejections)
+}
+
 type Rate struct {
 	// limit count in one period if time.
 	// Zero means limit every time.
@@ -133,5 +142,9 @@ func (rl *Limiter) Reached() bool {
 	}
 
 	// pass every rate limit.
-	return flag > 0
+	if flag > 0 {
+		atomic.AddInt64(&rejections, 1)
+		return true
+	}
+	return false
 }
@@ -48,6 +48,15 @@ func TestRatelimit(t *testing.T) {
 		So(count, ShouldEqual, 500)
 	})
 
+	Convey("should count rejections", t, func() {
+		before := Rejections()
+		rl := New(SecOpts(2, 60))
+		for i := 0; i < 5; i++ {
+			rl.Reached()
+		}
+		So(Rejections()-before, ShouldEqual, 3)
+	})
+
 	Convey("should correctly increase allowance", t, func() {
 		n := 10
 		rl := New(Opts(time.Millisecond, n, 10))
@@ -35,7 +35,11 @@ func RecoveryWithWriter(out io.Writer) Handler {
 				if logger != nil {
 					stack := stack(3)
 					httprequest, _ := httputil.DumpRequest(c.Request, false)
-					logger.Printf("[Recovery] panic recovered:\n%s\n%s\n%s%s", string(httprequest), err, stack, reset)
+					var trace string
+					if t := c.Trace(); t != nil {
+						trace = " " + t.RequestID + " " + t.TraceID
+					}
+					logger.Printf("[Recovery] panic recovered%s:\n%s\n%s\n%s%s", trace, string(httprequest), err, stack, reset)
 				}
 				c.Writer.WriteHeader(http.StatusInternalServerError)
 				c.Writer.WriteString("500 internal server error")
@@ -0,0 +1,100 @@
+package render
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// Attachment renders the content of Reader as a file to download, the
+// Content-Disposition header carries Filename in RFC 5987 encoding, so
+// non-ASCII names such as "报告.pdf" are kept by the browsers. For example:
+//	f, _ := os.Open(path)
+//	defer f.Close()
+//	ctx.Reply(http.StatusOK, render.Attachment{Filename: "报告.pdf", Reader: f})
+type Attachment struct {
+	Filename string
+	// ContentType is detected by the extension of Filename if it is empty,
+	// default is "application/octet-stream".
+	ContentType string
+	Reader      io.Reader
+	// Inline lets the browsers display the file instead of downloading it.
+	Inline bool
+}
+
+func (r Attachment) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	disposition := "attachment"
+	if r.Inline {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Disposition", ContentDisposition(disposition, r.Filename))
+	if r.Reader == nil {
+		return nil
+	}
+	_, err := io.Copy(w, r.Reader)
+	return err
+}
+
+func (r Attachment) WriteContentType(w http.ResponseWriter) {
+	ct := r.ContentType
+	if ct == "" {
+		ct = mime.TypeByExtension(filepath.Ext(r.Filename))
+	}
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	writeContentType(w, []string{ct})
+}
+
+// ContentDisposition returns the value of the Content-Disposition header of
+// disposition and filename. The filename parameter is the ASCII fallback for
+// old clients, and the filename* parameter is the UTF-8 name encoded by RFC 5987.
+// For example, ContentDisposition("attachment", "报告.pdf") returns:
+//	attachment; filename="__.pdf"; filename*=UTF-8''%E6%8A%A5%E5%91%8A.pdf
+func ContentDisposition(disposition, filename string) string {
+	if filename == "" {
+		return disposition
+	}
+
+	var fallback strings.Builder
+	ascii := true
+	for _, c := range filename {
+		switch {
+		case c == '"' || c == '\\':
+			fallback.WriteByte('\\')
+			fallback.WriteRune(c)
+		case c < ' ' || c > '~':
+			ascii = false
+			fallback.WriteByte('_')
+		default:
+			fallback.WriteRune(c)
+		}
+	}
+	value := disposition + `; filename="` + fallback.String() + `"`
+	if ascii {
+		return value
+	}
+	return value + "; filename*=UTF-8''" + encodeRFC5987(filename)
+}
+
+// encodeRFC5987 percent-encodes the bytes of s other than attr-char,
+// see https://tools.ietf.org/html/rfc5987#section-3.2.1.
+func encodeRFC5987(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+			strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&15])
+	}
+	return b.String()
+}
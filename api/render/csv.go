@@ -0,0 +1,122 @@
+package render
+
+import (
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/zltgo/reflectx"
+)
+
+// CSVMapper maps the columns of CSV by the tag "csv", such as:
+//	type Goods struct {
+//		Name  string  `csv:"name"`
+//		Price float64 `csv:"price"`
+//		Note  string  `csv:"-"`
+//	}
+var CSVMapper = reflectx.NewMapper("csv", nil)
+
+// CSV renders a slice or an array of structs as CSV, the first row is the
+// header of the column names. The columns are the leaf fields of the structs
+// in the order of declaration, nested structs are expanded as "Parent.Field".
+type CSV struct {
+	Data interface{}
+	// Comma is the field delimiter, default is ','.
+	Comma rune
+	// NoHeader omits the header row.
+	NoHeader bool
+}
+
+var csvContentType = []string{"text/csv; charset=utf-8"}
+
+func (r CSV) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+
+	v := reflect.Indirect(reflect.ValueOf(r.Data))
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return errors.New("render: data of CSV must be a slice or an array of structs")
+	}
+	elem := reflectx.Deref(v.Type().Elem())
+	if elem.Kind() != reflect.Struct {
+		return errors.New("render: data of CSV must be a slice or an array of structs")
+	}
+	columns := csvColumns(elem)
+
+	cw := csv.NewWriter(w)
+	if r.Comma != 0 {
+		cw.Comma = r.Comma
+	}
+	record := make([]string, len(columns))
+	if !r.NoHeader {
+		for i, fi := range columns {
+			record[i] = fi.Path
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		row := reflect.Indirect(v.Index(i))
+		for j, fi := range columns {
+			record[j] = ""
+			if !row.IsValid() {
+				continue
+			}
+			// nil pointers are empty.
+			fv := reflect.Indirect(reflectx.FieldByIndexesReadOnly(row, fi.Index))
+			if !fv.IsValid() {
+				continue
+			}
+			str, err := csvValue(fv)
+			if err != nil {
+				return err
+			}
+			record[j] = str
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (r CSV) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, csvContentType)
+}
+
+// csvValue formats v by encoding.TextMarshaler, reflectx.ValueToStr or fmt.Sprint.
+func csvValue(v reflect.Value) (string, error) {
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+	if str, err := reflectx.ValueToStr(v); err == nil {
+		return str, nil
+	}
+	return fmt.Sprint(v.Interface()), nil
+}
+
+// csvColumns returns the leaf fields of t in the order of declaration.
+func csvColumns(t reflect.Type) []*reflectx.FieldInfo {
+	leaves := CSVMapper.TypeMap(t).Leaves
+	columns := make([]*reflectx.FieldInfo, 0, len(leaves))
+	for _, fi := range leaves {
+		columns = append(columns, fi)
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		a, b := columns[i].Index, columns[j].Index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return columns
+}
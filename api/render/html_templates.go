@@ -0,0 +1,273 @@
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zltgo/reflectx"
+)
+
+// HTMLOptions configures HTMLTemplates, zero fields are set to default values.
+type HTMLOptions struct {
+	// FS contains the templates, such as an embed.FS, the names of templates
+	// are their paths in FS. Use os.DirFS with Reload in debug mode.
+	FS fs.FS
+
+	// Pages are the glob patterns of pages, each page is parsed with its
+	// layout and the partials into its own template set.
+	Pages []string `default:"pages/*.html"`
+
+	// Partials are the glob patterns of templates shared by all the pages.
+	Partials []string `default:"partials/*.html"`
+
+	// Layout is the default layout of pages. A page can declare its layout in
+	// the first line, "none" means the page has no layout:
+	//	{{/* layout: layouts/admin.html */}}
+	// The layout is executed instead of the page, and the page overrides the
+	// blocks of the layout, such as {{block "content" .}}{{end}}.
+	Layout string
+
+	Delims  Delims
+	FuncMap template.FuncMap
+
+	// Reload reparses the templates when the files of FS change, which are
+	// checked every ReloadInterval milliseconds by a watcher.
+	Reload         bool
+	ReloadInterval int `default:"500"`
+}
+
+// HTMLTemplates is an HTMLRender of multiple pages with layouts and partials.
+// For example:
+//	//go:embed templates
+//	var files embed.FS
+//
+//	tmpls, err := render.NewHTMLTemplates(render.HTMLOptions{
+//		FS:      must(fs.Sub(files, "templates")),
+//		Layout:  "layouts/base.html",
+//		FuncMap: render.MergeFuncMaps(serv.FuncMap(), csrf.FuncMap()),
+//	})
+//	serv.OnShutdown(tmpls.Close)
+//	serv.GET("/", func(ctx *api.Context) {
+//		ctx.Reply(http.StatusOK, tmpls.Instance("pages/index.html", data))
+//	})
+type HTMLTemplates struct {
+	opts HTMLOptions
+
+	mu    sync.RWMutex
+	pages map[string]*template.Template
+	err   error
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+var _ HTMLRender = &HTMLTemplates{}
+
+// NewHTMLTemplates parses all the pages, and starts the watcher if opts.Reload is true.
+func NewHTMLTemplates(opts HTMLOptions) (*HTMLTemplates, error) {
+	if opts.FS == nil {
+		return nil, errors.New("render: FS of HTMLOptions can not be nil")
+	}
+	reflectx.SetDefault(&opts)
+	if opts.Delims.Left == "" {
+		opts.Delims.Left = "{{"
+	}
+	if opts.Delims.Right == "" {
+		opts.Delims.Right = "}}"
+	}
+
+	r := &HTMLTemplates{opts: opts, done: make(chan struct{})}
+	// take the snapshot before parsing, the changes during parsing are not missed.
+	var last string
+	if opts.Reload {
+		last = r.snapshot()
+	}
+	pages, err := r.parse()
+	if err != nil {
+		return nil, err
+	}
+	r.pages = pages
+	if opts.Reload {
+		go r.watch(last, time.Duration(opts.ReloadInterval)*time.Millisecond)
+	}
+	return r, nil
+}
+
+// Instance returns the Render of the page of name, such as "pages/index.html".
+// The Render returns an error if the page is not found or the templates
+// failed to reload.
+func (r *HTMLTemplates) Instance(name string, data interface{}) Render {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.err != nil {
+		return errorRender{r.err}
+	}
+	t, ok := r.pages[name]
+	if !ok {
+		return errorRender{errors.New("render: html page '" + name + "' not found")}
+	}
+	return HTML{Template: t, Data: data}
+}
+
+// Close stops the watcher.
+func (r *HTMLTemplates) Close() error {
+	r.closeOnce.Do(func() { close(r.done) })
+	return nil
+}
+
+// parse parses the pages with their layouts and the partials.
+func (r *HTMLTemplates) parse() (map[string]*template.Template, error) {
+	partials, err := r.glob(r.opts.Partials)
+	if err != nil {
+		return nil, err
+	}
+	pageNames, err := r.glob(r.opts.Pages)
+	if err != nil {
+		return nil, err
+	}
+
+	layoutRe := regexp.MustCompile(`^\s*` + regexp.QuoteMeta(r.opts.Delims.Left) +
+		`/\*\s*layout:\s*(\S+)\s*\*/` + regexp.QuoteMeta(r.opts.Delims.Right))
+	pages := make(map[string]*template.Template, len(pageNames))
+	for _, name := range pageNames {
+		page, err := fs.ReadFile(r.opts.FS, name)
+		if err != nil {
+			return nil, err
+		}
+		layout := r.opts.Layout
+		if line, _ := bufio.NewReader(bytes.NewReader(page)).ReadString('\n'); line != "" {
+			if m := layoutRe.FindStringSubmatch(line); m != nil {
+				layout = m[1]
+			}
+		}
+		if layout == "none" {
+			layout = ""
+		}
+
+		// the root template is the one executed.
+		root := name
+		if layout != "" {
+			root = layout
+		}
+		t := template.New(root).Delims(r.opts.Delims.Left, r.opts.Delims.Right).Funcs(r.opts.FuncMap)
+		files := append([]string{}, partials...)
+		if layout != "" {
+			files = append([]string{layout}, files...)
+		}
+		for _, file := range files {
+			if err := r.parseFile(t, file, nil); err != nil {
+				return nil, err
+			}
+		}
+		if err := r.parseFile(t, name, page); err != nil {
+			return nil, err
+		}
+		pages[name] = t
+	}
+	return pages, nil
+}
+
+// parseFile parses the file of name into t, data is read from FS if it is nil.
+func (r *HTMLTemplates) parseFile(t *template.Template, name string, data []byte) (err error) {
+	if data == nil {
+		if data, err = fs.ReadFile(r.opts.FS, name); err != nil {
+			return err
+		}
+	}
+	tt := t
+	if name != t.Name() {
+		tt = t.New(name)
+	}
+	_, err = tt.Parse(string(data))
+	return err
+}
+
+// glob returns the sorted file names matching patterns.
+func (r *HTMLTemplates) glob(patterns []string) ([]string, error) {
+	var names []string
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(r.opts.FS, pattern)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, matches...)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// watch reparses the templates if the files of FS change from the snapshot last.
+func (r *HTMLTemplates) watch(last string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		}
+		cur := r.snapshot()
+		if cur == last {
+			continue
+		}
+		last = cur
+
+		pages, err := r.parse()
+		r.mu.Lock()
+		if r.err = err; err == nil {
+			r.pages = pages
+		}
+		r.mu.Unlock()
+	}
+}
+
+// snapshot returns the names, sizes and modification times of the files of FS.
+func (r *HTMLTemplates) snapshot() string {
+	var b strings.Builder
+	fs.WalkDir(r.opts.FS, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if fi, err := d.Info(); err == nil {
+			b.WriteString(path)
+			b.WriteString(fi.ModTime().String())
+			b.WriteString(strconv.FormatInt(fi.Size(), 10))
+		}
+		return nil
+	})
+	return b.String()
+}
+
+// MergeFuncMaps returns a FuncMap of all the functions of maps,
+// the latter ones win if the names are the same.
+func MergeFuncMaps(maps ...template.FuncMap) template.FuncMap {
+	merged := template.FuncMap{}
+	for _, m := range maps {
+		for k, v := range m {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// errorRender returns err on rendering.
+type errorRender struct {
+	err error
+}
+
+func (r errorRender) Render(http.ResponseWriter) error { return r.err }
+
+func (r errorRender) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, htmlContentType)
+}
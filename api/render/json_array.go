@@ -0,0 +1,107 @@
+package render
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+)
+
+// JSONArray renders the elements of Data as a JSON array one by one, so large
+// result sets are never fully buffered. Data can be a channel which is read
+// until closed, or an iterator of iter.Seq, such as:
+//	ch := make(chan Goods)
+//	go func() {
+//		defer close(ch)
+//		for rows.Next() {
+//			...
+//			select {
+//			case ch <- goods:
+//			case <-ctx.Done():
+//				return
+//			}
+//		}
+//	}()
+//	ctx.Reply(http.StatusOK, render.JSONArray{Data: ch})
+// The channel is not read any more if writing fails, so the sender should
+// watch the request context as above.
+// The response is flushed every FlushSize elements if the writer is an http.Flusher.
+type JSONArray struct {
+	Data interface{}
+	// FlushSize is the number of elements between flushes, 0 means never.
+	FlushSize int
+}
+
+var errJSONArray = errors.New("render: data of JSONArray must be a channel or an iter.Seq")
+
+func (r JSONArray) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+
+	v := reflect.ValueOf(r.Data)
+	var each func(fn func(elem interface{}) bool)
+	switch {
+	case !v.IsValid():
+		return errJSONArray
+	case v.Kind() == reflect.Chan && v.Type().ChanDir()&reflect.RecvDir != 0:
+		each = func(fn func(elem interface{}) bool) {
+			for {
+				elem, ok := v.Recv()
+				if !ok || !fn(elem.Interface()) {
+					return
+				}
+			}
+		}
+	case isSeq(v.Type()):
+		each = func(fn func(elem interface{}) bool) {
+			yield := reflect.MakeFunc(v.Type().In(0), func(args []reflect.Value) []reflect.Value {
+				return []reflect.Value{reflect.ValueOf(fn(args[0].Interface()))}
+			})
+			v.Call([]reflect.Value{yield})
+		}
+	default:
+		return errJSONArray
+	}
+
+	flusher, _ := w.(http.Flusher)
+	if _, err := w.Write([]byte{'['}); err != nil {
+		return err
+	}
+	var err error
+	n := 0
+	each(func(elem interface{}) bool {
+		var b []byte
+		if b, err = json.Marshal(elem); err != nil {
+			return false
+		}
+		if n > 0 {
+			b = append([]byte{','}, b...)
+		}
+		if _, err = w.Write(b); err != nil {
+			return false
+		}
+		n++
+		if flusher != nil && r.FlushSize > 0 && n%r.FlushSize == 0 {
+			flusher.Flush()
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write([]byte{']'})
+	return err
+}
+
+func (r JSONArray) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+// isSeq reports whether t is func(yield func(T) bool).
+func isSeq(t reflect.Type) bool {
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 0 {
+		return false
+	}
+	yield := t.In(0)
+	return yield.Kind() == reflect.Func && yield.NumIn() == 1 &&
+		yield.NumOut() == 1 && yield.Out(0).Kind() == reflect.Bool
+}
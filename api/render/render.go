@@ -24,6 +24,10 @@ var (
 	_ HTMLRender = HTMLProduction{}
 	_ Render     = YAML{}
 	_ Render     = MsgPack{}
+	_ Render     = ProtoBuf{}
+	_ Render     = CSV{}
+	_ Render     = JSONArray{}
+	_ Render     = Attachment{}
 )
 
 func writeContentType(w http.ResponseWriter, value []string) {
@@ -8,11 +8,19 @@ import (
 	"bytes"
 	"encoding/xml"
 	"html/template"
+	"mime"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 // TODO unit tests
@@ -176,3 +184,212 @@ func TestRenderHTMLTemplate(t *testing.T) {
 	assert.Equal(t, w.Body.String(), "Hello alexandernyquist")
 	assert.Equal(t, w.Header().Get("Content-Type"), "text/html; charset=utf-8")
 }
+
+func TestRenderProtoBuf(t *testing.T) {
+	w := httptest.NewRecorder()
+	msg := wrapperspb.String("hello")
+	assert.NoError(t, (ProtoBuf{msg}).Render(w))
+	assert.Equal(t, "application/x-protobuf", w.Header().Get("Content-Type"))
+
+	var decoded wrapperspb.StringValue
+	assert.NoError(t, proto.Unmarshal(w.Body.Bytes(), &decoded))
+	assert.Equal(t, "hello", decoded.Value)
+
+	assert.Error(t, (ProtoBuf{"not a message"}).Render(httptest.NewRecorder()))
+}
+
+type csvGoods struct {
+	Name   string `csv:"name"`
+	Price  float64
+	Secret string `csv:"-"`
+	Tags   []string
+	Maker  *struct {
+		City string `csv:"city"`
+	}
+}
+
+func TestRenderCSV(t *testing.T) {
+	w := httptest.NewRecorder()
+	goods := []*csvGoods{
+		{Name: `apple, "red"`, Price: 1.5, Secret: "x", Tags: []string{"a", "b"}},
+		nil,
+		{Name: "pear", Price: 2},
+	}
+	goods[2].Maker = &struct {
+		City string `csv:"city"`
+	}{"Beijing"}
+
+	assert.NoError(t, (CSV{Data: goods}).Render(w))
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "name,Price,Tags,Maker.city\n"+
+		"\"apple, \"\"red\"\"\",1.5,[a b],\n"+
+		",,,\n"+
+		"pear,2,[],Beijing\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	assert.NoError(t, (CSV{Data: []csvGoods{{Name: "apple"}}, Comma: ';', NoHeader: true}).Render(w))
+	assert.Equal(t, "apple;0;[];\n", w.Body.String())
+
+	assert.Error(t, (CSV{Data: []int{1}}).Render(httptest.NewRecorder()))
+	assert.Error(t, (CSV{Data: csvGoods{}}).Render(httptest.NewRecorder()))
+}
+
+func TestRenderJSONArray(t *testing.T) {
+	ch := make(chan map[string]int)
+	go func() {
+		defer close(ch)
+		for i := 0; i < 3; i++ {
+			ch <- map[string]int{"id": i}
+		}
+	}()
+	w := httptest.NewRecorder()
+	assert.NoError(t, (JSONArray{Data: ch, FlushSize: 2}).Render(w))
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `[{"id":0},{"id":1},{"id":2}]`, w.Body.String())
+	assert.True(t, w.Flushed)
+
+	// iter.Seq
+	seq := func(yield func(string) bool) {
+		for _, s := range []string{"a", "b", "c"} {
+			if !yield(s) {
+				return
+			}
+		}
+	}
+	w = httptest.NewRecorder()
+	assert.NoError(t, (JSONArray{Data: seq}).Render(w))
+	assert.Equal(t, `["a","b","c"]`, w.Body.String())
+	assert.False(t, w.Flushed)
+
+	empty := make(chan int)
+	close(empty)
+	w = httptest.NewRecorder()
+	assert.NoError(t, (JSONArray{Data: empty}).Render(w))
+	assert.Equal(t, `[]`, w.Body.String())
+
+	// stop iterating on errors.
+	bad := make(chan interface{}, 2)
+	bad <- 1
+	bad <- func() {}
+	close(bad)
+	assert.Error(t, (JSONArray{Data: bad}).Render(httptest.NewRecorder()))
+
+	assert.Error(t, (JSONArray{Data: []int{1}}).Render(httptest.NewRecorder()))
+	assert.Error(t, (JSONArray{}).Render(httptest.NewRecorder()))
+}
+
+func TestRenderAttachment(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := (Attachment{Filename: "报告 2019.pdf", Reader: strings.NewReader("%PDF")}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="__ 2019.pdf"; filename*=UTF-8''%E6%8A%A5%E5%91%8A%202019.pdf`,
+		w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "%PDF", w.Body.String())
+
+	_, params, err := mime.ParseMediaType(w.Header().Get("Content-Disposition"))
+	assert.NoError(t, err)
+	assert.Equal(t, "报告 2019.pdf", params["filename"])
+
+	w = httptest.NewRecorder()
+	assert.NoError(t, (Attachment{Filename: `a"b.unknown`, Inline: true}).Render(w))
+	assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, `inline; filename="a\"b.unknown"`, w.Header().Get("Content-Disposition"))
+
+	assert.Equal(t, "attachment", ContentDisposition("attachment", ""))
+}
+
+func TestHTMLTemplates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.html":  {Data: []byte(`<html>{{template "nav" .}}{{block "content" .}}empty{{end}}</html>`)},
+		"layouts/admin.html": {Data: []byte(`<admin>{{block "content" .}}{{end}}</admin>`)},
+		"partials/nav.html":  {Data: []byte(`{{define "nav"}}<nav>{{upper .}}</nav>{{end}}`)},
+		"pages/index.html":   {Data: []byte(`{{define "content"}}<p>{{.}}</p>{{end}}`)},
+		"pages/admin.html":   {Data: []byte("{{/* layout: layouts/admin.html */}}\n{{define \"content\"}}{{.}}{{end}}")},
+		"pages/plain.html":   {Data: []byte("{{/* layout: none */}}\n{{template \"nav\" .}}")},
+	}
+	_, err := NewHTMLTemplates(HTMLOptions{})
+	assert.Error(t, err)
+
+	tmpls, err := NewHTMLTemplates(HTMLOptions{
+		FS:      fsys,
+		Layout:  "layouts/base.html",
+		FuncMap: MergeFuncMaps(template.FuncMap{"upper": strings.ToLower}, template.FuncMap{"upper": strings.ToUpper}),
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer tmpls.Close()
+
+	render := func(name string, data interface{}) (string, error) {
+		w := httptest.NewRecorder()
+		err := tmpls.Instance(name, data).Render(w)
+		if err == nil {
+			assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+		}
+		return w.Body.String(), err
+	}
+
+	body, err := render("pages/index.html", "hi")
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><nav>HI</nav><p>hi</p></html>", body)
+
+	body, err = render("pages/admin.html", "<b>")
+	assert.NoError(t, err)
+	assert.Equal(t, "<admin>&lt;b&gt;</admin>", body)
+
+	body, err = render("pages/plain.html", "x")
+	assert.NoError(t, err)
+	assert.Equal(t, "\n<nav>X</nav>", body)
+
+	_, err = render("pages/missing.html", nil)
+	assert.Error(t, err)
+
+	// a page with an unknown layout fails.
+	fsys["pages/bad.html"] = &fstest.MapFile{Data: []byte("{{/* layout: layouts/none.html */}}")}
+	_, err = NewHTMLTemplates(HTMLOptions{FS: fsys})
+	assert.Error(t, err)
+}
+
+func TestHTMLTemplatesReload(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(data), 0644))
+	}
+	write("pages/index.html", "v1")
+
+	tmpls, err := NewHTMLTemplates(HTMLOptions{FS: os.DirFS(dir), Reload: true, ReloadInterval: 5})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer tmpls.Close()
+
+	render := func() string {
+		w := httptest.NewRecorder()
+		if err := tmpls.Instance("pages/index.html", nil).Render(w); err != nil {
+			return err.Error()
+		}
+		return w.Body.String()
+	}
+	wait := func(want string) {
+		for i := 0; i < 200 && render() != want; i++ {
+			time.Sleep(5 * time.Millisecond)
+		}
+		assert.Equal(t, want, render())
+	}
+	assert.Equal(t, "v1", render())
+
+	write("pages/index.html", "version 2")
+	wait("version 2")
+
+	// the error of reloading is returned until the files are fixed.
+	write("pages/index.html", "{{")
+	for i := 0; i < 200 && !strings.Contains(render(), "unclosed"); i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Contains(t, render(), "unclosed")
+	write("pages/index.html", "version 3")
+	wait("version 3")
+}
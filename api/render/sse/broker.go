@@ -0,0 +1,283 @@
+package sse
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zltgo/reflectx"
+)
+
+var (
+	ErrNotFlusher  = errors.New("sse: streaming is not supported by the ResponseWriter")
+	ErrSlowClient  = errors.New("sse: client is too slow to receive events")
+	ErrBrokerClose = errors.New("sse: broker is closed")
+)
+
+// BrokerOptions configures Broker, zero fields are set to default values.
+type BrokerOptions struct {
+	// BufferSize is the number of recent events kept per topic for replaying.
+	BufferSize int `default:"100"`
+
+	// ClientBuffer is the number of events queued per client, a client is
+	// disconnected if its queue is full.
+	ClientBuffer int `default:"64"`
+
+	// Heartbeat is the interval in milliseconds of sending comments to keep
+	// the connections alive through proxies, -1 disables it.
+	Heartbeat int `default:"15000"`
+
+	// Retry is the reconnection time in milliseconds sent to the clients,
+	// zero uses the default of the browsers.
+	Retry uint
+}
+
+// Broker fans out the published events of named topics to many clients.
+// The recent events of each topic are kept in a ring buffer, a client
+// reconnecting with the Last-Event-ID header receives the events it missed.
+// For example:
+//	broker := sse.NewBroker(sse.BrokerOptions{})
+//	broker.CloseOn(serv.Done())
+//	serv.GET("/events", func(ctx *api.Context) {
+//		broker.Serve(ctx.Writer, ctx.Request, "news")
+//	})
+//	broker.Publish("news", sse.Event{Event: "update", Data: news})
+type Broker struct {
+	opts BrokerOptions
+
+	mu     sync.Mutex
+	seq    uint64
+	topics map[string]*topic
+	done   chan struct{}
+	closed bool
+}
+
+type topic struct {
+	ring    []entry // ring buffer of the recent events
+	next    int     // index of the next event in ring
+	clients map[*client]struct{}
+}
+
+type entry struct {
+	seq   uint64
+	event Event
+}
+
+type client struct {
+	ch   chan Event
+	slow bool
+}
+
+func NewBroker(opts BrokerOptions) *Broker {
+	reflectx.SetDefault(&opts)
+	if opts.BufferSize < 0 || opts.ClientBuffer < 0 {
+		panic("sse: BufferSize and ClientBuffer must be positive")
+	}
+	return &Broker{
+		opts:   opts,
+		topics: make(map[string]*topic),
+		done:   make(chan struct{}),
+	}
+}
+
+// Publish sends e to the clients subscribing name. The Id of e is set to a
+// sequence number of the broker, which is used by replaying.
+// A client is disconnected if it is too slow to receive events.
+func (b *Broker) Publish(name string, e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+
+	b.seq++
+	e.Id = strconv.FormatUint(b.seq, 10)
+	t := b.topic(name)
+	if len(t.ring) < b.opts.BufferSize {
+		t.ring = append(t.ring, entry{b.seq, e})
+	} else {
+		t.ring[t.next] = entry{b.seq, e}
+	}
+	t.next = (t.next + 1) % b.opts.BufferSize
+
+	for c := range t.clients {
+		if c.slow {
+			continue
+		}
+		select {
+		case c.ch <- e:
+		default:
+			b.drop(c)
+		}
+	}
+}
+
+// Serve streams the events of topics to the client until it is disconnected
+// or the broker is closed. The events after the Last-Event-ID header are
+// replayed first. Heartbeat comments are sent if no event is sent in the
+// interval. It returns ErrSlowClient if the client is dropped, or the error
+// of writing.
+func (b *Broker) Serve(w http.ResponseWriter, r *http.Request, topics ...string) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return ErrNotFlusher
+	}
+	lastID, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+	c, replay, err := b.subscribe(topics, lastID)
+	if err != nil {
+		return err
+	}
+	defer b.unsubscribe(c, topics)
+
+	h := w.Header()
+	h["Content-Type"] = contentType
+	h["Cache-Control"] = noCache
+	// disable buffering of nginx.
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	sw := checkWriter(w)
+	writeRetry(sw, b.opts.Retry)
+	for _, e := range replay {
+		if err := Encode(sw, e); err != nil {
+			return err
+		}
+	}
+	flusher.Flush()
+
+	var heartbeat <-chan time.Time
+	if b.opts.Heartbeat > 0 {
+		ticker := time.NewTicker(time.Duration(b.opts.Heartbeat) * time.Millisecond)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-b.done:
+			return nil
+		case e, ok := <-c.ch:
+			if !ok {
+				return ErrSlowClient
+			}
+			if err := Encode(sw, e); err != nil {
+				return err
+			}
+		case <-heartbeat:
+			if _, err := sw.WriteString(": heartbeat\n\n"); err != nil {
+				return err
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// ServeHTTP serves the topics of the query parameter "topic", such as
+// /events?topic=news&topic=sports.
+func (b *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.Serve(w, r, r.URL.Query()["topic"]...)
+}
+
+// Clients returns the number of clients subscribing name.
+func (b *Broker) Clients(name string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t, ok := b.topics[name]; ok {
+		return len(t.clients)
+	}
+	return 0
+}
+
+// Close disconnects all the clients, events published after it are discarded.
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		close(b.done)
+	}
+	return nil
+}
+
+// CloseOn closes the broker when done is closed. With api.Server.Done, the
+// streams end as soon as Shutdown is called, otherwise Shutdown waits for them
+// until its context expires, since the OnShutdown functions are called after
+// the in-flight requests are drained.
+func (b *Broker) CloseOn(done <-chan struct{}) {
+	go func() {
+		select {
+		case <-done:
+			b.Close()
+		case <-b.done:
+		}
+	}()
+}
+
+// subscribe adds a client to topics and returns the events after lastID,
+// the events published later are sent to the client.
+func (b *Broker) subscribe(topics []string, lastID uint64) (*client, []Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, nil, ErrBrokerClose
+	}
+
+	c := &client{ch: make(chan Event, b.opts.ClientBuffer)}
+	var missed []entry
+	for _, name := range topics {
+		t := b.topic(name)
+		t.clients[c] = struct{}{}
+		if lastID == 0 {
+			continue
+		}
+		for _, en := range t.ring {
+			if en.seq > lastID {
+				missed = append(missed, en)
+			}
+		}
+	}
+
+	sort.Slice(missed, func(i, j int) bool { return missed[i].seq < missed[j].seq })
+	replay := make([]Event, len(missed))
+	for i := range missed {
+		replay[i] = missed[i].event
+	}
+	return c, replay, nil
+}
+
+// unsubscribe removes c from topics, the topics without clients and events are deleted.
+func (b *Broker) unsubscribe(c *client, topics []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, name := range topics {
+		if t, ok := b.topics[name]; ok {
+			delete(t.clients, c)
+			if len(t.clients) == 0 && len(t.ring) == 0 {
+				delete(b.topics, name)
+			}
+		}
+	}
+}
+
+// drop closes the queue of c, and Serve returns ErrSlowClient.
+func (b *Broker) drop(c *client) {
+	if !c.slow {
+		c.slow = true
+		close(c.ch)
+	}
+}
+
+// topic returns the topic of name, it is created if not found.
+func (b *Broker) topic(name string) *topic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &topic{clients: make(map[*client]struct{})}
+		b.topics[name] = t
+	}
+	return t
+}
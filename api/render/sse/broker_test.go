@@ -0,0 +1,170 @@
+package sse
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readEvent reads the next block of lines until an empty line.
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	var block string
+	for {
+		line, err := r.ReadString('\n')
+		if !assert.NoError(t, err) {
+			return block
+		}
+		if line == "\n" {
+			return block
+		}
+		block += line
+	}
+}
+
+func subscribeBroker(t *testing.T, url, lastID string) (*http.Response, *bufio.Reader) {
+	req, _ := http.NewRequest("GET", url, nil)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return resp, bufio.NewReader(resp.Body)
+}
+
+func waitClients(b *Broker, topic string, n int) {
+	for i := 0; i < 100 && b.Clients(topic) != n; i++ {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBroker(t *testing.T) {
+	b := NewBroker(BrokerOptions{BufferSize: 3, Retry: 2000, Heartbeat: -1})
+	ts := httptest.NewServer(b)
+	defer ts.Close()
+
+	resp, r := subscribeBroker(t, ts.URL+"?topic=news&topic=sports", "")
+	assert.Equal(t, ContentType, resp.Header.Get("Content-Type"))
+	assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+	waitClients(b, "news", 1)
+	assert.Equal(t, 1, b.Clients("sports"))
+
+	b.Publish("news", Event{Event: "update", Data: "a"})
+	b.Publish("weather", Event{Data: "b"})
+	b.Publish("sports", Event{Data: map[string]int{"score": 1}})
+	assert.Equal(t, "retry:2000\nid:1\nevent:update\ndata:a\n", readEvent(t, r))
+	assert.Equal(t, "id:3\ndata:{\"score\":1}\n", readEvent(t, r))
+
+	// clean up on disconnect
+	resp.Body.Close()
+	waitClients(b, "news", 0)
+	assert.Equal(t, 0, b.Clients("news"))
+	assert.Equal(t, 0, b.Clients("sports"))
+
+	// replay the events after Last-Event-ID in order, the ring keeps the
+	// last 3 events of news, so id 4 is lost.
+	for i := 0; i < 4; i++ {
+		b.Publish("news", Event{Data: i})
+	}
+	b.Publish("sports", Event{Data: "s"})
+	resp, r = subscribeBroker(t, ts.URL+"?topic=news&topic=sports", "3")
+	defer resp.Body.Close()
+	assert.Equal(t, "retry:2000\nid:5\ndata:1\n", readEvent(t, r))
+	assert.Equal(t, "id:6\ndata:2\n", readEvent(t, r))
+	assert.Equal(t, "id:7\ndata:3\n", readEvent(t, r))
+	assert.Equal(t, "id:8\ndata:s\n", readEvent(t, r))
+
+	b.Publish("sports", Event{Data: "live"})
+	assert.Equal(t, "id:9\ndata:live\n", readEvent(t, r))
+
+	// closing the broker ends the streams.
+	assert.NoError(t, b.Close())
+	_, err := r.ReadString('\n')
+	assert.Error(t, err)
+	b.Publish("sports", Event{Data: "discarded"})
+
+	rec := httptest.NewRecorder()
+	assert.Equal(t, ErrBrokerClose, b.Serve(rec, httptest.NewRequest("GET", "/", nil), "news"))
+}
+
+func TestBrokerHeartbeat(t *testing.T) {
+	b := NewBroker(BrokerOptions{Heartbeat: 10})
+	defer b.Close()
+	ts := httptest.NewServer(b)
+	defer ts.Close()
+
+	resp, r := subscribeBroker(t, ts.URL+"?topic=news", "")
+	defer resp.Body.Close()
+	assert.Equal(t, ": heartbeat\n", readEvent(t, r))
+	assert.Equal(t, ": heartbeat\n", readEvent(t, r))
+}
+
+func TestBrokerSlowClient(t *testing.T) {
+	b := NewBroker(BrokerOptions{ClientBuffer: 1, Heartbeat: -1})
+	defer b.Close()
+
+	// the handler is blocked and can not receive events.
+	block := make(chan struct{})
+	errs := make(chan error, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		errs <- b.Serve(blockingWriter{w, block}, r, "news", "sports")
+	}))
+	defer ts.Close()
+
+	go func() {
+		if resp, err := http.Get(ts.URL); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	waitClients(b, "news", 1)
+
+	b.Publish("news", Event{Data: 1})
+	b.Publish("news", Event{Data: 2})
+	b.Publish("sports", Event{Data: 3})
+	close(block)
+	select {
+	case err := <-errs:
+		assert.Equal(t, ErrSlowClient, err)
+	case <-time.After(time.Second):
+		t.Error("slow client should be dropped")
+	}
+	assert.Equal(t, 0, b.Clients("sports"))
+
+	rec := httptest.NewRecorder()
+	assert.Equal(t, ErrNotFlusher, b.Serve(struct{ http.ResponseWriter }{rec}, httptest.NewRequest("GET", "/", nil)))
+}
+
+// blockingWriter blocks the first flush until block is closed.
+type blockingWriter struct {
+	http.ResponseWriter
+	block chan struct{}
+}
+
+func (w blockingWriter) Flush() {
+	<-w.block
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func TestBrokerCloseOn(t *testing.T) {
+	assert.Panics(t, func() { NewBroker(BrokerOptions{BufferSize: -1}) })
+
+	b := NewBroker(BrokerOptions{Heartbeat: -1})
+	done := make(chan struct{})
+	b.CloseOn(done)
+	ts := httptest.NewServer(b)
+	defer ts.Close()
+
+	resp, r := subscribeBroker(t, ts.URL+"?topic=news", "")
+	defer resp.Body.Close()
+	waitClients(b, "news", 1)
+	close(done)
+	_, err := r.ReadString('\n')
+	assert.Error(t, err)
+	waitClients(b, "news", 0)
+	assert.Equal(t, 0, b.Clients("news"))
+}
@@ -5,55 +5,91 @@
 package sse
 
 import (
+	"bufio"
 	"bytes"
 	"io"
-	"io/ioutil"
+	"strconv"
 )
 
-type decoder struct {
-	events []Event
+// Decode reads all the events of r, use Decoder to read a live stream.
+// The last event is dispatched at the end of r even if it is not ended by
+// a blank line.
+func Decode(r io.Reader) ([]Event, error) {
+	var events []Event
+	dec := NewDecoder(r)
+	dec.dispatchEOF = true
+	for {
+		event, err := dec.Next()
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
 }
 
-func Decode(r io.Reader) ([]Event, error) {
-	var dec decoder
-	return dec.decode(r)
+// Decoder reads the events of a stream one by one as they arrive.
+type Decoder struct {
+	r *bufio.Reader
+	// the event stream's last event ID, it is kept across events.
+	lastID string
+	// the last event ID buffer, it is set to lastID when an event is dispatched.
+	idBuffer string
+	// the event stream's reconnection time.
+	retry uint
+	// skip a LF at the beginning of the next line, the previous line
+	// ends with CR and the LF may not arrive yet.
+	skipLF bool
+	// dispatch the incomplete event at the end of the stream.
+	dispatchEOF bool
 }
 
-func (d *decoder) dispatchEvent(event Event, data string) {
-	dataLength := len(data)
-	if dataLength > 0 {
-		//If the data buffer's last character is a U+000A LINE FEED (LF) character, then remove the last character from the data buffer.
-		data = data[:dataLength-1]
-		dataLength--
-	}
-	if dataLength == 0 && event.Event == "" {
-		return
-	}
-	if event.Event == "" {
-		event.Event = "message"
-	}
-	event.Data = data
-	d.events = append(d.events, event)
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
 }
 
-func (d *decoder) decode(r io.Reader) ([]Event, error) {
-	buf, err := ioutil.ReadAll(r)
-	if err != nil {
-		return nil, err
-	}
+// LastEventID returns the last event ID of the stream, which should be sent
+// as the Last-Event-ID header on reconnection.
+func (d *Decoder) LastEventID() string {
+	return d.lastID
+}
 
+// Retry returns the reconnection time in milliseconds set by the last retry
+// field, including the ones of events not dispatched, 0 if there is none.
+func (d *Decoder) Retry() uint {
+	return d.retry
+}
+
+// Next blocks until an event is dispatched, and returns io.EOF at the end of
+// the stream. The Data of events is string, Event is "message" if not set,
+// and Retry is set if the event has a valid retry field.
+// Comments and events without data are ignored. An incomplete event at the
+// end of the stream is discarded, the connection may be lost in the middle.
+func (d *Decoder) Next() (Event, error) {
 	var currentEvent Event
-	var dataBuffer *bytes.Buffer = new(bytes.Buffer)
-	// TODO (and unit tests)
-	// Lines must be separated by either a U+000D CARRIAGE RETURN U+000A LINE FEED (CRLF) character pair,
-	// a single U+000A LINE FEED (LF) character,
-	// or a single U+000D CARRIAGE RETURN (CR) character.
-	lines := bytes.Split(buf, []byte{'\n'})
-	for _, line := range lines {
+	var dataBuffer bytes.Buffer
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			if err == io.EOF && d.dispatchEOF {
+				// The whole body is read by Decode, dispatch the event one final time.
+				d.lastID = d.idBuffer
+				if event, ok := d.dispatchEvent(currentEvent, dataBuffer.Bytes()); ok {
+					return event, nil
+				}
+			}
+			// Once the end of the file is reached, any pending data must be discarded.
+			return Event{}, err
+		}
+
 		if len(line) == 0 {
 			// If the line is empty (a blank line). Dispatch the event.
-			d.dispatchEvent(currentEvent, dataBuffer.String())
-
+			d.lastID = d.idBuffer
+			if event, ok := d.dispatchEvent(currentEvent, dataBuffer.Bytes()); ok {
+				return event, nil
+			}
 			// reset current event and data buffer
 			currentEvent = Event{}
 			dataBuffer.Reset()
@@ -65,7 +101,7 @@ func (d *decoder) decode(r io.Reader) ([]Event, error) {
 		}
 
 		var field, value []byte
-		colonIndex := bytes.IndexRune(line, ':')
+		colonIndex := bytes.IndexByte(line, ':')
 		if colonIndex != -1 {
 			// If the line contains a U+003A COLON character character (:)
 			// Collect the characters on the line before the first U+003A COLON character (:),
@@ -92,25 +128,76 @@ func (d *decoder) decode(r io.Reader) ([]Event, error) {
 			// Set the event name buffer to field value.
 			currentEvent.Event = string(value)
 		case "id":
-			// Set the event stream's last event ID to the field value.
-			currentEvent.Id = string(value)
+			// If the field value does not contain U+0000 NULL, then set the last event ID buffer to the field value.
+			if bytes.IndexByte(value, 0) == -1 {
+				d.idBuffer = string(value)
+			}
 		case "retry":
 			// If the field value consists of only characters in the range U+0030 DIGIT ZERO (0) to U+0039 DIGIT NINE (9),
 			// then interpret the field value as an integer in base ten, and set the event stream's reconnection time to that integer.
 			// Otherwise, ignore the field.
-			currentEvent.Id = string(value)
+			if retry, err := strconv.ParseUint(string(value), 10, 32); err == nil {
+				d.retry = uint(retry)
+				currentEvent.Retry = d.retry
+			}
 		case "data":
 			// Append the field value to the data buffer,
 			dataBuffer.Write(value)
 			// then append a single U+000A LINE FEED (LF) character to the data buffer.
-			dataBuffer.WriteString("\n")
+			dataBuffer.WriteByte('\n')
 		default:
 			//Otherwise. The field is ignored.
 			continue
 		}
 	}
-	// Once the end of the file is reached, the user agent must dispatch the event one final time.
-	d.dispatchEvent(currentEvent, dataBuffer.String())
+}
 
-	return d.events, nil
+// dispatchEvent returns the event of data, ok is false if there is nothing to dispatch.
+func (d *Decoder) dispatchEvent(event Event, data []byte) (Event, bool) {
+	dataLength := len(data)
+	if dataLength > 0 {
+		//If the data buffer's last character is a U+000A LINE FEED (LF) character, then remove the last character from the data buffer.
+		data = data[:dataLength-1]
+		dataLength--
+	}
+	if dataLength == 0 && event.Event == "" {
+		return event, false
+	}
+	if event.Event == "" {
+		event.Event = "message"
+	}
+	event.Id = d.lastID
+	event.Data = string(data)
+	return event, true
+}
+
+// readLine reads a line without the end of line.
+// Lines must be separated by either a U+000D CARRIAGE RETURN U+000A LINE FEED (CRLF) character pair,
+// a single U+000A LINE FEED (LF) character,
+// or a single U+000D CARRIAGE RETURN (CR) character.
+func (d *Decoder) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				return line, nil
+			}
+			return nil, err
+		}
+		if d.skipLF {
+			d.skipLF = false
+			if b == '\n' {
+				continue
+			}
+		}
+		switch b {
+		case '\n':
+			return line, nil
+		case '\r':
+			d.skipLF = true
+			return line, nil
+		}
+		line = append(line, b)
+	}
 }
@@ -6,6 +6,7 @@ package sse
 
 import (
 	"bytes"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -114,3 +115,58 @@ data:
 	assert.NoError(t, err)
 	assert.Len(t, events, 1)
 }
+
+func TestDecoderStream(t *testing.T) {
+	pr, pw := io.Pipe()
+	dec := NewDecoder(pr)
+
+	go pw.Write([]byte("retry: 1500\r\nid: 1\r\nevent: update\r\ndata: a\r\ndata: b\r\n\r\n"))
+	event, err := dec.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, Event{Event: "update", Id: "1", Retry: 1500, Data: "a\nb"}, event)
+	assert.Equal(t, uint(1500), dec.Retry())
+
+	// the event is dispatched before the stream ends, the id is kept.
+	go pw.Write([]byte(": heartbeat\r\rretry: x\rdata: c\r\r"))
+	event, err = dec.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, Event{Event: "message", Id: "1", Data: "c"}, event)
+	assert.Equal(t, "1", dec.LastEventID())
+
+	// retry without data is not dispatched but kept.
+	go func() {
+		pw.Write([]byte("retry: 20\n\n"))
+		pw.Write([]byte("\nid:\ndata: d\n\ndata: incomplete"))
+		pw.Close()
+	}()
+	event, err = dec.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, Event{Event: "message", Data: "d"}, event)
+	assert.Equal(t, uint(20), dec.Retry())
+	_, err = dec.Next()
+	assert.Equal(t, io.EOF, err)
+
+	// the incomplete event is dropped if the stream is broken.
+	pr, pw = io.Pipe()
+	dec = NewDecoder(pr)
+	go func() {
+		pw.Write([]byte("data: broken"))
+		pw.CloseWithError(io.ErrUnexpectedEOF)
+	}()
+	_, err = dec.Next()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// the event cut in the middle is discarded, and its id is not the last one.
+	pr, pw = io.Pipe()
+	dec = NewDecoder(pr)
+	go func() {
+		pw.Write([]byte("id: 1\ndata: a\n\nid: 2\ndata: b"))
+		pw.Close()
+	}()
+	event, err = dec.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "1", event.Id)
+	_, err = dec.Next()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, "1", dec.LastEventID())
+}
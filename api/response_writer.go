@@ -36,6 +36,9 @@ type ResponseWriter interface {
 
 	// Set functions should call before write response.
 	Defer(f func())
+
+	// Returns the http.Pusher for server push, nil if it is not supported.
+	Pusher() http.Pusher
 }
 
 var _ ResponseWriter = &responseWriter{}
@@ -72,13 +75,19 @@ func (w *responseWriter) WriteHeader(code int) {
 func (w *responseWriter) WriteHeaderNow() {
 	if !w.Written() {
 		w.size = 0
-		for i := len(w.beforeFuncs) - 1; i >= 0; i-- {
-			w.beforeFuncs[i]()
-		}
+		w.runDefers()
 		w.ResponseWriter.WriteHeader(w.status)
 	}
 }
 
+// runDefers calls the Defer functions once.
+func (w *responseWriter) runDefers() {
+	for i := len(w.beforeFuncs) - 1; i >= 0; i-- {
+		w.beforeFuncs[i]()
+	}
+	w.beforeFuncs = nil
+}
+
 func (w *responseWriter) Write(data []byte) (n int, err error) {
 	w.WriteHeaderNow()
 	n, err = w.ResponseWriter.Write(data)
@@ -122,3 +131,11 @@ func (w *responseWriter) CloseNotify() <-chan bool {
 func (w *responseWriter) Flush() {
 	w.ResponseWriter.(http.Flusher).Flush()
 }
+
+// Pusher returns the http.Pusher of HTTP/2 connections, nil if it is not supported.
+func (w *responseWriter) Pusher() http.Pusher {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p
+	}
+	return nil
+}
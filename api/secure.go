@@ -0,0 +1,82 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/zltgo/reflectx"
+)
+
+// SecureOptions configures the Secure middleware, zero fields are set to
+// default values, use "-" to omit a header.
+type SecureOptions struct {
+	// HSTSMaxAge is the max-age in seconds of Strict-Transport-Security,
+	// which is only sent over https. Default is one year if it is nil,
+	// 0 tells browsers to forget the policy, -1 omits the header.
+	HSTSMaxAge            *int
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+
+	// ContentSecurityPolicy is omitted if it is empty, for example:
+	// "default-src 'self'; img-src 'self' data:".
+	ContentSecurityPolicy string
+
+	// FrameOptions is the value of X-Frame-Options, "DENY" or "SAMEORIGIN".
+	FrameOptions string `default:"DENY"`
+
+	// ReferrerPolicy is the value of Referrer-Policy.
+	ReferrerPolicy string `default:"strict-origin-when-cross-origin"`
+
+	// ContentTypeOptions is the value of X-Content-Type-Options.
+	ContentTypeOptions string `default:"nosniff"`
+}
+
+// Secure returns a middleware setting the security headers of responses.
+// For example:
+//	serv.Use(api.Secure(api.SecureOptions{
+//		ContentSecurityPolicy: "default-src 'self'",
+//		FrameOptions:          "SAMEORIGIN",
+//	}))
+func Secure(opts SecureOptions) Handler {
+	reflectx.SetDefault(&opts)
+
+	maxAge := 31536000
+	if opts.HSTSMaxAge != nil {
+		maxAge = *opts.HSTSMaxAge
+	}
+	var hsts string
+	if maxAge >= 0 {
+		hsts = "max-age=" + strconv.Itoa(maxAge)
+		if opts.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if opts.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
+	headers := [][2]string{
+		{"Content-Security-Policy", opts.ContentSecurityPolicy},
+		{"X-Frame-Options", opts.FrameOptions},
+		{"Referrer-Policy", opts.ReferrerPolicy},
+		{"X-Content-Type-Options", opts.ContentTypeOptions},
+	}
+
+	return func(c *Context) {
+		h := c.Writer.Header()
+		for _, kv := range headers {
+			if kv[1] != "" && kv[1] != "-" {
+				h.Set(kv[0], kv[1])
+			}
+		}
+		if hsts != "" && isHTTPS(c) {
+			h.Set("Strict-Transport-Security", hsts)
+		}
+	}
+}
+
+// isHTTPS reports whether the request is sent over TLS, or forwarded from
+// https by a proxy.
+func isHTTPS(c *Context) bool {
+	return c.Request.TLS != nil || strings.EqualFold(c.Request.Header.Get("X-Forwarded-Proto"), "https")
+}
@@ -1,7 +1,7 @@
 package api
 
 import (
-	"net"
+	"context"
 	"net/http"
 	"os"
 	"path"
@@ -59,20 +59,51 @@ func H(fn interface{}) Handler {
 		panic("can not warp " + t.String() + "to api.Handler")
 	}
 
-	return func(ctx *Context) {
+	return funcHandler(fn, func(ctx *Context) {
 		vs, err := ctx.Invoke(fn)
 		if err != nil {
 			// validate failed.
-			ctx.Reply(http.StatusBadRequest, err)
+			ctx.Error(err)
 			return
 		}
 		// do nothing if fn does not have return values
 		if len(vs) > 0 {
 			ctx.ReplyValues(vs)
 		}
+	})
+}
+
+// funcHandler returns a Handler calling h, which keeps fn for FuncOf.
+// It must not be inlined, the closures of all the callers share the code
+// address funcHandlerPC.
+//
+//go:noinline
+func funcHandler(fn interface{}, h Handler) Handler {
+	return func(ctx *Context) {
+		if ctx.probe != nil {
+			*ctx.probe = fn
+			return
+		}
+		h(ctx)
 	}
 }
 
+// funcHandlerPC is the code address of the Handlers returned by funcHandler.
+var funcHandlerPC = reflect.ValueOf(funcHandler(nil, nil)).Pointer()
+
+// FuncOf returns the function wrapped to h by H or Handle, it is useful for
+// reflecting on the parameters and return values of the handlers, see
+// Route.Funcs and 'zltgo/api/openapi'. It returns nil if h is not created by
+// H from a function with injected arguments or by Handle.
+func FuncOf(h Handler) interface{} {
+	if h == nil || reflect.ValueOf(h).Pointer() != funcHandlerPC {
+		return nil
+	}
+	var fn interface{}
+	h(&Context{probe: &fn})
+	return fn
+}
+
 // Server is the framework's instance, it contains the muxer, middleware and configuration settings.
 // Create an instance of Server, by using New() or Default()
 type Server struct {
@@ -103,6 +134,31 @@ type Server struct {
 	// Value of 'maxMemory' param that is given to http.Request's ParseMultipartForm
 	// method call.
 	MaxMultipartMemory int64
+
+	// Offers are the formats Context.Reply negotiates with the Accept header,
+	// in the server-side order of preference. Default is DefaultOffers.
+	// Use Negotiate to limit the formats of a route or a group.
+	Offers []string
+
+	// TLS configures the servers started by RunTLS.
+	TLS TLSOptions
+
+	// UnixSocket configures the socket file created by RunUnix.
+	UnixSocket UnixSocketOptions
+
+	// named routes for reverse routing, see Route.Named.
+	names map[string]*Route
+
+	// lifecycle of the listening servers, see Shutdown.
+	mu         sync.Mutex
+	servers    []*http.Server
+	startOnce  sync.Once
+	startErr   error
+	closeOnce  sync.Once
+	done       chan struct{}
+	onStart    []func() error
+	onShutdown []func() error
+	wsConns    map[*WSConn]struct{}
 }
 
 // New returns a new blank Server instance without any middleware attached.
@@ -123,6 +179,8 @@ func New(middleware ...Handler) *Server {
 		RedirectTrailingSlash: true,
 		RedirectFixedPath:     false,
 		MaxMultipartMemory:    defaultMultipartMemory,
+		Offers:                DefaultOffers,
+		done:                  make(chan struct{}),
 	}
 
 	serv.pool.New = func() interface{} {
@@ -132,7 +190,7 @@ func New(middleware ...Handler) *Server {
 }
 
 func (serv *Server) allocateContext() *Context {
-	return &Context{middleware: serv.middleware}
+	return &Context{middleware: serv.middleware, serv: serv}
 }
 
 // Default returns an Engine instance with the Logger and Recovery middleware already attached.
@@ -157,113 +215,234 @@ func (serv *Server) Use(middleware ...Handler) {
 // This function is intended for bulk loading and to allow the usage of less
 // frequently used, non-standardized or custom methods (e.g. for internal
 // communication with a proxy).
-func (serv *Server) Handle(method, url string, handlers ...Handler) {
+//
+// The returned route can be named for reverse routing, see URL.
+func (serv *Server) Handle(method, url string, handlers ...Handler) *Route {
 	Assert(url[0] == '/', "url must begin with '/'")
 	Assert(len(method) > 0, "http method can not be empty")
 	Assert(len(handlers) > 0, "there must be at least one handler")
 	Assert(len(serv.middleware)+len(handlers) < int(abortIndex), "too many handlers")
 
+	route := &Route{Method: method, Url: url, Handlers: handlers, Funcs: make([]interface{}, len(handlers)), serv: serv}
+	for i, h := range handlers {
+		route.Funcs[i] = FuncOf(h)
+	}
 	switch method {
 	case "GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS", "CONNECT", "TRACE":
-		serv.router.Add(method, url, handlers)
+		serv.router.Add(method, url, route)
 	case "ANY":
-		serv.router.Add("GET", url, handlers)
-		serv.router.Add("POST", url, handlers)
-		serv.router.Add("PUT", url, handlers)
-		serv.router.Add("DELETE", url, handlers)
-		serv.router.Add("PATCH", url, handlers)
-		serv.router.Add("HEAD", url, handlers)
-		serv.router.Add("OPTIONS", url, handlers)
-		serv.router.Add("CONNECT", url, handlers)
-		serv.router.Add("TRACE", url, handlers)
+		serv.router.Add("GET", url, route)
+		serv.router.Add("POST", url, route)
+		serv.router.Add("PUT", url, route)
+		serv.router.Add("DELETE", url, route)
+		serv.router.Add("PATCH", url, route)
+		serv.router.Add("HEAD", url, route)
+		serv.router.Add("OPTIONS", url, route)
+		serv.router.Add("CONNECT", url, route)
+		serv.router.Add("TRACE", url, route)
 	default:
 		panic("unknown http method: " + method)
 	}
 
 	debugPrintRoute(method, url, handlers)
-	return
+	return route
 }
 
 // POST is a shortcut for router.Handle("POST", url, handle).
-func (serv *Server) POST(url string, handlers ...Handler) {
-	serv.Handle("POST", url, handlers...)
+func (serv *Server) POST(url string, handlers ...Handler) *Route {
+	return serv.Handle("POST", url, handlers...)
 }
 
 // GET is a shortcut for router.Handle("GET", url, handle).
-func (serv *Server) GET(url string, handlers ...Handler) {
-	serv.Handle("GET", url, handlers...)
+func (serv *Server) GET(url string, handlers ...Handler) *Route {
+	return serv.Handle("GET", url, handlers...)
 }
 
 // DELETE is a shortcut for router.Handle("DELETE", url, handle).
-func (serv *Server) DELETE(url string, handlers ...Handler) {
-	serv.Handle("DELETE", url, handlers...)
+func (serv *Server) DELETE(url string, handlers ...Handler) *Route {
+	return serv.Handle("DELETE", url, handlers...)
 }
 
 // PATCH is a shortcut for router.Handle("PATCH", url, handle).
-func (serv *Server) PATCH(url string, handlers ...Handler) {
-	serv.Handle("PATCH", url, handlers...)
+func (serv *Server) PATCH(url string, handlers ...Handler) *Route {
+	return serv.Handle("PATCH", url, handlers...)
 }
 
 // PUT is a shortcut for router.Handle("PUT", url, handle).
-func (serv *Server) PUT(url string, handlers ...Handler) {
-	serv.Handle("PUT", url, handlers...)
+func (serv *Server) PUT(url string, handlers ...Handler) *Route {
+	return serv.Handle("PUT", url, handlers...)
 }
 
 // OPTIONS is a shortcut for router.Handle("OPTIONS", url, handle).
-func (serv *Server) OPTIONS(url string, handlers ...Handler) {
-	serv.Handle("OPTIONS", url, handlers...)
+func (serv *Server) OPTIONS(url string, handlers ...Handler) *Route {
+	return serv.Handle("OPTIONS", url, handlers...)
 }
 
 // HEAD is a shortcut for router.Handle("HEAD", url, handle).
-func (serv *Server) HEAD(url string, handlers ...Handler) {
-	serv.Handle("HEAD", url, handlers...)
+func (serv *Server) HEAD(url string, handlers ...Handler) *Route {
+	return serv.Handle("HEAD", url, handlers...)
 }
 
 // Any registers a route that matches all the HTTP methods.
 // GET, POST, PUT, PATCH, HEAD, OPTIONS, DELETE, CONNECT, TRACE.
-func (serv *Server) ANY(url string, handlers ...Handler) {
-	serv.Handle("ANY", url, handlers...)
+func (serv *Server) ANY(url string, handlers ...Handler) *Route {
+	return serv.Handle("ANY", url, handlers...)
 }
 
 // Run attaches the router to a http.Server and starts listening and serving HTTP requests.
 // It is a shortcut for http.ListenAndServe(addr, router)
-// Note: this method will block the calling goroutine indefinitely unless an error happens.
+// Note: this method will block the calling goroutine until Shutdown is called or an error happens.
 func (serv *Server) Run(addr string) (err error) {
 	defer func() { debugPrintError(err) }()
 
+	hs, err := serv.newHTTPServer(addr)
+	if err != nil {
+		return
+	}
 	debugPrint("Listening and serving HTTP on %s\n", addr)
-	err = http.ListenAndServe(addr, serv)
+	err = ignoreServerClosed(hs.ListenAndServe())
 	return
 }
 
 // RunTLS attaches the router to a http.Server and starts listening and serving HTTPS (secure) requests.
 // It is a shortcut for http.ListenAndServeTLS(addr, certFile, keyFile, router)
-// Note: this method will block the calling goroutine indefinitely unless an error happens.
+// Note: this method will block the calling goroutine until Shutdown is called or an error happens.
 func (serv *Server) RunTLS(addr, certFile, keyFile string) (err error) {
-	debugPrint("Listening and serving HTTPS on %s\n", addr)
 	defer func() { debugPrintError(err) }()
 
-	err = http.ListenAndServeTLS(addr, certFile, keyFile, serv)
+	hs, err := serv.newHTTPServer(addr)
+	if err != nil {
+		return
+	}
+	if hs.TLSConfig, err = serv.TLS.Config(); err != nil {
+		return
+	}
+	debugPrint("Listening and serving HTTPS on %s\n", addr)
+	err = ignoreServerClosed(hs.ListenAndServeTLS(certFile, keyFile))
 	return
 }
 
 // RunUnix attaches the router to a http.Server and starts listening and serving HTTP requests
-// through the specified unix socket (ie. a file).
-// Note: this method will block the calling goroutine indefinitely unless an error happens.
+// through the specified unix socket (ie. a file), the mode and the ownership of the file
+// are set by serv.UnixSocket.
+// Note: this method will block the calling goroutine until Shutdown is called or an error happens.
 func (serv *Server) RunUnix(file string) (err error) {
-	debugPrint("Listening and serving HTTP on unix:/%s", file)
 	defer func() { debugPrintError(err) }()
 
+	hs, err := serv.newHTTPServer("")
+	if err != nil {
+		return
+	}
+	debugPrint("Listening and serving HTTP on unix:/%s", file)
+
 	os.Remove(file)
-	listener, err := net.Listen("unix", file)
+	listener, err := listenUnix(file, serv.UnixSocket.Mode)
 	if err != nil {
 		return
 	}
 	defer listener.Close()
-	err = http.Serve(listener, serv)
+	if err = serv.UnixSocket.chown(file); err != nil {
+		return
+	}
+	err = ignoreServerClosed(hs.Serve(listener))
 	return
 }
 
+// OnStart registers functions called once before the first listener starts,
+// the server will not start if any of them returns an error.
+func (serv *Server) OnStart(fns ...func() error) {
+	serv.mu.Lock()
+	serv.onStart = append(serv.onStart, fns...)
+	serv.mu.Unlock()
+}
+
+// OnShutdown registers functions called by Shutdown after in-flight requests
+// are drained, usually used for flushing caches and closing session stores.
+// They are called in reverse order, like defer.
+func (serv *Server) OnShutdown(fns ...func() error) {
+	serv.mu.Lock()
+	serv.onShutdown = append(serv.onShutdown, fns...)
+	serv.mu.Unlock()
+}
+
+// Done returns a channel that is closed when Shutdown is called.
+// Long-lived handlers such as event streams should return when it is closed,
+// Context.Stream does this automatically.
+func (serv *Server) Done() <-chan struct{} {
+	return serv.done
+}
+
+// Shutdown gracefully shuts down all the listeners started by Run, RunTLS and RunUnix.
+// It stops accepting new connections, notifies long-lived streams by closing Done,
+// closes the WebSocket connections created by Context.Upgrade with 1001 (going away),
+// waits for in-flight requests to finish and then calls the OnShutdown functions.
+// If ctx expires before the requests are drained, the error of ctx is returned,
+// the OnShutdown functions are called anyway.
+// Once Shutdown has been called, the server can not be started again.
+func (serv *Server) Shutdown(ctx context.Context) error {
+	serv.closeOnce.Do(func() { close(serv.done) })
+
+	serv.mu.Lock()
+	servers := serv.servers
+	serv.servers = nil
+	hooks := serv.onShutdown
+	serv.mu.Unlock()
+	serv.closeWS()
+
+	var err error
+	for _, hs := range servers {
+		if e := hs.Shutdown(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if e := hooks[i](); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// newHTTPServer calls the OnStart functions if necessary, and returns a http.Server
+// tracked for Shutdown.
+func (serv *Server) newHTTPServer(addr string) (*http.Server, error) {
+	serv.startOnce.Do(func() {
+		serv.mu.Lock()
+		hooks := serv.onStart
+		serv.mu.Unlock()
+
+		for _, fn := range hooks {
+			if serv.startErr = fn(); serv.startErr != nil {
+				return
+			}
+		}
+	})
+	if serv.startErr != nil {
+		return nil, serv.startErr
+	}
+
+	serv.mu.Lock()
+	defer serv.mu.Unlock()
+	select {
+	case <-serv.done:
+		return nil, http.ErrServerClosed
+	default:
+	}
+
+	hs := &http.Server{Addr: addr, Handler: serv}
+	serv.servers = append(serv.servers, hs)
+	return hs, nil
+}
+
+// Run returns nil instead of http.ErrServerClosed after Shutdown.
+func ignoreServerClosed(err error) error {
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
 // ServeHTTP conforms to the http.Handler interface.
 func (serv *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	c := serv.pool.Get().(*Context)
@@ -293,6 +472,7 @@ func (serv *Server) handleHTTPRequest(ctx *Context) {
 	path := ctx.Request.URL.Path
 
 	ctx.middleware = serv.middleware
+	ctx.offers = serv.Offers
 	// Find root of the tree for the given HTTP method
 	root := serv.router.GetTree(httpMethod)
 	if root != nil {
@@ -300,7 +480,8 @@ func (serv *Server) handleHTTPRequest(ctx *Context) {
 		handle, params, tsr := root.GetHandle(path, ctx.Params)
 		if handle != nil {
 			ctx.Params = params
-			ctx.run(handle.([]Handler))
+			ctx.route = handle.(*Route)
+			ctx.run(ctx.route.Handlers)
 			return
 		}
 
@@ -356,7 +537,19 @@ func (serv *Server) Static(urlPrefix, root string) {
 	if strings.Contains(urlPrefix, ":") || strings.Contains(urlPrefix, "*") {
 		panic("URL parameters can not be used when serving a static folder")
 	}
-	handler := func(c *Context) {
+	handler := staticHandler(urlPrefix, root)
+
+	url := path.Join(urlPrefix, "/*filepath")
+	// Register GET and HEAD handlers
+	serv.GET(url, handler)
+	serv.HEAD(url, handler)
+	return
+}
+
+// staticHandler serves the files under root, urlPrefix is trimmed from
+// the request path before looking up the file.
+func staticHandler(urlPrefix, root string) Handler {
+	return func(c *Context) {
 		// Open file
 		fpath := strings.TrimPrefix(c.Request.URL.Path, urlPrefix)
 		f, fi, err := openFile(filepath.Join(root, fpath))
@@ -375,12 +568,6 @@ func (serv *Server) Static(urlPrefix, root string) {
 		http.ServeContent(c.Writer, c.Request, fi.Name(), fi.ModTime(), f)
 		return
 	}
-
-	url := path.Join(urlPrefix, "/*filepath")
-	// Register GET and HEAD handlers
-	serv.GET(url, handler)
-	serv.HEAD(url, handler)
-	return
 }
 
 func redirectTrailingSlash(c *Context) {
@@ -428,6 +615,37 @@ type Route struct {
 	Method   string
 	Url      string
 	Handlers []Handler
+
+	// Funcs are the functions wrapped to Handlers by H or Handle, in the same
+	// order, nil for the other handlers. See FuncOf.
+	Funcs []interface{}
+
+	// Name is the optional name of the route for reverse routing, it is set
+	// by Named. See Server.URL.
+	Name string
+
+	serv *Server
+}
+
+// Named sets the name of the route for reverse routing, see Server.URL.
+// It panics if the name is used by a route of another url in the server,
+// routes of the same url in different methods can share a name.
+// Name routes before serving, Server.URL reads the names without locks.
+func (r *Route) Named(name string) *Route {
+	Assert(len(name) > 0, "route name can not be empty")
+	if r.serv == nil {
+		r.Name = name
+		return r
+	}
+	if other, ok := r.serv.names[name]; ok && other.Url != r.Url {
+		panic("api: route name '" + name + "' is used by " + other.Url)
+	}
+	r.Name = name
+	if r.serv.names == nil {
+		r.serv.names = make(map[string]*Route)
+	}
+	r.serv.names[name] = r
+	return r
 }
 
 type Routes []Route
@@ -438,10 +656,13 @@ func (serv *Server) GetRoutes() (routes Routes) {
 	for _, tree := range serv.router {
 		tree.Root.Walk(func(path string, handle interface{}) bool {
 			if handle != nil {
+				r := handle.(*Route)
 				routes = append(routes, Route{
 					Method:   tree.Name,
 					Url:      path,
-					Handlers: handle.([]Handler),
+					Handlers: r.Handlers,
+					Funcs:    r.Funcs,
+					Name:     r.Name,
 				})
 			}
 			return true
@@ -452,7 +673,10 @@ func (serv *Server) GetRoutes() (routes Routes) {
 
 func (serv *Server) AddRoutes(routes Routes) {
 	for i := range routes {
-		serv.Handle(routes[i].Method, routes[i].Url, routes[i].Handlers...)
+		r := serv.Handle(routes[i].Method, routes[i].Url, routes[i].Handlers...)
+		if routes[i].Name != "" {
+			r.Named(routes[i].Name)
+		}
 	}
 	return
 }
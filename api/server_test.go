@@ -2,10 +2,14 @@ package api
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -20,6 +24,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/zltgo/api/bind"
 	"github.com/zltgo/api/render"
+	"github.com/zltgo/api/render/sse"
 )
 
 func testRequest(t *testing.T, url string) {
@@ -116,6 +121,85 @@ func TestWithHttptestWithSpecifiedPort(t *testing.T) {
 	testRequest(t, "http://localhost:8033/example")
 }
 
+func TestShutdown(t *testing.T) {
+	router := New()
+	var events []string
+	router.OnStart(func() error {
+		events = append(events, "start")
+		return nil
+	})
+	router.OnShutdown(func() error {
+		events = append(events, "shutdown1")
+		return nil
+	}, func() error {
+		events = append(events, "shutdown2")
+		return nil
+	})
+
+	started := make(chan struct{})
+	router.GET("/slow", func(c *Context) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		c.Reply(http.StatusOK, "it worked")
+	})
+	router.GET("/stream", func(c *Context) {
+		c.Stream(func(w io.Writer) bool {
+			time.Sleep(time.Millisecond)
+			return true
+		})
+	})
+	broker := sse.NewBroker(sse.BrokerOptions{})
+	broker.CloseOn(router.Done())
+	router.GET("/events", func(c *Context) {
+		broker.Serve(c.Writer, c.Request, "news")
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- router.Run(":5151")
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// a long-lived stream must not block Shutdown
+	go http.Get("http://localhost:5151/stream")
+	go http.Get("http://localhost:5151/events")
+	for i := 0; i < 100 && broker.Clients("news") == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 1, broker.Clients("news"))
+
+	slow := make(chan string)
+	go func() {
+		resp, err := http.Get("http://localhost:5151/slow")
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		slow <- string(body)
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, router.Shutdown(ctx))
+	assert.NoError(t, <-done)
+
+	// in-flight request is drained
+	assert.Equal(t, "it worked", <-slow)
+	assert.Equal(t, []string{"start", "shutdown2", "shutdown1"}, events)
+
+	// server can not be started again
+	assert.Equal(t, http.ErrServerClosed, router.Run(":5151"))
+}
+
+func TestOnStartError(t *testing.T) {
+	router := New()
+	router.OnStart(func() error {
+		return errors.New("failed to start")
+	})
+	assert.EqualError(t, router.Run(":5152"), "failed to start")
+	assert.EqualError(t, router.RunUnix("/tmp/unix_unit_test_start"), "failed to start")
+}
+
 type FB struct {
 	Foo string `validate:"max=3"`
 	Bar int    `validate:"max=3"`
@@ -491,3 +575,76 @@ func BenchmarkMartini(b *testing.B) {
 
 	runRequest(b, m, "GET", "/test?Foo=f&Bar=1")
 }
+
+func TestValidationErrorReply(t *testing.T) {
+	s := New()
+	s.GET("/test/:Foo", H(func(fb FB) int {
+		return fb.Bar
+	}))
+	s.GET("/validate", H(func() (int, error) {
+		return http.StatusOK, bind.Validate(&FB{Foo: "abcd"})
+	}))
+
+	get := func(path, lang string) (*httptest.ResponseRecorder, ErrorBody) {
+		r, _ := http.NewRequest("GET", path, nil)
+		r.Header.Set("Accept-Language", lang)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+
+		var body ErrorBody
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w, body
+	}
+
+	w, body := get("/test/f?Bar=4", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, ErrorBody{
+		Code:    http.StatusBadRequest,
+		Message: "Bad Request",
+		Errors:  bind.ValidationErrors{{Field: "Bar", Rule: "max", Param: "3", Message: "Bar must be at most 3"}},
+	}, body)
+
+	_, body = get("/test/f?Bar=4", "zh-CN,zh;q=0.9")
+	assert.Equal(t, "Bar最大为3", body.Errors[0].Message)
+
+	// validation errors returned by handlers
+	w, body = get("/validate", "en")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "Foo", body.Errors[0].Field)
+}
+
+func TestMaxMultipartMemory(t *testing.T) {
+	type upload struct {
+		File *multipart.FileHeader `form:"file"`
+	}
+	newRequest := func() *http.Request {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		w, _ := mw.CreateFormFile("file", "a.txt")
+		w.Write(bytes.Repeat([]byte("a"), 1024))
+		mw.Close()
+		req, _ := http.NewRequest("POST", "/upload", &buf)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		return req
+	}
+
+	var onDisk bool
+	serv := New()
+	serv.POST("/upload", H(func(up upload) {
+		f, err := up.File.Open()
+		if assert.NoError(t, err) {
+			_, onDisk = f.(*os.File)
+			f.Close()
+		}
+	}))
+
+	// the file is kept in memory by default.
+	serv.ServeHTTP(httptest.NewRecorder(), newRequest())
+	assert.False(t, onDisk)
+
+	// the file is stored on disk if it exceeds MaxMultipartMemory.
+	serv.MaxMultipartMemory = 512
+	serv.ServeHTTP(httptest.NewRecorder(), newRequest())
+	assert.True(t, onDisk)
+}
@@ -1,7 +1,9 @@
 package session
 
 import (
+	"encoding/gob"
 	"net/http"
+	"time"
 
 	"github.com/zltgo/api/cache"
 	"github.com/zltgo/api/jwt"
@@ -35,6 +37,10 @@ type CookieOpts struct {
 
 	// Used for finding id in values.
 	IdKey string `default:"_cid"`
+
+	// Gob encodes the values by gob instead of json, the concrete types of
+	// values are kept, which must be registered by RegisterType.
+	Gob bool
 }
 
 var _ Store = &CookieStore{}
@@ -52,6 +58,10 @@ type CookieStore struct {
 // to select sha1, sha256, sha384, or sha512.
 // If hashKey is nil, it will be created by RandBytes(16).
 func NewCookieStore(ck Cookie, idKey, hashKey, blockKey string) *CookieStore {
+	return newCookieStore(ck, idKey, hashKey, blockKey, false)
+}
+
+func newCookieStore(ck Cookie, idKey, hashKey, blockKey string, useGob bool) *CookieStore {
 	reflectx.SetDefault(&ck)
 	if idKey == "" {
 		idKey = "_cid"
@@ -62,10 +72,14 @@ func NewCookieStore(ck Cookie, idKey, hashKey, blockKey string) *CookieStore {
 	if len(blockKey) == 0 {
 		blockKey = jwt.RandString(16)
 	}
+	newParser := jwt.NewParser
+	if useGob {
+		newParser = jwt.NewGobParser
+	}
 	return &CookieStore{
 		Cookie:   ck,
 		idKey:    idKey,
-		tkParser: jwt.NewParser(ck.MaxAge, []byte(hashKey), []byte(blockKey)),
+		tkParser: newParser(ck.MaxAge, []byte(hashKey), []byte(blockKey)),
 	}
 }
 
@@ -77,12 +91,26 @@ func NewCookieProvider(opts CookieOpts, lmc *cache.LruMemCache) *Provider {
 
 	return &Provider{
 		Cookie:  opts.Cookie,
-		store:   NewCookieStore(ck, opts.IdKey, opts.HashKey, opts.BlockKey),
+		store:   newCookieStore(ck, opts.IdKey, opts.HashKey, opts.BlockKey, opts.Gob),
 		lmc:     lmc,
 		RateOpt: ratelimit.SecOpts(opts.RateSec...),
 	}
 }
 
+// RegisterType registers the concrete types of values for gob, such as
+// structs stored in the sessions of CookieOpts.Gob, for example:
+//	session.RegisterType(User{}, []Item{})
+// Register them before sessions are saved, gob can not encode unregistered types.
+func RegisterType(values ...interface{}) {
+	for _, v := range values {
+		gob.Register(v)
+	}
+}
+
+func init() {
+	RegisterType(map[string]interface{}{}, []interface{}{}, time.Time{})
+}
+
 // Get retruns data stored by id.
 func (cs *CookieStore) Get(r *http.Request, id string) (map[string]interface{}, error) {
 	// get token in cookie
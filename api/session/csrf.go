@@ -0,0 +1,141 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"net/http"
+	"reflect"
+
+	"github.com/zltgo/api"
+	"github.com/zltgo/api/render"
+	"github.com/zltgo/reflectx"
+	"github.com/zltgo/reflectx/values"
+)
+
+var ErrCSRF = errors.New("session: csrf token is missing or invalid")
+
+// CSRFOpts configures the CSRF protection, zero fields are set to default values.
+type CSRFOpts struct {
+	// Cookie stores the token readable by java script, HttpOnly must be false.
+	Cookie struct {
+		Name   string `default:"_csrf"`
+		Path   string `default:"/"`
+		Domain string
+		Secure bool
+	}
+
+	// Header and Form are where the token is submitted by the client,
+	// the header is checked first.
+	Header string `default:"X-CSRF-Token"`
+	Form   string `default:"_csrf"`
+
+	// Key is the key of the token in Session.
+	Key string `default:"_csrf"`
+}
+
+// CSRF protects cookie sessions from cross-site request forgery by double-submit
+// tokens: a random token is stored in Session and sent to the client in a cookie,
+// the requests other than GET, HEAD, OPTIONS and TRACE must submit the token
+// by the header or the form field, and the cookie must be the same one.
+// Other sites can neither read the cookie nor forge the session of the token.
+type CSRF struct {
+	CSRFOpts
+}
+
+func NewCSRF(opts CSRFOpts) *CSRF {
+	reflectx.SetDefault(&opts)
+	return &CSRF{opts}
+}
+
+var typeSession = reflect.TypeOf((*Session)(nil))
+
+// CSRFHandler checks the token of unsafe requests and replies 403 on failure,
+// it must be called after Provider.SessionHandler, for example:
+//	csrf := session.NewCSRF(session.CSRFOpts{})
+//	serv := api.New(provider.SessionHandler, csrf.CSRFHandler)
+func (m *CSRF) CSRFHandler(ctx *api.Context) {
+	se, ok := ctx.Value(typeSession).(*Session)
+	if !ok {
+		panic("session: CSRFHandler must be called after SessionHandler")
+	}
+	token := m.Token(se)
+
+	switch ctx.Request.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE":
+	default:
+		submitted := ctx.Request.Header.Get(m.Header)
+		if submitted == "" {
+			submitted = ctx.Request.PostFormValue(m.Form)
+		}
+		ck, err := ctx.Request.Cookie(m.Cookie.Name)
+		if err != nil || !tokenEqual(ck.Value, token) || !tokenEqual(submitted, token) {
+			ctx.Error(ErrCSRF)
+			ctx.Reply(http.StatusForbidden, render.JSON{Data: api.ErrorBody{
+				Code:    http.StatusForbidden,
+				Message: ErrCSRF.Error(),
+			}})
+			return
+		}
+	}
+
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     m.Cookie.Name,
+		Value:    token,
+		Path:     m.Cookie.Path,
+		Domain:   m.Cookie.Domain,
+		Secure:   m.Cookie.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Token returns the token of se, a new one is created if not found.
+// It can be rendered in forms, such as:
+//	<input type="hidden" name="_csrf" value="{{.CSRFToken}}">
+func (m *CSRF) Token(se *Session) string {
+	return values.Getsert(se, m.Key, func() interface{} {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			panic(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}).(string)
+}
+
+// FuncMap returns the template functions of the token, the argument is the
+// *Session or the *api.Context of the request:
+//	{{csrfField .Ctx}} renders <input type="hidden" name="_csrf" value="...">
+//	{{csrfToken .Ctx}} renders the token only, such as in a meta tag.
+func (m *CSRF) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"csrfToken": m.tokenOf,
+		"csrfField": func(v interface{}) (template.HTML, error) {
+			token, err := m.tokenOf(v)
+			if err != nil {
+				return "", err
+			}
+			return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(m.Form) +
+				`" value="` + template.HTMLEscapeString(token) + `">`), nil
+		},
+	}
+}
+
+// tokenOf returns the token of the *Session or *api.Context.
+func (m *CSRF) tokenOf(v interface{}) (string, error) {
+	switch x := v.(type) {
+	case *Session:
+		return m.Token(x), nil
+	case *api.Context:
+		if se, ok := x.Value(typeSession).(*Session); ok {
+			return m.Token(se), nil
+		}
+		return "", errors.New("session: no session in the context")
+	}
+	return "", errors.New("session: csrf token needs *Session or *api.Context")
+}
+
+func tokenEqual(a, b string) bool {
+	return a != "" && subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,138 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/zltgo/api"
+	"github.com/zltgo/api/cache"
+	"github.com/zltgo/reflectx/values"
+)
+
+func TestCSRF(t *testing.T) {
+	p := NewProvider(nil, cache.NewLruMemCache(100))
+	csrf := NewCSRF(CSRFOpts{})
+	serv := api.New(p.SessionHandler, csrf.CSRFHandler)
+	serv.GET("/form", func(ctx *api.Context) {
+		var se *Session
+		ctx.MustGet(&se)
+		ctx.Reply(http.StatusOK, csrf.Token(se))
+	})
+	serv.POST("/form", func(ctx *api.Context) {
+		ctx.Reply(http.StatusOK, "ok")
+	})
+
+	do := func(r *http.Request, cks ...*http.Cookie) *httptest.ResponseRecorder {
+		for _, ck := range cks {
+			r.AddCookie(ck)
+		}
+		w := httptest.NewRecorder()
+		serv.ServeHTTP(w, r)
+		return w
+	}
+
+	r, _ := http.NewRequest("GET", "/form", nil)
+	w := do(r)
+	token := w.Body.String()
+	var sessionCk, csrfCk *http.Cookie
+	for _, ck := range w.Result().Cookies() {
+		switch ck.Name {
+		case p.Cookie.Name:
+			sessionCk = ck
+		case csrf.Cookie.Name:
+			csrfCk = ck
+		}
+	}
+
+	Convey("the token is sent in cookie", t, func() {
+		So(token, ShouldNotBeEmpty)
+		So(sessionCk, ShouldNotBeNil)
+		So(csrfCk, ShouldNotBeNil)
+		So(csrfCk.Value, ShouldEqual, token)
+		So(csrfCk.HttpOnly, ShouldBeFalse)
+
+		r, _ := http.NewRequest("GET", "/form", nil)
+		So(do(r, sessionCk).Body.String(), ShouldEqual, token)
+	})
+
+	Convey("submit the token by header", t, func() {
+		r, _ := http.NewRequest("POST", "/form", nil)
+		r.Header.Set("X-CSRF-Token", token)
+		w := do(r, sessionCk, csrfCk)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "ok")
+	})
+
+	Convey("submit the token by form", t, func() {
+		r, _ := http.NewRequest("POST", "/form", strings.NewReader(url.Values{"_csrf": {token}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		So(do(r, sessionCk, csrfCk).Code, ShouldEqual, http.StatusOK)
+	})
+
+	Convey("reject requests without token", t, func() {
+		r, _ := http.NewRequest("POST", "/form", nil)
+		w := do(r, sessionCk, csrfCk)
+		So(w.Code, ShouldEqual, http.StatusForbidden)
+
+		var body api.ErrorBody
+		So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+		So(body, ShouldResemble, api.ErrorBody{Code: http.StatusForbidden, Message: ErrCSRF.Error()})
+	})
+
+	Convey("reject requests without cookie", t, func() {
+		r, _ := http.NewRequest("POST", "/form", nil)
+		r.Header.Set("X-CSRF-Token", token)
+		So(do(r, sessionCk).Code, ShouldEqual, http.StatusForbidden)
+	})
+
+	Convey("reject tokens of other sessions", t, func() {
+		r, _ := http.NewRequest("GET", "/form", nil)
+		other := do(r).Body.String()
+
+		r, _ = http.NewRequest("POST", "/form", nil)
+		r.Header.Set("X-CSRF-Token", other)
+		So(do(r, sessionCk, &http.Cookie{Name: "_csrf", Value: other}).Code, ShouldEqual, http.StatusForbidden)
+	})
+}
+
+func TestCSRFFuncMap(t *testing.T) {
+	csrf := NewCSRF(CSRFOpts{})
+	tmpl := template.Must(template.New("form").Funcs(csrf.FuncMap()).Parse(
+		`<meta content="{{csrfToken .}}">{{csrfField .}}`))
+	se := &Session{Id: "1", Values: values.NewSafeMap("json", map[string]interface{}{"_csrf": "abc<"})}
+
+	Convey("render the token of a session", t, func() {
+		var b bytes.Buffer
+		So(tmpl.Execute(&b, se), ShouldBeNil)
+		So(b.String(), ShouldEqual, `<meta content="abc&lt;"><input type="hidden" name="_csrf" value="abc&lt;">`)
+	})
+
+	Convey("render the token of a context", t, func() {
+		p := NewProvider(nil, cache.NewLruMemCache(100))
+		serv := api.New(p.SessionHandler)
+		serv.GET("/form", func(ctx *api.Context) {
+			var b bytes.Buffer
+			if err := tmpl.Execute(&b, ctx); err != nil {
+				ctx.Reply(http.StatusInternalServerError, err)
+				return
+			}
+			ctx.Reply(http.StatusOK, b.String())
+		})
+		r, _ := http.NewRequest("GET", "/form", nil)
+		w := httptest.NewRecorder()
+		serv.ServeHTTP(w, r)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldContainSubstring, `<input type="hidden" name="_csrf" value="`)
+	})
+
+	Convey("reject other arguments", t, func() {
+		So(tmpl.Execute(&bytes.Buffer{}, "x"), ShouldNotBeNil)
+	})
+}
@@ -117,10 +117,19 @@ func (m *MongoStore) Remove(w http.ResponseWriter, id string) error {
 	defer ms.Close()
 	mc := ms.DB("").C(m.C)
 
-	return mc.RemoveId(id)
+	if err := mc.RemoveId(id); err != mgo.ErrNotFound {
+		return err
+	}
+	return nil
 }
 
 // IdKey returns a key for finding id in map[string]interface{}.
 func (m *MongoStore) IdKey() string {
 	return KeyMongoId
 }
+
+// Close closes the mongodb session, it can be registered to api.Server.OnShutdown.
+func (m *MongoStore) Close() error {
+	m.Ms.Close()
+	return nil
+}
@@ -0,0 +1,283 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zltgo/api/cache"
+	"github.com/zltgo/api/ratelimit"
+	"github.com/zltgo/reflectx"
+)
+
+var (
+	ErrNotFound    = errors.New("session: not found")
+	ErrStoreClosed = errors.New("session: store is closed")
+)
+
+// Options for servers speaking the Redis protocol(RESP).
+type RESPOpts struct {
+	Cookie
+
+	// Addr is the tcp address of the server.
+	Addr     string `default:"127.0.0.1:6379"`
+	Password string
+	DB       int
+
+	// Prefix is prepended to the ids as the keys.
+	Prefix string `default:"session:"`
+
+	// TTL>0 means sessions expire after TTL seconds without saving.
+	// Default is 30 days.
+	TTL int `default:"2592000"`
+
+	// PoolSize is the max number of idle connections.
+	PoolSize int `default:"10"`
+
+	// Timeout in milliseconds of dialing and each command.
+	Timeout int `default:"3000"`
+
+	// Rate limit options in seconds for create cookie cache per ip.
+	// Default is nil, means no limit at all.
+	RateSec []int
+}
+
+var _ Store = &RESPStore{}
+
+// RESPStore stores sessions in Redis or any server speaking RESP as json,
+// the keys expire by the server.
+type RESPStore struct {
+	opts RESPOpts
+
+	mu     sync.Mutex
+	idle   []*respConn
+	closed bool
+}
+
+// RespError is an error replied by the server.
+type RespError string
+
+func (e RespError) Error() string { return string(e) }
+
+type respConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// NewRESPStore returns a RESPStore, the connections are dialed on demand.
+func NewRESPStore(opts RESPOpts) *RESPStore {
+	reflectx.SetDefault(&opts)
+	return &RESPStore{opts: opts}
+}
+
+// NewRESPProvider returns a Provider of RESPStore, the server is pinged
+// to check the options.
+func NewRESPProvider(opts RESPOpts, lmc *cache.LruMemCache) (*Provider, error) {
+	reflectx.SetDefault(&opts)
+	store := NewRESPStore(opts)
+	if _, err := store.Do("PING"); err != nil {
+		return nil, err
+	}
+	return &Provider{
+		Cookie:  opts.Cookie,
+		store:   store,
+		lmc:     lmc,
+		RateOpt: ratelimit.SecOpts(opts.RateSec...),
+	}, nil
+}
+
+// Get retruns data stored by id, ErrNotFound is returned if not found or expired.
+func (m *RESPStore) Get(r *http.Request, id string) (map[string]interface{}, error) {
+	reply, err := m.Do("GET", m.opts.Prefix+id)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := reply.(string)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	mp := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(data), &mp); err != nil {
+		return nil, err
+	}
+	return mp, nil
+}
+
+// Save saves id and data to store, the expiry is renewed.
+func (m *RESPStore) Save(w http.ResponseWriter, id string, mp map[string]interface{}) error {
+	mp[KeySessionId] = id
+	data, err := json.Marshal(mp)
+	if err != nil {
+		return err
+	}
+	if m.opts.TTL > 0 {
+		_, err = m.Do("SET", m.opts.Prefix+id, string(data), "EX", strconv.Itoa(m.opts.TTL))
+	} else {
+		_, err = m.Do("SET", m.opts.Prefix+id, string(data))
+	}
+	return err
+}
+
+// Remove removes id from store.
+func (m *RESPStore) Remove(w http.ResponseWriter, id string) error {
+	_, err := m.Do("DEL", m.opts.Prefix+id)
+	return err
+}
+
+// IdKey returns a key for finding id in map[string]interface{}.
+func (m *RESPStore) IdKey() string {
+	return KeySessionId
+}
+
+// Close closes the idle connections, it can be registered to api.Server.OnShutdown.
+func (m *RESPStore) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	for _, c := range m.idle {
+		c.Close()
+	}
+	m.idle = nil
+	return nil
+}
+
+// Do sends a command to the server and returns the reply, which is one of
+// string, int64, nil, []interface{}, or a RespError as the error.
+func (m *RESPStore) Do(args ...string) (interface{}, error) {
+	c, err := m.get()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := c.do(time.Duration(m.opts.Timeout)*time.Millisecond, args...)
+	if _, ok := err.(RespError); err != nil && !ok {
+		// the connection is broken.
+		c.Close()
+		return nil, err
+	}
+	m.put(c)
+	return reply, err
+}
+
+// get returns an idle connection, or dials a new one.
+func (m *RESPStore) get() (*respConn, error) {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ErrStoreClosed
+	}
+	if n := len(m.idle); n > 0 {
+		c := m.idle[n-1]
+		m.idle = m.idle[:n-1]
+		m.mu.Unlock()
+		return c, nil
+	}
+	m.mu.Unlock()
+
+	timeout := time.Duration(m.opts.Timeout) * time.Millisecond
+	conn, err := net.DialTimeout("tcp", m.opts.Addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &respConn{conn, bufio.NewReader(conn)}
+	if m.opts.Password != "" {
+		if _, err := c.do(timeout, "AUTH", m.opts.Password); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if m.opts.DB != 0 {
+		if _, err := c.do(timeout, "SELECT", strconv.Itoa(m.opts.DB)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// put returns c to the idle connections, c is closed if the pool is full.
+func (m *RESPStore) put(c *respConn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed || len(m.idle) >= m.opts.PoolSize {
+		c.Close()
+		return
+	}
+	m.idle = append(m.idle, c)
+}
+
+func (c *respConn) do(timeout time.Duration, args ...string) (interface{}, error) {
+	c.SetDeadline(time.Now().Add(timeout))
+	if _, err := c.Write(appendCommand(nil, args...)); err != nil {
+		return nil, err
+	}
+	return readReply(c.r)
+}
+
+// appendCommand appends args to b as an array of bulk strings.
+func appendCommand(b []byte, args ...string) []byte {
+	b = append(b, '*')
+	b = strconv.AppendInt(b, int64(len(args)), 10)
+	b = append(b, '\r', '\n')
+	for _, arg := range args {
+		b = append(b, '$')
+		b = strconv.AppendInt(b, int64(len(arg)), 10)
+		b = append(b, '\r', '\n')
+		b = append(b, arg...)
+		b = append(b, '\r', '\n')
+	}
+	return b
+}
+
+// readReply reads a reply of RESP, error replies are returned as RespError.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("session: invalid reply: " + line)
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, RespError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return string(b[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		arr := make([]interface{}, n)
+		for i := range arr {
+			if arr[i], err = readReply(r); err != nil {
+				if e, ok := err.(RespError); ok {
+					arr[i] = e
+					continue
+				}
+				return nil, err
+			}
+		}
+		return arr, nil
+	}
+	return nil, errors.New("session: invalid reply: " + line)
+}
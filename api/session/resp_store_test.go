@@ -0,0 +1,203 @@
+package session
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/zltgo/api/cache"
+)
+
+// respStandIn is an in-process server of a few RESP commands for testing.
+type respStandIn struct {
+	ln       net.Listener
+	password string
+
+	mu      sync.Mutex
+	data    map[string]string
+	expires map[string]time.Time
+	conns   int
+}
+
+func newRESPStandIn(password string) *respStandIn {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	s := &respStandIn{
+		ln:       ln,
+		password: password,
+		data:     make(map[string]string),
+		expires:  make(map[string]time.Time),
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *respStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	s.mu.Lock()
+	s.conns++
+	s.mu.Unlock()
+
+	r := bufio.NewReader(conn)
+	authed := s.password == ""
+	for {
+		cmd, err := readReply(r)
+		if err != nil {
+			return
+		}
+		args, _ := cmd.([]interface{})
+		if len(args) == 0 {
+			conn.Write([]byte("-ERR invalid command\r\n"))
+			continue
+		}
+		argv := make([]string, len(args))
+		for i := range args {
+			argv[i], _ = args[i].(string)
+		}
+		name := strings.ToUpper(argv[0])
+		if !authed && name != "AUTH" {
+			conn.Write([]byte("-NOAUTH Authentication required.\r\n"))
+			continue
+		}
+		conn.Write([]byte(s.exec(name, argv[1:], &authed)))
+	}
+}
+
+func (s *respStandIn) exec(name string, argv []string, authed *bool) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch name {
+	case "PING":
+		return "+PONG\r\n"
+	case "AUTH":
+		if len(argv) != 1 || argv[0] != s.password {
+			return "-ERR invalid password\r\n"
+		}
+		*authed = true
+		return "+OK\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		v, ok := s.data[argv[0]]
+		if exp, has := s.expires[argv[0]]; has && !time.Now().Before(exp) {
+			ok = false
+		}
+		if !ok {
+			return "$-1\r\n"
+		}
+		return "$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n"
+	case "SET":
+		s.data[argv[0]] = argv[1]
+		delete(s.expires, argv[0])
+		if len(argv) == 4 && strings.ToUpper(argv[2]) == "EX" {
+			sec, _ := strconv.Atoi(argv[3])
+			s.expires[argv[0]] = time.Now().Add(time.Duration(sec) * time.Second)
+		}
+		return "+OK\r\n"
+	case "DEL":
+		_, ok := s.data[argv[0]]
+		delete(s.data, argv[0])
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	}
+	return "-ERR unknown command '" + name + "'\r\n"
+}
+
+func TestRESPStore(t *testing.T) {
+	s := newRESPStandIn("secret")
+	defer s.ln.Close()
+
+	Convey("wrong password", t, func() {
+		_, err := NewRESPProvider(RESPOpts{Addr: s.ln.Addr().String(), Password: "x"}, cache.NewLruMemCache(1))
+		So(err, ShouldHaveSameTypeAs, RespError(""))
+	})
+
+	cp := cache.NewLruMemCache(1)
+	p, err := NewRESPProvider(RESPOpts{Addr: s.ln.Addr().String(), Password: "secret", DB: 1}, cp)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer p.Close()
+
+	var ck *http.Cookie
+	Convey("save session", t, func() {
+		r, _ := http.NewRequest("GET", "/test", nil)
+		w := httptest.NewRecorder()
+		c, err := p.GetSession(r)
+		So(err, ShouldEqual, http.ErrNoCookie)
+
+		c.Set("ping", "pang")
+		So(p.SaveSession(w, c), ShouldBeNil)
+		ck = w.Result().Cookies()[0]
+		So(s.data["session:"+c.Id], ShouldContainSubstring, `"ping":"pang"`)
+		So(s.expires["session:"+c.Id], ShouldHappenAfter, time.Now().Add(29*24*time.Hour))
+	})
+
+	Convey("get session from store", t, func() {
+		r, _ := http.NewRequest("GET", "/test", nil)
+		r.AddCookie(ck)
+
+		cp.Set("any id", "delete cookie cache")
+		c, err := p.GetSession(r)
+		So(err, ShouldBeNil)
+		So(c.ValueOf("ping").String(), ShouldEqual, "pang")
+	})
+
+	Convey("remove session", t, func() {
+		r, _ := http.NewRequest("GET", "/test", nil)
+		r.AddCookie(ck)
+		c, _ := p.GetSession(r)
+		c.RemoveAll()
+		So(p.SaveSession(httptest.NewRecorder(), c), ShouldBeNil)
+		So(s.data, ShouldBeEmpty)
+
+		_, err := p.store.Get(r, c.Id)
+		So(err, ShouldEqual, ErrNotFound)
+	})
+
+	Convey("connections are reused", t, func() {
+		s.mu.Lock()
+		n := s.conns
+		s.mu.Unlock()
+		store := p.store.(*RESPStore)
+		for i := 0; i < 10; i++ {
+			_, err := store.Do("PING")
+			So(err, ShouldBeNil)
+		}
+		s.mu.Lock()
+		So(s.conns, ShouldEqual, n)
+		s.mu.Unlock()
+
+		_, err := store.Do("FLUSHALL")
+		So(err, ShouldHaveSameTypeAs, RespError(""))
+		reply, err := store.Do("PING")
+		So(err, ShouldBeNil)
+		So(reply, ShouldEqual, "PONG")
+	})
+
+	Convey("closed store", t, func() {
+		So(p.Close(), ShouldBeNil)
+		_, err := p.store.(*RESPStore).Do("PING")
+		So(err, ShouldEqual, ErrStoreClosed)
+	})
+}
@@ -2,7 +2,9 @@ package session
 
 import (
 	"errors"
+	"io"
 	"net/http"
+	"time"
 
 	"gopkg.in/mgo.v2/bson"
 
@@ -14,14 +16,55 @@ import (
 )
 
 var (
-	ErrOverrun    = errors.New("session: too much call")
-	ErrIdMismatch = errors.New("session: id mismatched")
-	ErrIp         = errors.New("session: remote ip is invalid")
+	ErrOverrun         = errors.New("session: too much call")
+	ErrIdMismatch      = errors.New("session: id mismatched")
+	ErrIp              = errors.New("session: remote ip is invalid")
+	ErrIdleExpired     = errors.New("session: idle timeout expired")
+	ErrAbsoluteExpired = errors.New("session: absolute timeout expired")
+	ErrNoProvider      = errors.New("session: session is not created by Provider")
+)
+
+const (
+	// KeyCreated and KeyAccessed are the unix time in seconds of creating and
+	// the last access of a session, they are set if the timeouts are enabled.
+	KeyCreated  = "_created"
+	KeyAccessed = "_accessed"
 )
 
 type Session struct {
 	Id string
 	values.Values
+
+	p *Provider
+	// the old id to be removed from store by saving.
+	oldId string
+	// the flash messages of the previous request.
+	flashes map[string]interface{}
+}
+
+// Regenerate migrates the data to a new id, and deletes the old one from
+// Store and LruMemCache. It should be called after login to prevent session
+// fixation, for example:
+//	se.Set("Uid", uid)
+//	if err := se.Regenerate(); err != nil {
+//		return err
+//	}
+func (se *Session) Regenerate() error {
+	if se.p == nil || se.Id == "" {
+		return ErrNoProvider
+	}
+	if se.oldId == "" {
+		se.oldId = se.Id
+	}
+	se.p.lmc.Remove(SessionId(se.Id))
+
+	se.Id = bson.NewObjectId().Hex()
+	se.Set(se.p.store.IdKey(), se.Id)
+	if se.p.AbsoluteTimeout > 0 {
+		se.Set(KeyCreated, time.Now().Unix())
+	}
+	se.p.lmc.Set(SessionId(se.Id), se.Values)
+	return nil
 }
 
 type Store interface {
@@ -48,7 +91,18 @@ type Provider struct {
 	// may delete cookies themselves every time.
 	// count of calls per ip are stored in LruMemStore, so LruMemStore must
 	// big enough.
-	RateOpt []ratelimit.Option
+	RateOpt []ratelimit.Rate
+
+	// IdleTimeout>0 means a session expires if it is not accessed in
+	// IdleTimeout seconds, AbsoluteTimeout>0 means a session expires
+	// AbsoluteTimeout seconds after creating or Regenerate.
+	// The expired session is removed and a new one is created.
+	IdleTimeout     int
+	AbsoluteTimeout int
+
+	// OnExpire is called with the expired session, err is ErrIdleExpired
+	// or ErrAbsoluteExpired.
+	OnExpire func(r *http.Request, se *Session, err error)
 }
 
 func NewProvider(store Store, lmc *cache.LruMemCache) *Provider {
@@ -67,6 +121,7 @@ func NewProvider(store Store, lmc *cache.LruMemCache) *Provider {
 }
 
 // Wrap a session middleware.
+// The expired sessions are replaced by new ones if the timeouts are set.
 func (p *Provider) SessionHandler(ctx *api.Context) {
 	// se will be a new session in case of ErrNotFound
 	se, err := p.GetSession(ctx.Request)
@@ -95,13 +150,67 @@ func (p *Provider) SessionHandler(ctx *api.Context) {
 	return
 }
 
+// Close purges the cached sessions and closes the store if it is an io.Closer.
+// It can be registered to api.Server.OnShutdown, for example:
+//	serv.OnShutdown(provider.Close)
+func (p *Provider) Close() error {
+	p.lmc.Clear()
+	if c, ok := p.store.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
 type SessionId string
 type RatelimitId string
 
 // Get returns a cached session.
 // Get should return nil session if ErrOverrun occurred.
 // Usually you should simply create a new Session if an error occurred.
+// A new session is returned with ErrIdleExpired or ErrAbsoluteExpired if
+// the session is expired.
 func (p *Provider) GetSession(r *http.Request) (*Session, error) {
+	se, err := p.getSession(r)
+	if se != nil {
+		se.takeFlashes()
+	}
+	if se == nil || (p.IdleTimeout <= 0 && p.AbsoluteTimeout <= 0) {
+		return se, err
+	}
+
+	now := time.Now().Unix()
+	created := se.ValueOf(KeyCreated)
+	accessed := se.ValueOf(KeyAccessed)
+	var expired error
+	switch {
+	case p.AbsoluteTimeout > 0 && !created.IsNil() && now-created.Int64() >= int64(p.AbsoluteTimeout):
+		expired = ErrAbsoluteExpired
+	case p.IdleTimeout > 0 && !accessed.IsNil() && now-accessed.Int64() >= int64(p.IdleTimeout):
+		expired = ErrIdleExpired
+	}
+	if expired != nil {
+		if p.OnExpire != nil {
+			p.OnExpire(r, se, expired)
+		}
+		p.lmc.Remove(SessionId(se.Id))
+		old := se.Id
+		if se, err = p.newSession(r, expired); se == nil {
+			return nil, err
+		}
+		// remove the expired one from store by saving.
+		se.oldId = old
+	}
+
+	if p.AbsoluteTimeout > 0 && (created.IsNil() || expired != nil) {
+		se.Set(KeyCreated, now)
+	}
+	if p.IdleTimeout > 0 {
+		se.Set(KeyAccessed, now)
+	}
+	return se, err
+}
+
+func (p *Provider) getSession(r *http.Request) (*Session, error) {
 	// get id in cookie
 	id, err := p.GetCookie(r)
 	// create a new session in case of http.ErrNoCookie
@@ -130,7 +239,7 @@ func (p *Provider) GetSession(r *http.Request) (*Session, error) {
 	if id != vs.ValueOf(p.store.IdKey()).String() {
 		return p.newSession(r, ErrIdMismatch)
 	}
-	return &Session{id, vs}, err
+	return &Session{Id: id, Values: vs, p: p}, err
 }
 
 // save session before render, LockGuard is necessay.
@@ -146,6 +255,14 @@ func (p *Provider) SaveSession(w http.ResponseWriter, se *Session) (err error) {
 		return nil
 	}
 
+	// remove the old id of Regenerate.
+	if se.oldId != "" {
+		if err = p.store.Remove(w, se.oldId); err != nil {
+			return err
+		}
+		se.oldId = ""
+	}
+
 	sm := se.Values.(*values.SafeMap)
 	sm.LockGuard(func(data map[string]interface{}) {
 		//remove from store if data is empty
@@ -179,7 +296,7 @@ func (p *Provider) newSession(r *http.Request, err error) (*Session, error) {
 			return ratelimit.New(p.RateOpt)
 		})
 
-		if rl.(*ratelimit.RateLimiter).Limit() {
+		if rl.(*ratelimit.Limiter).Reached() {
 			return nil, ErrOverrun
 		}
 	}
@@ -191,7 +308,7 @@ func (p *Provider) newSession(r *http.Request, err error) (*Session, error) {
 
 	// cache the session to LruMemCache
 	p.lmc.Set(SessionId(id), sm)
-	return &Session{id, sm}, err
+	return &Session{Id: id, Values: sm, p: p}, err
 }
 
 //****************** nostore*********************************
@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"sync"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/zltgo/api/cache"
@@ -179,6 +180,125 @@ func TestCookieRatelimit(t *testing.T) {
 	})
 }
 
+func TestRegenerate(t *testing.T) {
+	s := newRESPStandIn("")
+	defer s.ln.Close()
+	cp := cache.NewLruMemCache(100)
+	p, err := NewRESPProvider(RESPOpts{Addr: s.ln.Addr().String()}, cp)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer p.Close()
+
+	Convey("migrate data to a new id", t, func() {
+		r, _ := http.NewRequest("GET", "/test", nil)
+		w := httptest.NewRecorder()
+		se, _ := p.GetSession(r)
+		se.Set("ping", "pang")
+		So(p.SaveSession(w, se), ShouldBeNil)
+		old := se.Id
+
+		r.AddCookie(w.Result().Cookies()[0])
+		se, err := p.GetSession(r)
+		So(err, ShouldBeNil)
+		So(se.Id, ShouldEqual, old)
+		So(se.Regenerate(), ShouldBeNil)
+		So(se.Id, ShouldNotEqual, old)
+		So(se.ValueOf(KeySessionId).String(), ShouldEqual, se.Id)
+
+		_, err = cp.Get(SessionId(old))
+		So(err, ShouldNotBeNil)
+		w = httptest.NewRecorder()
+		So(p.SaveSession(w, se), ShouldBeNil)
+		So(w.Result().Cookies()[0].Value, ShouldEqual, se.Id)
+
+		s.mu.Lock()
+		_, hasOld := s.data["session:"+old]
+		_, hasNew := s.data["session:"+se.Id]
+		s.mu.Unlock()
+		So(hasOld, ShouldBeFalse)
+		So(hasNew, ShouldBeTrue)
+
+		// the old id is not valid any more.
+		r, _ = http.NewRequest("GET", "/test", nil)
+		r.AddCookie(&http.Cookie{Name: p.Cookie.Name, Value: old})
+		se2, _ := p.GetSession(r)
+		So(se2.Id, ShouldNotEqual, old)
+		So(se2.ValueOf("ping").IsNil(), ShouldBeTrue)
+	})
+
+	Convey("sessions not created by Provider", t, func() {
+		So((&Session{}).Regenerate(), ShouldEqual, ErrNoProvider)
+	})
+}
+
+func TestSessionTimeout(t *testing.T) {
+	p := NewProvider(nil, cache.NewLruMemCache(100))
+	var expired []error
+	p.OnExpire = func(r *http.Request, se *Session, err error) {
+		expired = append(expired, err)
+	}
+	get := func(ck *http.Cookie) (*Session, error) {
+		r, _ := http.NewRequest("GET", "/test", nil)
+		if ck != nil {
+			r.AddCookie(ck)
+		}
+		return p.GetSession(r)
+	}
+	ago := func(sec int) int64 {
+		return time.Now().Unix() - int64(sec)
+	}
+
+	Convey("timeouts are disabled by default", t, func() {
+		se, _ := get(nil)
+		So(se.ValueOf(KeyCreated).IsNil(), ShouldBeTrue)
+		So(se.ValueOf(KeyAccessed).IsNil(), ShouldBeTrue)
+	})
+
+	p.IdleTimeout = 60
+	p.AbsoluteTimeout = 3600
+	se, _ := get(nil)
+	ck := &http.Cookie{Name: p.Cookie.Name, Value: se.Id}
+
+	Convey("access time is renewed", t, func() {
+		So(se.ValueOf(KeyCreated).Int64(), ShouldBeGreaterThan, ago(2))
+		se.Set(KeyAccessed, ago(30))
+		se.Set(KeyCreated, ago(100))
+
+		got, err := get(ck)
+		So(err, ShouldBeNil)
+		So(got.Id, ShouldEqual, se.Id)
+		So(got.ValueOf(KeyAccessed).Int64(), ShouldBeGreaterThan, ago(2))
+		So(got.ValueOf(KeyCreated).Int64(), ShouldEqual, ago(100))
+		So(expired, ShouldBeEmpty)
+	})
+
+	Convey("idle timeout", t, func() {
+		se.Set("ping", "pang")
+		se.Set(KeyAccessed, ago(60))
+		got, err := get(ck)
+		So(err, ShouldEqual, ErrIdleExpired)
+		So(got.Id, ShouldNotEqual, se.Id)
+		So(got.ValueOf("ping").IsNil(), ShouldBeTrue)
+		So(got.ValueOf(KeyCreated).Int64(), ShouldBeGreaterThan, ago(2))
+		So(expired, ShouldResemble, []error{ErrIdleExpired})
+
+		_, err = p.lmc.Get(SessionId(se.Id))
+		So(err, ShouldNotBeNil)
+		se = got
+		ck.Value = se.Id
+	})
+
+	Convey("absolute timeout", t, func() {
+		se.Set(KeyCreated, ago(3600))
+		got, err := get(ck)
+		So(err, ShouldEqual, ErrAbsoluteExpired)
+		So(got.Id, ShouldNotEqual, se.Id)
+		So(expired[len(expired)-1], ShouldEqual, ErrAbsoluteExpired)
+	})
+}
+
 func Benchmark_NewSession(b *testing.B) {
 	lmc := cache.NewLruMemCache(1024)
 	cs := NewCookieProvider(CookieOpts{}, lmc)
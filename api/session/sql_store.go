@@ -0,0 +1,200 @@
+package session
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zltgo/api/cache"
+	"github.com/zltgo/api/ratelimit"
+	"github.com/zltgo/reflectx"
+)
+
+// KeySessionId is the key of id in the data of SQLStore and RESPStore.
+const KeySessionId = "_id"
+
+// Options for database/sql.
+type SQLOpts struct {
+	Cookie
+
+	// Table stores the sessions, it is created by CreateTable if not exists:
+	//	CREATE TABLE IF NOT EXISTS sessions (
+	//		id VARCHAR(64) PRIMARY KEY,
+	//		data TEXT NOT NULL,
+	//		expires_at BIGINT NOT NULL
+	//	)
+	Table         string `default:"sessions"`
+	IdColumn      string `default:"id"`
+	DataColumn    string `default:"data"`
+	ExpiresColumn string `default:"expires_at"`
+	CreateTable   bool
+
+	// Placeholder is the bind parameter of the driver, "?" for mysql and
+	// sqlite, "$" for postgres which is numbered as $1, $2.
+	Placeholder string `default:"?"`
+
+	// TTL>0 means sessions expire after TTL seconds without saving, the unix
+	// time of expiry is stored in ExpiresColumn.
+	// Default is 30 days.
+	TTL int `default:"2592000"`
+
+	// SweepInterval is the interval in seconds of deleting the expired
+	// sessions in background, -1 disables it.
+	SweepInterval int `default:"600"`
+
+	// Rate limit options in seconds for create cookie cache per ip.
+	// Default is nil, means no limit at all.
+	RateSec []int
+}
+
+var _ Store = &SQLStore{}
+
+// SQLStore stores sessions in a table of database/sql as json.
+type SQLStore struct {
+	db  *sql.DB
+	ttl int
+
+	getSQL, existSQL, updateSQL, insertSQL, deleteSQL, sweepSQL string
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// NewSQLStore returns a SQLStore of db and starts the sweeper, db is not
+// closed by the store.
+func NewSQLStore(db *sql.DB, opts SQLOpts) (*SQLStore, error) {
+	reflectx.SetDefault(&opts)
+
+	arg := func(i int) string {
+		if opts.Placeholder == "$" {
+			return "$" + strconv.Itoa(i)
+		}
+		return opts.Placeholder
+	}
+	t, id, data, exp := opts.Table, opts.IdColumn, opts.DataColumn, opts.ExpiresColumn
+	m := &SQLStore{
+		db:  db,
+		ttl: opts.TTL,
+		// expires_at is 0 if sessions never expire.
+		getSQL: "SELECT " + data + " FROM " + t + " WHERE " + id + " = " + arg(1) +
+			" AND (" + exp + " = 0 OR " + exp + " > " + arg(2) + ")",
+		existSQL: "SELECT 1 FROM " + t + " WHERE " + id + " = " + arg(1),
+		updateSQL: "UPDATE " + t + " SET " + data + " = " + arg(1) + ", " + exp + " = " + arg(2) +
+			" WHERE " + id + " = " + arg(3),
+		insertSQL: "INSERT INTO " + t + " (" + id + ", " + data + ", " + exp + ") VALUES (" +
+			arg(1) + ", " + arg(2) + ", " + arg(3) + ")",
+		deleteSQL: "DELETE FROM " + t + " WHERE " + id + " = " + arg(1),
+		sweepSQL:  "DELETE FROM " + t + " WHERE " + exp + " <> 0 AND " + exp + " <= " + arg(1),
+		done:      make(chan struct{}),
+	}
+
+	if opts.CreateTable {
+		if _, err := db.Exec("CREATE TABLE IF NOT EXISTS " + t + " (" + id + " VARCHAR(64) PRIMARY KEY, " +
+			data + " TEXT NOT NULL, " + exp + " BIGINT NOT NULL)"); err != nil {
+			return nil, err
+		}
+	}
+	if opts.SweepInterval > 0 {
+		go m.sweeper(time.Duration(opts.SweepInterval) * time.Second)
+	}
+	return m, nil
+}
+
+// NewSQLProvider returns a Provider of SQLStore.
+func NewSQLProvider(db *sql.DB, opts SQLOpts, lmc *cache.LruMemCache) (*Provider, error) {
+	reflectx.SetDefault(&opts)
+	store, err := NewSQLStore(db, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Provider{
+		Cookie:  opts.Cookie,
+		store:   store,
+		lmc:     lmc,
+		RateOpt: ratelimit.SecOpts(opts.RateSec...),
+	}, nil
+}
+
+// Get retruns data stored by id, sql.ErrNoRows is returned if not found or expired.
+func (m *SQLStore) Get(r *http.Request, id string) (map[string]interface{}, error) {
+	var data string
+	if err := m.db.QueryRowContext(r.Context(), m.getSQL, id, time.Now().Unix()).Scan(&data); err != nil {
+		return nil, err
+	}
+	mp := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(data), &mp); err != nil {
+		return nil, err
+	}
+	return mp, nil
+}
+
+// Save saves id and data to store, the expiry is renewed.
+func (m *SQLStore) Save(w http.ResponseWriter, id string, mp map[string]interface{}) error {
+	mp[KeySessionId] = id
+	data, err := json.Marshal(mp)
+	if err != nil {
+		return err
+	}
+	var expires int64
+	if m.ttl > 0 {
+		expires = time.Now().Unix() + int64(m.ttl)
+	}
+
+	// update or insert, which is supported by all the databases.
+	res, err := m.db.Exec(m.updateSQL, string(data), expires, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	if _, err = m.db.Exec(m.insertSQL, id, string(data), expires); err == nil {
+		return nil
+	}
+	// the row is inserted by others, or not changed by the update of mysql.
+	var one int
+	if m.db.QueryRow(m.existSQL, id).Scan(&one) != nil {
+		return err
+	}
+	_, err = m.db.Exec(m.updateSQL, string(data), expires, id)
+	return err
+}
+
+// Remove removes id from store.
+func (m *SQLStore) Remove(w http.ResponseWriter, id string) error {
+	_, err := m.db.Exec(m.deleteSQL, id)
+	return err
+}
+
+// IdKey returns a key for finding id in map[string]interface{}.
+func (m *SQLStore) IdKey() string {
+	return KeySessionId
+}
+
+// Sweep deletes the expired sessions.
+func (m *SQLStore) Sweep() error {
+	_, err := m.db.Exec(m.sweepSQL, time.Now().Unix())
+	return err
+}
+
+// Close stops the sweeper, it can be registered to api.Server.OnShutdown.
+func (m *SQLStore) Close() error {
+	m.closeOnce.Do(func() { close(m.done) })
+	return nil
+}
+
+func (m *SQLStore) sweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.Sweep()
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package session
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/zltgo/api/cache"
+)
+
+func TestSQLStore(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	// each connection of sqlite3 has its own memory database.
+	db.SetMaxOpenConns(1)
+
+	cp := cache.NewLruMemCache(1)
+	p, err := NewSQLProvider(db, SQLOpts{CreateTable: true, SweepInterval: -1}, cp)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer p.Close()
+	count := func() (n int) {
+		db.QueryRow("SELECT COUNT(*) FROM sessions").Scan(&n)
+		return
+	}
+
+	var ck *http.Cookie
+	Convey("save session", t, func() {
+		r, _ := http.NewRequest("GET", "/test", nil)
+		w := httptest.NewRecorder()
+		c, err := p.GetSession(r)
+		So(err, ShouldEqual, http.ErrNoCookie)
+
+		c.Set("ping", "pang")
+		So(p.SaveSession(w, c), ShouldBeNil)
+		ck = w.Result().Cookies()[0]
+
+		// update the same row, even if nothing changes.
+		So(p.SaveSession(w, c), ShouldBeNil)
+		So(p.SaveSession(w, c), ShouldBeNil)
+		So(count(), ShouldEqual, 1)
+	})
+
+	Convey("get session from store", t, func() {
+		r, _ := http.NewRequest("GET", "/test", nil)
+		r.AddCookie(ck)
+
+		cp.Set("any id", "delete cookie cache")
+		c, err := p.GetSession(r)
+		So(err, ShouldBeNil)
+		So(c.ValueOf("ping").String(), ShouldEqual, "pang")
+	})
+
+	Convey("expired sessions are not found and swept", t, func() {
+		r, _ := http.NewRequest("GET", "/test", nil)
+		store := p.store.(*SQLStore)
+		So(store.Save(nil, "old", map[string]interface{}{"a": 1}), ShouldBeNil)
+		_, err := db.Exec("UPDATE sessions SET expires_at = ? WHERE id = ?", time.Now().Unix()-1, "old")
+		So(err, ShouldBeNil)
+
+		_, err = store.Get(r, "old")
+		So(err, ShouldEqual, sql.ErrNoRows)
+		So(count(), ShouldEqual, 2)
+		So(store.Sweep(), ShouldBeNil)
+		So(count(), ShouldEqual, 1)
+	})
+
+	Convey("remove session", t, func() {
+		r, _ := http.NewRequest("GET", "/test", nil)
+		r.AddCookie(ck)
+		c, _ := p.GetSession(r)
+		c.RemoveAll()
+		So(p.SaveSession(httptest.NewRecorder(), c), ShouldBeNil)
+		So(count(), ShouldEqual, 0)
+	})
+
+	Convey("sweeper runs in background", t, func() {
+		store, err := NewSQLStore(db, SQLOpts{Table: "sweep", CreateTable: true, TTL: 1, SweepInterval: 1})
+		So(err, ShouldBeNil)
+		defer store.Close()
+
+		So(store.Save(nil, "a", map[string]interface{}{}), ShouldBeNil)
+		n := 1
+		for i := 0; i < 30 && n > 0; i++ {
+			time.Sleep(100 * time.Millisecond)
+			db.QueryRow("SELECT COUNT(*) FROM sweep").Scan(&n)
+		}
+		So(n, ShouldEqual, 0)
+	})
+}
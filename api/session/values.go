@@ -0,0 +1,104 @@
+package session
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// KeyFlash is the key of the flash messages for the next request.
+const KeyFlash = "_flash"
+
+// The typed getters return the zero value if key is not found or the value
+// can not be converted. Numbers are converted whatever they are decoded as,
+// such as float64 by json or int64 by bson.
+
+func (se *Session) GetString(key string) string {
+	return se.ValueOf(key).String()
+}
+
+func (se *Session) GetInt(key string) int {
+	return se.ValueOf(key).Int()
+}
+
+func (se *Session) GetInt64(key string) int64 {
+	return se.ValueOf(key).Int64()
+}
+
+func (se *Session) GetFloat(key string) float64 {
+	return se.ValueOf(key).Float()
+}
+
+func (se *Session) GetBool(key string) bool {
+	return se.ValueOf(key).Bool()
+}
+
+// GetTime returns the time.Time of key, which may be decoded as a string of
+// RFC 3339 by json, or unix seconds.
+func (se *Session) GetTime(key string) time.Time {
+	switch v := se.Get(key).(type) {
+	case time.Time:
+		return v
+	case string:
+		t, _ := time.Parse(time.RFC3339Nano, v)
+		return t
+	case nil:
+		return time.Time{}
+	}
+	if sec := se.GetInt64(key); sec != 0 {
+		return time.Unix(sec, 0)
+	}
+	return time.Time{}
+}
+
+// GetStruct stores the value of key in the value pointed to by ptr.
+// The value is converted by json if its type is not the same, such as a
+// struct decoded as map[string]interface{} by json.
+// It returns ErrNotFound if key is not found.
+func (se *Session) GetStruct(key string, ptr interface{}) error {
+	v := se.Get(key)
+	if v == nil {
+		return ErrNotFound
+	}
+	dst := reflect.ValueOf(ptr).Elem()
+	if src := reflect.ValueOf(v); src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, ptr)
+}
+
+// AddFlash adds a message of category for the next request only, the
+// messages are read by Flashes of the next request, for example:
+//	se.AddFlash("success", "saved")
+//	ctx.Redirect(http.StatusSeeOther, "/list")
+func (se *Session) AddFlash(category string, msg interface{}) {
+	se.Update(KeyFlash, func(old interface{}) interface{} {
+		flashes, ok := old.(map[string]interface{})
+		if !ok {
+			flashes = make(map[string]interface{})
+		}
+		list, _ := flashes[category].([]interface{})
+		flashes[category] = append(list, msg)
+		return flashes
+	})
+}
+
+// Flashes returns the messages of category added by the previous request.
+func (se *Session) Flashes(category string) []interface{} {
+	list, _ := se.flashes[category].([]interface{})
+	return list
+}
+
+// takeFlashes moves the flash messages of the previous request out of the
+// values, so that they survive exactly one request.
+func (se *Session) takeFlashes() {
+	if flashes, ok := se.Get(KeyFlash).(map[string]interface{}); ok {
+		se.flashes = flashes
+		se.Remove(KeyFlash)
+	}
+}
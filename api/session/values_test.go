@@ -0,0 +1,133 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/zltgo/api"
+	"github.com/zltgo/api/cache"
+)
+
+type testUser struct {
+	Name  string
+	Age   int
+	Roles []string
+}
+
+func init() {
+	RegisterType(testUser{})
+}
+
+// roundTrip saves se by p and loads it from the cookies without LruMemCache.
+func roundTrip(p *Provider, se *Session) *Session {
+	w := httptest.NewRecorder()
+	if err := p.SaveSession(w, se); err != nil {
+		panic(err)
+	}
+	p.lmc.Clear()
+	r, _ := http.NewRequest("GET", "/test", nil)
+	for _, ck := range w.Result().Cookies() {
+		r.AddCookie(ck)
+	}
+	se, err := p.GetSession(r)
+	if err != nil {
+		panic(err)
+	}
+	return se
+}
+
+func TestTypedValues(t *testing.T) {
+	now := time.Now().Round(time.Second)
+	user := testUser{"zyx", 18, []string{"admin"}}
+	set := func(se *Session) {
+		se.Set("s", "str")
+		se.Set("i", 42)
+		se.Set("f", 1.5)
+		se.Set("b", true)
+		se.Set("t", now)
+		se.Set("u", user)
+	}
+
+	for _, useGob := range []bool{false, true} {
+		p := NewCookieProvider(CookieOpts{Gob: useGob}, cache.NewLruMemCache(10))
+		r, _ := http.NewRequest("GET", "/test", nil)
+		se, _ := p.GetSession(r)
+		set(se)
+		se = roundTrip(p, se)
+
+		Convey("typed getters after round trip", t, func() {
+			So(se.GetString("s"), ShouldEqual, "str")
+			So(se.GetInt("i"), ShouldEqual, 42)
+			So(se.GetInt64("i"), ShouldEqual, 42)
+			So(se.GetFloat("f"), ShouldEqual, 1.5)
+			So(se.GetBool("b"), ShouldBeTrue)
+			So(se.GetTime("t").Equal(now), ShouldBeTrue)
+
+			var u testUser
+			So(se.GetStruct("u", &u), ShouldBeNil)
+			So(u, ShouldResemble, user)
+		})
+
+		Convey("missing keys", t, func() {
+			So(se.GetString("x"), ShouldEqual, "")
+			So(se.GetInt("x"), ShouldEqual, 0)
+			So(se.GetBool("x"), ShouldBeFalse)
+			So(se.GetTime("x").IsZero(), ShouldBeTrue)
+			var u testUser
+			So(se.GetStruct("x", &u), ShouldEqual, ErrNotFound)
+		})
+
+		if useGob {
+			Convey("gob keeps the concrete types", t, func() {
+				So(se.Get("i"), ShouldEqual, 42)
+				So(se.Get("t"), ShouldHaveSameTypeAs, time.Time{})
+				So(se.Get("u"), ShouldResemble, user)
+			})
+		}
+	}
+}
+
+func TestFlash(t *testing.T) {
+	p := NewProvider(nil, cache.NewLruMemCache(100))
+	serv := api.New(p.SessionHandler)
+	serv.POST("/save", func(ctx *api.Context) {
+		var se *Session
+		ctx.MustGet(&se)
+		se.AddFlash("success", "saved")
+		se.AddFlash("success", "again")
+		se.AddFlash("error", "oops")
+		ctx.Reply(http.StatusOK, len(se.Flashes("success")))
+	})
+	serv.GET("/list", func(ctx *api.Context) {
+		var se *Session
+		ctx.MustGet(&se)
+		ctx.Reply(http.StatusOK, map[string]interface{}{
+			"success": se.Flashes("success"),
+			"error":   se.Flashes("error"),
+		})
+	})
+
+	var ck *http.Cookie
+	do := func(method, path string) string {
+		r, _ := http.NewRequest(method, path, nil)
+		r.Header.Set("Accept", "application/json")
+		if ck != nil {
+			r.AddCookie(ck)
+		}
+		w := httptest.NewRecorder()
+		serv.ServeHTTP(w, r)
+		if cks := w.Result().Cookies(); len(cks) > 0 {
+			ck = cks[0]
+		}
+		return w.Body.String()
+	}
+
+	Convey("flashes survive exactly one subsequent request", t, func() {
+		So(do("POST", "/save"), ShouldEqual, "0")
+		So(do("GET", "/list"), ShouldEqual, `{"error":["oops"],"success":["saved","again"]}`)
+		So(do("GET", "/list"), ShouldEqual, `{"error":null,"success":null}`)
+	})
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrHijackTimeout = errors.New("api: Hijack is not supported inside Timeout")
+
+// Timeout returns a middleware that cancels the request context after d.
+// The pending handlers are running in another goroutine and the response is
+// buffered, if the deadline passes before they return, a 503 is written and
+// any later write of the handlers fails with http.ErrHandlerTimeout.
+// Handlers doing long work should watch ctx.Done(), because Timeout waits for
+// them to return before the Context is reused.
+// It can be used per route or per group, for example:
+//	serv.Group("/api", api.Timeout(5*time.Second))
+func Timeout(d time.Duration) Handler {
+	return func(c *Context) {
+		tctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+
+		w := c.Writer
+		tw := newTimeoutWriter(w)
+		c.Request = c.Request.WithContext(tctx)
+		c.Writer = tw
+
+		done := make(chan struct{})
+		var panicked interface{}
+		go func() {
+			defer func() {
+				// Reply panics if the response is refused after timeout.
+				if p := recover(); p != nil && p != http.ErrHandlerTimeout {
+					panicked = p
+				}
+				close(done)
+			}()
+			c.Next()
+		}()
+
+		select {
+		case <-done:
+			c.Writer = w
+			if panicked != nil {
+				panic(panicked)
+			}
+			tw.flushTo(w)
+		case <-tctx.Done():
+			tw.timeout()
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.WriteString("503 service unavailable")
+			w.Flush()
+
+			// do not reuse Context before the handlers return.
+			<-done
+			c.Writer = w
+			if panicked != nil {
+				panic(panicked)
+			}
+		}
+		// prevent pending handlers from being called.
+		c.index = abortIndex
+	}
+}
+
+var _ ResponseWriter = &timeoutWriter{}
+
+// timeoutWriter buffers the response until the handlers return.
+type timeoutWriter struct {
+	w      ResponseWriter
+	header http.Header
+	buf    bytes.Buffer
+	status int
+	size   int
+
+	mu       sync.Mutex
+	timedOut bool
+}
+
+func newTimeoutWriter(w ResponseWriter) *timeoutWriter {
+	return &timeoutWriter{
+		w:      w,
+		header: make(http.Header),
+		status: w.Status(),
+		size:   noWritten,
+	}
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	if code > 0 {
+		tw.status = code
+	}
+}
+
+func (tw *timeoutWriter) WriteHeaderNow() {
+	if !tw.Written() {
+		tw.size = 0
+	}
+}
+
+func (tw *timeoutWriter) Write(data []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.WriteHeaderNow()
+	n, err := tw.buf.Write(data)
+	tw.size += n
+	return n, err
+}
+
+func (tw *timeoutWriter) WriteString(s string) (int, error) {
+	return tw.Write([]byte(s))
+}
+
+func (tw *timeoutWriter) Status() int {
+	return tw.status
+}
+
+func (tw *timeoutWriter) Size() int {
+	return tw.size
+}
+
+func (tw *timeoutWriter) Written() bool {
+	return tw.size != noWritten
+}
+
+// Defer is ignored after timeout, the response has been written.
+func (tw *timeoutWriter) Defer(f func()) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.timedOut {
+		tw.w.Defer(f)
+	}
+}
+
+// Flush does nothing, the response is written after the handlers return.
+func (tw *timeoutWriter) Flush() {}
+
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, ErrHijackTimeout
+}
+
+func (tw *timeoutWriter) CloseNotify() <-chan bool {
+	return tw.w.CloseNotify()
+}
+
+func (tw *timeoutWriter) Pusher() http.Pusher {
+	return tw.w.Pusher()
+}
+
+func (tw *timeoutWriter) timeout() {
+	tw.mu.Lock()
+	tw.timedOut = true
+	tw.mu.Unlock()
+}
+
+// flushTo copies the buffered response to w.
+func (tw *timeoutWriter) flushTo(w ResponseWriter) {
+	dst := w.Header()
+	for k, vv := range tw.header {
+		dst[k] = vv
+	}
+	w.WriteHeader(tw.status)
+	if tw.Written() {
+		w.WriteHeaderNow()
+		io.Copy(w, &tw.buf)
+	}
+}
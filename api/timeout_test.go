@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextIsStdContext(t *testing.T) {
+	router := New()
+	router.GET("/ctx", func(c *Context) {
+		c.Map(42)
+		var std context.Context = c
+		assert.Equal(t, 42, std.Value(reflect.TypeOf(0)))
+		assert.Nil(t, std.Value(reflect.TypeOf("")))
+		assert.Equal(t, "bar", std.Value(ctxKey("foo")))
+
+		_, ok := c.Deadline()
+		assert.False(t, ok)
+		assert.NoError(t, c.Err())
+		c.Reply(http.StatusOK, "ok")
+	})
+
+	r, _ := http.NewRequest("GET", "/ctx", nil)
+	r = r.WithContext(context.WithValue(r.Context(), ctxKey("foo"), "bar"))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestInjectStdContext(t *testing.T) {
+	router := New()
+	router.GET("/ctx", Timeout(time.Second), H(func(ctx context.Context) string {
+		_, ok := ctx.Deadline()
+		assert.True(t, ok)
+		return "injected"
+	}))
+
+	w := performRequest(router, "GET", "/ctx")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "injected", w.Body.String())
+}
+
+func TestTimeout(t *testing.T) {
+	router := New()
+	passed := false
+	group := router.Group("/api", Timeout(20*time.Millisecond))
+	group.GET("/fast", func(c *Context) {
+		c.Writer.Header().Set("X-Fast", "true")
+		c.Reply(http.StatusCreated, "fast")
+	})
+	group.GET("/slow", func(c *Context) {
+		select {
+		case <-c.Done():
+			assert.Equal(t, context.DeadlineExceeded, c.Err())
+		case <-time.After(time.Second):
+			t.Error("context is not canceled")
+		}
+		c.Reply(http.StatusOK, "slow")
+	}, func(c *Context) {
+		passed = true
+	})
+	group.GET("/empty", func(c *Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	w := performRequest(router, "GET", "/api/fast")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "fast", w.Body.String())
+	assert.Equal(t, "true", w.Header().Get("X-Fast"))
+
+	w = performRequest(router, "GET", "/api/slow")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "503 service unavailable", w.Body.String())
+	assert.False(t, passed)
+
+	w = performRequest(router, "GET", "/api/empty")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	router := New(RecoveryWithWriter(nil))
+	router.GET("/panic", Timeout(time.Second), func(c *Context) {
+		panic(errors.New("oops"))
+	})
+
+	w := performRequest(router, "GET", "/panic")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+type ctxKey string
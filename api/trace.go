@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	HeaderRequestID   = "X-Request-ID"
+	HeaderTraceparent = "traceparent"
+	HeaderTracestate  = "tracestate"
+)
+
+// Trace identifies a request across services by the X-Request-ID header
+// and the W3C trace context, see https://www.w3.org/TR/trace-context/.
+type Trace struct {
+	// RequestID is accepted from the X-Request-ID header or generated.
+	RequestID string
+	// TraceID is the 32 hex digits shared by all the spans of the trace.
+	TraceID string
+	// SpanID is the 16 hex digits of the span of this server,
+	// it is the parent-id of the requests sent to other services.
+	SpanID string
+	// ParentID is the span of the caller, it is empty if the trace starts here.
+	ParentID string
+	// Flags is the 2 hex digits of trace flags, "01" means sampled.
+	Flags string
+	// State is the tracestate header passed through.
+	State string
+}
+
+// Traceparent returns the traceparent header of the requests sent to other services.
+func (t *Trace) Traceparent() string {
+	return "00-" + t.TraceID + "-" + t.SpanID + "-" + t.Flags
+}
+
+type traceKey struct{}
+
+// TraceFrom returns the Trace of the request context set by Tracing,
+// nil is returned if not found. ctx can be an *api.Context.
+func TraceFrom(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+// Trace returns the Trace of the request set by Tracing, nil is returned if not found.
+func (ctx *Context) Trace() *Trace {
+	return TraceFrom(ctx.Request.Context())
+}
+
+// Tracing returns a middleware that accepts or generates the X-Request-ID and
+// the traceparent of the request. The Trace is stored in the request context,
+// written to the X-Request-ID header of the response and printed by Logger
+// and Recovery. Requests sent by 'zltgo/api/client' with the request context
+// forward it to other services, for example:
+//	r, _ := client.NewJsonRequest("GET", "http://dress/app/goods", nil)
+//	client.Default.Exec(r.WithContext(ctx), &goods)
+func Tracing() Handler {
+	return func(c *Context) {
+		t := &Trace{
+			RequestID: c.Request.Header.Get(HeaderRequestID),
+			SpanID:    randomHex(8),
+			Flags:     "01",
+		}
+		if !validRequestID(t.RequestID) {
+			t.RequestID = randomHex(16)
+		}
+		if traceID, parentID, flags, ok := parseTraceparent(c.Request.Header.Get(HeaderTraceparent)); ok {
+			t.TraceID, t.ParentID, t.Flags = traceID, parentID, flags
+			t.State = c.Request.Header.Get(HeaderTracestate)
+		} else {
+			t.TraceID = randomHex(16)
+		}
+
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), traceKey{}, t))
+		c.Writer.Header().Set(HeaderRequestID, t.RequestID)
+		c.Next()
+	}
+}
+
+// validRequestID accepts at most 128 visible ASCII characters,
+// which are safe to be printed in logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTraceparent parses the header like
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+// Fields after the flags of future versions are ignored.
+func parseTraceparent(h string) (traceID, parentID, flags string, ok bool) {
+	h = strings.TrimSpace(h)
+	if len(h) < 55 || (len(h) > 55 && (h[:2] == "00" || h[55] != '-')) {
+		return
+	}
+	if h[2] != '-' || h[35] != '-' || h[52] != '-' || h[:2] == "ff" {
+		return
+	}
+	version, traceID, parentID, flags := h[:2], h[3:35], h[36:52], h[53:55]
+	for _, s := range []string{version, traceID, parentID, flags} {
+		if !isLowerHex(s) {
+			return
+		}
+	}
+	if strings.Trim(traceID, "0") == "" || strings.Trim(parentID, "0") == "" {
+		return
+	}
+	return traceID, parentID, flags, true
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if (s[i] < '0' || s[i] > '9') && (s[i] < 'a' || s[i] > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// randomHex returns n random bytes in hex.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracing(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	var trace *Trace
+	router := New(LoggerWithWriter(buffer), Tracing())
+	router.GET("/example", func(c *Context) {
+		trace = c.Trace()
+		assert.Equal(t, trace, TraceFrom(c))
+	})
+
+	// generated
+	w := performRequest(router, "GET", "/example")
+	assert.Len(t, trace.RequestID, 32)
+	assert.Len(t, trace.TraceID, 32)
+	assert.Len(t, trace.SpanID, 16)
+	assert.Empty(t, trace.ParentID)
+	assert.Equal(t, trace.RequestID, w.Header().Get(HeaderRequestID))
+	assert.Equal(t, "00-"+trace.TraceID+"-"+trace.SpanID+"-01", trace.Traceparent())
+	assert.Contains(t, buffer.String(), trace.RequestID+" "+trace.TraceID)
+
+	// accepted
+	req, _ := http.NewRequest("GET", "/example", nil)
+	req.Header.Set(HeaderRequestID, "req-42")
+	req.Header.Set(HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	req.Header.Set(HeaderTracestate, "congo=t61rcWkgMzE")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "req-42", trace.RequestID)
+	assert.Equal(t, "req-42", w.Header().Get(HeaderRequestID))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", trace.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", trace.ParentID)
+	assert.NotEqual(t, trace.ParentID, trace.SpanID)
+	assert.Equal(t, "00", trace.Flags)
+	assert.Equal(t, "congo=t61rcWkgMzE", trace.State)
+
+	// rejected
+	req.Header.Set(HeaderRequestID, "bad id\n")
+	req.Header.Set(HeaderTraceparent, "00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	assert.NotEqual(t, "bad id\n", trace.RequestID)
+	assert.NotEqual(t, "00000000000000000000000000000000", trace.TraceID)
+	assert.Empty(t, trace.State)
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		header string
+		ok     bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		_, _, _, ok := parseTraceparent(test.header)
+		assert.Equal(t, test.ok, ok, test.header)
+	}
+}
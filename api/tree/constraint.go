@@ -0,0 +1,123 @@
+package tree
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ParamTypes are the named constraints of params, such as "/:id<int>".
+// Constraints other than ParamTypes are compiled as regular expressions
+// matching the whole segment, such as "/:uuid<[0-9a-f]{24}>".
+// It is not thread-safe, add types at initialization.
+var ParamTypes = map[string]func(string) bool{
+	"int":   isInt,
+	"uint":  isUint,
+	"alpha": isAlpha,
+	"hex":   isHex,
+	"uuid":  isUUID,
+}
+
+// SplitWildcard splits a wildcard like ":id<int>" to its name and constraint.
+func SplitWildcard(wildcard string) (name, constraint string) {
+	name = wildcard[1:]
+	if i := strings.IndexByte(name, '<'); i >= 0 && name[len(name)-1] == '>' {
+		name, constraint = name[:i], name[i+1:len(name)-1]
+	}
+	return
+}
+
+// Constraint returns the function checking values of the constraint,
+// nil is returned for an empty constraint. It panics if the constraint is
+// neither a param type nor a valid regular expression.
+func Constraint(constraint string) func(string) bool {
+	if constraint == "" {
+		return nil
+	}
+	if fn, ok := ParamTypes[constraint]; ok {
+		return fn
+	}
+	re, err := regexp.Compile("^(?:" + constraint + ")$")
+	if err != nil {
+		panic("invalid param constraint '" + constraint + "': " + err.Error())
+	}
+	return re.MatchString
+}
+
+// parseWildcard returns the name and constraint function of the wildcard.
+func parseWildcard(wildcard, fullPath string) (string, func(string) bool) {
+	name, constraint := SplitWildcard(wildcard)
+	if strings.IndexByte(name, '<') >= 0 {
+		panic("constraint must end with '>' in path '" + fullPath + "'")
+	}
+	if name == "" {
+		panic("wildcards must be named with a non-empty name in path '" + fullPath + "'")
+	}
+	if wildcard[0] == '*' && constraint != "" {
+		panic("catch-all can not be constrained in path '" + fullPath + "'")
+	}
+	return name, Constraint(constraint)
+}
+
+func isInt(s string) bool {
+	if len(s) > 1 && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	return isUint(s)
+}
+
+func isUint(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isHex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c|0x20 < 'a' || c|0x20 > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isUUID checks the canonical form like "123e4567-e89b-12d3-a456-426614174000".
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			if !isHex(s[i : i+1]) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package tree
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Reverse builds a path from the route path by filling the wildcards with
+// values in order, for example:
+//	Reverse("/user/:id<int>/files/*filepath", "12", "/a/b.txt") // "/user/12/files/a/b.txt"
+// The number of values must be equal to the number of wildcards, and the values
+// of params must match the constraints. Values are escaped by url.PathEscape,
+// the slashes of catch-all values are kept.
+func Reverse(path string, values ...string) (string, error) {
+	if n := int(countParams(path)); n != len(values) {
+		return "", fmt.Errorf("tree: path '%s' has %d params, got %d values", path, n, len(values))
+	}
+
+	var b strings.Builder
+	for _, value := range values {
+		wildcard, i, valid := findWildcard(path)
+		if !valid {
+			return "", fmt.Errorf("tree: invalid wildcard '%s' in path '%s'", wildcard, path)
+		}
+		b.WriteString(path[:i])
+		path = path[i+len(wildcard):]
+
+		name, constraint := SplitWildcard(wildcard)
+		if wildcard[0] == '*' {
+			// the catch-all value is matched with the leading '/',
+			// which is already a part of the route path.
+			value = strings.TrimPrefix(value, "/")
+			segs := strings.Split(value, "/")
+			for j := range segs {
+				segs[j] = url.PathEscape(segs[j])
+			}
+			b.WriteString(strings.Join(segs, "/"))
+			continue
+		}
+
+		if value == "" {
+			return "", fmt.Errorf("tree: empty value of param '%s'", name)
+		}
+		if match := Constraint(constraint); match != nil && !match(value) {
+			return "", fmt.Errorf("tree: value '%s' of param '%s' does not match '%s'", value, name, constraint)
+		}
+		b.WriteString(url.PathEscape(value))
+	}
+	b.WriteString(path)
+	return b.String(), nil
+}
@@ -70,14 +70,15 @@ func min(a, b int) int {
 	}
 	return b
 }
-
 func countParams(path string) uint8 {
 	var n uint
-	for i := 0; i < len(path); i++ {
-		if path[i] != ':' && path[i] != '*' {
-			continue
+	for {
+		wildcard, i, _ := findWildcard(path)
+		if i < 0 {
+			break
 		}
 		n++
+		path = path[i+len(wildcard):]
 	}
 	if n >= 255 {
 		return 255
@@ -85,6 +86,49 @@ func countParams(path string) uint8 {
 	return uint8(n)
 }
 
+// findWildcard searches for a wildcard segment and checks the name for invalid characters.
+// Returns -1 as index, if no wildcard was found. The constraint of a param like
+// ":id<[0-9]+>" is a part of the wildcard, it may contain ':', '*' and '/'.
+func findWildcard(path string) (wildcard string, i int, valid bool) {
+	for start := 0; start < len(path); start++ {
+		c := path[start]
+		if c != ':' && c != '*' {
+			continue
+		}
+
+		// find wildcard end (either '/' or path end)
+		valid = true
+		end := start + 1
+		for end < len(path) && path[end] != '/' {
+			switch path[end] {
+			// the wildcard name must not contain ':' and '*'
+			case ':', '*':
+				valid = false
+			case '<':
+				// skip the constraint, which ends with '>' followed by '/' or path end
+				for j := end + 1; j < len(path); j++ {
+					if path[j] == '>' && (j+1 == len(path) || path[j+1] == '/') {
+						end = j
+						break
+					}
+				}
+			}
+			end++
+		}
+		return path[start:end], start, valid
+	}
+	return "", -1, false
+}
+
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	max := min(len(a), len(b))
+	for i < max && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
 type nodeType uint8
 
 const (
@@ -95,7 +139,9 @@ const (
 )
 
 type Node struct {
-	path      string
+	path string
+	// the last child is a param or catchAll Node if wildChild is true,
+	// static children are looked up by indices first.
 	wildChild bool
 	nType     nodeType
 	maxParams uint8
@@ -103,6 +149,10 @@ type Node struct {
 	children  []*Node
 	handle    interface{}
 	priority  uint32
+
+	// name and constraint of param and catchAll Node.
+	key   string
+	match func(string) bool
 }
 
 // increments priority of the given child and reorders if necessary.
@@ -129,6 +179,17 @@ func (n *Node) incrementChildPrio(pos int) int {
 	return newPos
 }
 
+// addChild adds a static child before the wildcard child, the wildcard child
+// is always the last one.
+func (n *Node) addChild(child *Node) {
+	if n.wildChild && len(n.children) > 0 {
+		wildcardChild := n.children[len(n.children)-1]
+		n.children = append(n.children[:len(n.children)-1], child, wildcardChild)
+	} else {
+		n.children = append(n.children, child)
+	}
+}
+
 // Walk calls f sequentially for each key and value present in the map.
 // If f returns false, Walk stops the iteration.
 // The handle will not be nil all the time.
@@ -153,176 +214,161 @@ func (n *Node) walk(prefix string, f func(path string, handle interface{}) bool)
 }
 
 // addRoute adds a Node with the given handle to the path.
+// Static segments can coexist with a param at the same level, such as
+// "/user/new" and "/user/:id", the static one takes priority.
+// They can not coexist with a catch-all, "/files/new" and "/files/*path"
+// panic in either order, use a param like "/files/:name" or a prefix like
+// "/files/raw/*path" instead.
+// Params can be constrained by a type or a regex, such as "/:id<int>" and
+// "/:uuid<[0-9a-f]{24}>", see ParamTypes.
 // Not concurrency-safe!
 func (n *Node) AddHandle(path string, handle interface{}) {
 	fullPath := path
 	n.priority++
 	numParams := countParams(path)
 
-	// non-empty tree
-	if len(n.path) > 0 || len(n.children) > 0 {
-	walk:
-		for {
-			// Update maxParams of the current Node
-			if numParams > n.maxParams {
-				n.maxParams = numParams
-			}
+	// Empty tree
+	if len(n.path) == 0 && len(n.children) == 0 {
+		n.insertChild(numParams, path, fullPath, handle)
+		n.nType = root
+		return
+	}
 
-			// Find the longest common prefix.
-			// This also implies that the common prefix contains no ':' or '*'
-			// since the existing key can't contain those chars.
-			i := 0
-			max := min(len(path), len(n.path))
-			for i < max && path[i] == n.path[i] {
-				i++
-			}
+walk:
+	for {
+		// Update maxParams of the current Node
+		if numParams > n.maxParams {
+			n.maxParams = numParams
+		}
 
-			// Split edge
-			if i < len(n.path) {
-				child := Node{
-					path:      n.path[i:],
-					wildChild: n.wildChild,
-					indices:   n.indices,
-					children:  n.children,
-					handle:    n.handle,
-					priority:  n.priority - 1,
-				}
+		// Find the longest common prefix.
+		// This also implies that the common prefix contains no ':' or '*'
+		// since the existing key can't contain those chars.
+		i := longestCommonPrefix(path, n.path)
+
+		// Split edge
+		if i < len(n.path) {
+			child := Node{
+				path:      n.path[i:],
+				wildChild: n.wildChild,
+				indices:   n.indices,
+				children:  n.children,
+				handle:    n.handle,
+				priority:  n.priority - 1,
+			}
 
-				// Update maxParams (max of all children)
-				for i := range child.children {
-					if child.children[i].maxParams > child.maxParams {
-						child.maxParams = child.children[i].maxParams
-					}
+			// Update maxParams (max of all children)
+			for i := range child.children {
+				if child.children[i].maxParams > child.maxParams {
+					child.maxParams = child.children[i].maxParams
 				}
-
-				n.children = []*Node{&child}
-				// []byte for proper unicode char conversion, see #65
-				n.indices = string([]byte{n.path[i]})
-				n.path = path[:i]
-				n.handle = nil
-				n.wildChild = false
 			}
 
-			// Make new Node a child of this Node
-			if i < len(path) {
-				path = path[i:]
-
-				if n.wildChild {
-					n = n.children[0]
-					n.priority++
-
-					// Update maxParams of the child Node
-					if numParams > n.maxParams {
-						n.maxParams = numParams
-					}
-					numParams--
-
-					// Check if the wildcard matches
-					if len(path) >= len(n.path) && n.path == path[:len(n.path)] {
-						// check for longer wildcard, e.g. :name and :names
-						if len(n.path) >= len(path) || path[len(n.path)] == '/' {
-							continue walk
-						}
-					}
+			n.children = []*Node{&child}
+			// []byte for proper unicode char conversion, see #65
+			n.indices = string([]byte{n.path[i]})
+			n.path = path[:i]
+			n.handle = nil
+			n.wildChild = false
+		}
 
-					panic("path segment '" + path +
-						"' conflicts with existing wildcard '" + n.path +
-						"' in path '" + fullPath + "'")
-				}
+		// Make new Node a child of this Node
+		if i < len(path) {
+			path = path[i:]
+			c := path[0]
 
-				c := path[0]
+			// slash after param
+			if n.nType == param && c == '/' && len(n.children) == 1 {
+				n = n.children[0]
+				n.priority++
+				continue walk
+			}
 
-				// slash after param
-				if n.nType == param && c == '/' && len(n.children) == 1 {
-					n = n.children[0]
-					n.priority++
+			// Check if a child with the next path byte exists
+			for i := 0; i < len(n.indices); i++ {
+				if c == n.indices[i] {
+					i = n.incrementChildPrio(i)
+					n = n.children[i]
 					continue walk
 				}
+			}
 
-				// Check if a child with the next path byte exists
-				for i := 0; i < len(n.indices); i++ {
-					if c == n.indices[i] {
-						i = n.incrementChildPrio(i)
-						n = n.children[i]
-						continue walk
-					}
+			// Otherwise insert it
+			if c != ':' && c != '*' && n.nType != catchAll {
+				// []byte for proper unicode char conversion, see #65
+				n.indices += string([]byte{c})
+				child := &Node{
+					maxParams: numParams,
 				}
-
-				// Otherwise insert it
-				if c != ':' && c != '*' {
-					// []byte for proper unicode char conversion, see #65
-					n.indices += string([]byte{c})
-					child := &Node{
-						maxParams: numParams,
-					}
-					n.children = append(n.children, child)
-					n.incrementChildPrio(len(n.indices) - 1)
-					n = child
+				n.addChild(child)
+				n.incrementChildPrio(len(n.indices) - 1)
+				n = child
+			} else if n.wildChild {
+				// inserting a wildcard Node, check if it conflicts with the existing wildcard
+				n = n.children[len(n.children)-1]
+				n.priority++
+
+				// Update maxParams of the child Node
+				if numParams > n.maxParams {
+					n.maxParams = numParams
 				}
-				n.insertChild(numParams, path, fullPath, handle)
-				return
-
-			} else if i == len(path) { // Make Node a (in-path) leaf
-				if n.handle != nil {
-					panic("handle are already registered for path ''" + fullPath + "'")
+				numParams--
+
+				// Check if the wildcard matches
+				if len(path) >= len(n.path) && n.path == path[:len(n.path)] &&
+					// adding a child to a catchAll is not possible
+					n.nType != catchAll &&
+					// check for longer wildcard, e.g. :name and :names
+					(len(n.path) >= len(path) || path[len(n.path)] == '/') {
+					continue walk
 				}
-				n.handle = handle
+
+				panic("path segment '" + path +
+					"' conflicts with existing wildcard '" + n.path +
+					"' in path '" + fullPath + "'")
 			}
+
+			n.insertChild(numParams, path, fullPath, handle)
 			return
 		}
-	} else { // Empty tree
-		n.insertChild(numParams, path, fullPath, handle)
-		n.nType = root
+
+		// Otherwise make Node a (in-path) leaf
+		if n.handle != nil {
+			panic("handle are already registered for path ''" + fullPath + "'")
+		}
+		n.handle = handle
+		return
 	}
 }
 
 func (n *Node) insertChild(numParams uint8, path string, fullPath string, handle interface{}) {
-	var offset int // already handled bytes of the path
-
-	// find prefix until first wildcard (beginning with ':'' or '*'')
-	for i, max := 0, len(path); numParams > 0; i++ {
-		c := path[i]
-		if c != ':' && c != '*' {
-			continue
-		}
-
-		// find wildcard end (either '/' or path end)
-		end := i + 1
-		for end < max && path[end] != '/' {
-			switch path[end] {
-			// the wildcard name must not contain ':' and '*'
-			case ':', '*':
-				panic("only one wildcard per path segment is allowed, has: '" +
-					path[i:] + "' in path '" + fullPath + "'")
-			default:
-				end++
-			}
-		}
-
-		// check if this Node existing children which would be
-		// unreachable if we insert the wildcard here
-		if len(n.children) > 0 {
-			panic("wildcard route '" + path[i:end] +
-				"' conflicts with existing children in path '" + fullPath + "'")
+	for {
+		// find prefix until first wildcard (beginning with ':'' or '*'')
+		wildcard, i, valid := findWildcard(path)
+		if i < 0 {
+			break
 		}
-
-		// check if the wildcard has a name
-		if end-i < 2 {
-			panic("wildcards must be named with a non-empty name in path '" + fullPath + "'")
+		if !valid {
+			panic("only one wildcard per path segment is allowed, has: '" +
+				wildcard + "' in path '" + fullPath + "'")
 		}
+		key, match := parseWildcard(wildcard, fullPath)
 
-		if c == ':' { // param
+		if wildcard[0] == ':' { // param
 			// split path at the beginning of the wildcard
 			if i > 0 {
-				n.path = path[offset:i]
-				offset = i
+				n.path = path[:i]
+				path = path[i:]
 			}
 
 			child := &Node{
 				nType:     param,
+				path:      wildcard,
 				maxParams: numParams,
+				key:       key,
+				match:     match,
 			}
-			n.children = []*Node{child}
+			n.addChild(child)
 			n.wildChild = true
 			n = child
 			n.priority++
@@ -330,9 +376,8 @@ func (n *Node) insertChild(numParams uint8, path string, fullPath string, handle
 
 			// if the path doesn't end with the wildcard, then there
 			// will be another non-wildcard subpath starting with '/'
-			if end < max {
-				n.path = path[offset:end]
-				offset = end
+			if len(wildcard) < len(path) {
+				path = path[len(wildcard):]
 
 				child := &Node{
 					maxParams: numParams,
@@ -340,52 +385,57 @@ func (n *Node) insertChild(numParams uint8, path string, fullPath string, handle
 				}
 				n.children = []*Node{child}
 				n = child
+				continue
 			}
 
-		} else { // catchAll
-			if end != max || numParams > 1 {
-				panic("catch-all routes are only allowed at the end of the path in path '" + fullPath + "'")
-			}
-
-			if len(n.path) > 0 && n.path[len(n.path)-1] == '/' {
-				panic("catch-all conflicts with existing handle for the path segment root in path '" + fullPath + "'")
-			}
+			// Otherwise we're done. Insert the handle in the new leaf
+			n.handle = handle
+			return
+		}
 
-			// currently fixed width 1 for '/'
-			i--
-			if path[i] != '/' {
-				panic("no / before catch-all in path '" + fullPath + "'")
-			}
+		// catchAll
+		if i+len(wildcard) != len(path) || numParams > 1 {
+			panic("catch-all routes are only allowed at the end of the path in path '" + fullPath + "'")
+		}
 
-			n.path = path[offset:i]
+		if len(n.path) > 0 && n.path[len(n.path)-1] == '/' {
+			panic("catch-all conflicts with existing handle for the path segment root in path '" + fullPath + "'")
+		}
 
-			// first Node: catchAll Node with empty path
-			child := &Node{
-				wildChild: true,
-				nType:     catchAll,
-				maxParams: 1,
-			}
-			n.children = []*Node{child}
-			n.indices = string(path[i])
-			n = child
-			n.priority++
+		// currently fixed width 1 for '/'
+		i--
+		if path[i] != '/' {
+			panic("no / before catch-all in path '" + fullPath + "'")
+		}
 
-			// second Node: Node holding the variable
-			child = &Node{
-				path:      path[i:],
-				nType:     catchAll,
-				maxParams: 1,
-				handle:    handle,
-				priority:  1,
-			}
-			n.children = []*Node{child}
+		n.path = path[:i]
 
-			return
+		// first Node: catchAll Node with empty path
+		child := &Node{
+			wildChild: true,
+			nType:     catchAll,
+			maxParams: 1,
 		}
+		n.addChild(child)
+		n.indices = string('/')
+		n = child
+		n.priority++
+
+		// second Node: Node holding the variable
+		child = &Node{
+			path:      path[i:],
+			nType:     catchAll,
+			maxParams: 1,
+			handle:    handle,
+			priority:  1,
+			key:       key,
+		}
+		n.children = []*Node{child}
+		return
 	}
 
 	// insert remaining path part and handle to the leaf
-	n.path = path[offset:]
+	n.path = path
 	n.handle = handle
 }
 
@@ -394,126 +444,142 @@ func (n *Node) insertChild(numParams uint8, path string, fullPath string, handle
 // If no handle can be found, a TSR (trailing slash redirect) recommendation is
 // made if a handle exists with an extra (without the) trailing slash for the
 // given path.
+// Static children are tried before the wildcard child, the lookup backtracks
+// to the wildcard child if the static ones do not match.
 func (n *Node) GetHandle(path string, po Params) (handle interface{}, p Params, tsr bool) {
 	p = po
-walk: // Outer loop for walking the tree
-	for {
-		if len(path) > len(n.path) {
-			if path[:len(n.path)] == n.path {
-				path = path[len(n.path):]
-				// If this Node does not have a wildcard (param or catchAll)
-				// child,  we can just look up the next child Node and continue
-				// to walk down the tree
-				if !n.wildChild {
-					c := path[0]
-					for i := 0; i < len(n.indices); i++ {
-						if c == n.indices[i] {
-							n = n.children[i]
-							continue walk
-						}
+	if len(path) > len(n.path) {
+		if path[:len(n.path)] == n.path {
+			path = path[len(n.path):]
+
+			// Try the static child with the next byte first, backtrack to
+			// the wildcard child if nothing found.
+			c := path[0]
+			found := false
+			for i := 0; i < len(n.indices); i++ {
+				if c == n.indices[i] {
+					found = true
+					if handle, p, tsr = n.children[i].GetHandle(path, po); handle != nil {
+						return
 					}
+					break
+				}
+			}
 
+			// If this Node does not have a wildcard (param or catchAll)
+			// child, we can not go further.
+			if !n.wildChild {
+				if !found {
 					// Nothing found.
 					// We can recommend to redirect to the same URL without a
 					// trailing slash if a leaf exists for that path.
 					tsr = (path == "/" && n.handle != nil)
-					return
 				}
+				return
+			}
 
-				// handle wildcard child
-				n = n.children[0]
-				switch n.nType {
-				case param:
-					// find param end (either '/' or path end)
-					end := 0
-					for end < len(path) && path[end] != '/' {
-						end++
-					}
+			// handle wildcard child
+			p = po
+			child := n.children[len(n.children)-1]
+			switch child.nType {
+			case param:
+				// find param end (either '/' or path end)
+				end := 0
+				for end < len(path) && path[end] != '/' {
+					end++
+				}
 
-					// save param value
-					if cap(p) < int(n.maxParams) {
-						p = make(Params, 0, n.maxParams)
-					}
-					i := len(p)
-					p = p[:i+1] // expand slice within preallocated capacity
-					p[i].Key = n.path[1:]
-					p[i].Value = path[:end]
-
-					// we need to go deeper!
-					if end < len(path) {
-						if len(n.children) > 0 {
-							path = path[end:]
-							n = n.children[0]
-							continue walk
-						}
+				if child.match != nil && !child.match(path[:end]) {
+					return
+				}
 
-						// ... but we can't
-						tsr = (len(path) == end+1)
-						return
-					}
+				// save param value
+				p = appendParam(p, child, path[:end])
 
-					if handle = n.handle; handle != nil {
+				// we need to go deeper!
+				if end < len(path) {
+					if len(child.children) > 0 {
+						var childTsr bool
+						handle, p, childTsr = child.children[0].GetHandle(path[end:], p)
+						tsr = tsr || childTsr
 						return
 					}
-					if len(n.children) == 1 {
-						// No handle found. Check if a handle for this path + a
-						// trailing slash exists for TSR recommendation
-						n = n.children[0]
-						tsr = (n.path == "/" && n.handle != nil)
-					}
 
+					// ... but we can't
+					tsr = tsr || (len(path) == end+1)
 					return
+				}
 
-				case catchAll:
-					// save param value
-					if cap(p) < int(n.maxParams) {
-						p = make(Params, 0, n.maxParams)
-					}
-					i := len(p)
-					p = p[:i+1] // expand slice within preallocated capacity
-					p[i].Key = n.path[2:]
-					p[i].Value = path
-
-					handle = n.handle
+				if handle = child.handle; handle != nil {
+					tsr = false
 					return
-
-				default:
-					panic("invalid Node type")
 				}
-			}
-		} else if path == n.path {
-			// We should have reached the Node containing the handle.
-			// Check if this Node has a handle registered.
-			if handle = n.handle; handle != nil {
+				if len(child.children) == 1 {
+					// No handle found. Check if a handle for this path + a
+					// trailing slash exists for TSR recommendation
+					child = child.children[0]
+					tsr = tsr || (child.path == "/" && child.handle != nil)
+				}
 				return
-			}
 
-			if path == "/" && n.wildChild && n.nType != root {
-				tsr = true
+			case catchAll:
+				// save param value
+				p = appendParam(p, child, path)
+				handle = child.handle
+				tsr = false
 				return
-			}
 
-			// No handle found. Check if a handle for this path + a
-			// trailing slash exists for trailing slash recommendation
-			for i := 0; i < len(n.indices); i++ {
-				if n.indices[i] == '/' {
-					n = n.children[i]
-					tsr = (len(n.path) == 1 && n.handle != nil) ||
-						(n.nType == catchAll && n.children[0].handle != nil)
-					return
-				}
+			default:
+				panic("invalid Node type")
 			}
+		}
+	} else if path == n.path {
+		// We should have reached the Node containing the handle.
+		// Check if this Node has a handle registered.
+		if handle = n.handle; handle != nil {
+			return
+		}
 
+		if path == "/" && n.wildChild && n.nType != root {
+			tsr = true
 			return
 		}
 
-		// Nothing found. We can recommend to redirect to the same URL with an
-		// extra trailing slash if a leaf exists for that path
-		tsr = (path == "/") ||
-			(len(n.path) == len(path)+1 && n.path[len(path)] == '/' &&
-				path == n.path[:len(n.path)-1] && n.handle != nil)
+		// No handle found. Check if a handle for this path + a
+		// trailing slash exists for trailing slash recommendation
+		for i := 0; i < len(n.indices); i++ {
+			if n.indices[i] == '/' {
+				n = n.children[i]
+				tsr = (len(n.path) == 1 && n.handle != nil) ||
+					(n.nType == catchAll && n.children[0].handle != nil)
+				return
+			}
+		}
+
 		return
 	}
+
+	// Nothing found. We can recommend to redirect to the same URL with an
+	// extra trailing slash if a leaf exists for that path
+	tsr = (path == "/") ||
+		(len(n.path) == len(path)+1 && n.path[len(path)] == '/' &&
+			path == n.path[:len(n.path)-1] && n.handle != nil)
+	return
+}
+
+// appendParam appends the value of param Node n to p, p is reallocated only
+// if its capacity is not enough for the rest params.
+func appendParam(p Params, n *Node, value string) Params {
+	if cap(p) < len(p)+int(n.maxParams) {
+		np := make(Params, len(p), len(p)+int(n.maxParams))
+		copy(np, p)
+		p = np
+	}
+	i := len(p)
+	p = p[:i+1] // expand slice within preallocated capacity
+	p[i].Key = n.key
+	p[i].Value = value
+	return p
 }
 
 // FindCaseInsensitivePath makes a case-insensitive lookup of the given path and tries to find a handle.
@@ -529,29 +595,28 @@ func (n *Node) FindCaseInsensitivePath(path string, fixTrailingSlash bool) (ciPa
 		ciPath = append(ciPath, n.path...)
 
 		if len(path) > 0 {
-			// If this Node does not have a wildcard (param or catchAll) child,
-			// we can just look up the next child Node and continue to walk down
-			// the tree
-			if !n.wildChild {
-				r := unicode.ToLower(rune(path[0]))
-				for i, index := range n.indices {
-					// must use recursive approach since both index and
-					// ToLower(index) could exist. We must check both.
-					if r == unicode.ToLower(index) {
-						out, found := n.children[i].FindCaseInsensitivePath(path, fixTrailingSlash)
-						if found {
-							return append(ciPath, out...), true
-						}
+			// Try the static children first, must use recursive approach
+			// since both index and ToLower(index) could exist. We must check both.
+			r := unicode.ToLower(rune(path[0]))
+			for i, index := range n.indices {
+				if r == unicode.ToLower(index) {
+					out, found := n.children[i].FindCaseInsensitivePath(path, fixTrailingSlash)
+					if found {
+						return append(ciPath, out...), true
 					}
 				}
+			}
 
-				// Nothing found. We can recommend to redirect to the same URL
+			// If this Node does not have a wildcard (param or catchAll) child,
+			// nothing found.
+			if !n.wildChild {
+				// We can recommend to redirect to the same URL
 				// without a trailing slash if a leaf exists for that path
 				found = (fixTrailingSlash && path == "/" && n.handle != nil)
 				return
 			}
 
-			n = n.children[0]
+			n = n.children[len(n.children)-1]
 			switch n.nType {
 			case param:
 				// find param end (either '/' or path end)
@@ -560,6 +625,10 @@ func (n *Node) FindCaseInsensitivePath(path string, fixTrailingSlash bool) (ciPa
 					k++
 				}
 
+				if n.match != nil && !n.match(path[:k]) {
+					return
+				}
+
 				// add param value to case insensitive path
 				ciPath = append(ciPath, path[:k]...)
 
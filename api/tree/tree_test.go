@@ -49,7 +49,7 @@ func checkRequests(t *testing.T, tree *Node, requests testRequests, unescapes ..
 		}
 
 		if !reflect.DeepEqual(ps, request.ps) {
-			t.Errorf("Params mismatch for route '%s'", request.path, ps)
+			t.Errorf("Params mismatch for route '%s': %v", request.path, ps)
 		}
 	}
 }
@@ -191,6 +191,193 @@ func TestTreeWildcard(t *testing.T) {
 	checkMaxParams(t, tree)
 }
 
+func TestTreeStaticPriority(t *testing.T) {
+	tree := &Node{}
+
+	routes := [...]string{
+		"/user/new",
+		"/user/:id",
+		"/user/:id/profile",
+		"/user/newbie/profile",
+		"/user/list/all",
+		"/file/:name",
+		"/file/readme.md",
+		"/:page",
+		"/about",
+	}
+	for _, route := range routes {
+		tree.AddHandle(route, fakeHandler(route))
+	}
+
+	//printChildren(tree, "")
+
+	checkRequests(t, tree, testRequests{
+		{"/user/new", false, "/user/new", nil},
+		{"/user/gopher", false, "/user/:id", Params{Param{"id", "gopher"}}},
+		{"/user/ne", false, "/user/:id", Params{Param{"id", "ne"}}},
+		// backtrack to the param if the static segments do not match
+		{"/user/newbie", false, "/user/:id", Params{Param{"id", "newbie"}}},
+		{"/user/news", false, "/user/:id", Params{Param{"id", "news"}}},
+		{"/user/new/profile", false, "/user/:id/profile", Params{Param{"id", "new"}}},
+		{"/user/newbie/profile", false, "/user/newbie/profile", nil},
+		{"/user/list", false, "/user/:id", Params{Param{"id", "list"}}},
+		{"/user/list/profile", false, "/user/:id/profile", Params{Param{"id", "list"}}},
+		{"/user/list/all", false, "/user/list/all", nil},
+		{"/file/readme.md", false, "/file/readme.md", nil},
+		{"/file/readme", false, "/file/:name", Params{Param{"name", "readme"}}},
+		{"/about", false, "/about", nil},
+		{"/abc", false, "/:page", Params{Param{"page", "abc"}}},
+		{"/user", false, "/:page", Params{Param{"page", "user"}}},
+	})
+
+	checkPriorities(t, tree)
+	checkMaxParams(t, tree)
+}
+
+func TestTreeConstraint(t *testing.T) {
+	tree := &Node{}
+
+	routes := [...]string{
+		"/user/:id<int>",
+		"/user/:id<int>/posts/:pid<uint>",
+		"/user/me",
+		"/doc/:uuid<[0-9a-f]{24}>",
+		"/doc/:uuid<[0-9a-f]{24}>/:name<alpha>",
+		"/ver/:v<v[0-9]+(\\.[0-9]+)*>",
+		"/x/:y<[a-z/]+>",
+		"/id/:id<uuid>",
+	}
+	for _, route := range routes {
+		tree.AddHandle(route, fakeHandler(route))
+	}
+
+	//printChildren(tree, "")
+
+	checkRequests(t, tree, testRequests{
+		{"/user/12", false, "/user/:id<int>", Params{Param{"id", "12"}}},
+		{"/user/-12", false, "/user/:id<int>", Params{Param{"id", "-12"}}},
+		{"/user/me", false, "/user/me", nil},
+		{"/user/you", true, "", nil},
+		{"/user/12/posts/3", false, "/user/:id<int>/posts/:pid<uint>", Params{Param{"id", "12"}, Param{"pid", "3"}}},
+		{"/user/12/posts/-3", true, "", Params{Param{"id", "12"}}},
+		{"/doc/5d2ec3bbd1e8b4f2a1c0e9f7", false, "/doc/:uuid<[0-9a-f]{24}>", Params{Param{"uuid", "5d2ec3bbd1e8b4f2a1c0e9f7"}}},
+		{"/doc/5d2ec3bbd1e8b4f2a1c0e9f7/Go", false, "/doc/:uuid<[0-9a-f]{24}>/:name<alpha>", Params{Param{"uuid", "5d2ec3bbd1e8b4f2a1c0e9f7"}, Param{"name", "Go"}}},
+		{"/doc/5d2ec3bbd1e8b4f2a1c0e9f", true, "", nil},
+		{"/doc/5d2ec3bbd1e8b4f2a1c0e9f7a", true, "", nil},
+		{"/ver/v1.2.3", false, "/ver/:v<v[0-9]+(\\.[0-9]+)*>", Params{Param{"v", "v1.2.3"}}},
+		{"/ver/1.2", true, "", nil},
+		{"/x/abc", false, "/x/:y<[a-z/]+>", Params{Param{"y", "abc"}}},
+		{"/id/123e4567-e89b-12d3-a456-426614174000", false, "/id/:id<uuid>", Params{Param{"id", "123e4567-e89b-12d3-a456-426614174000"}}},
+		{"/id/123e4567-e89b-12d3-a456-42661417400z", true, "", nil},
+	})
+
+	checkPriorities(t, tree)
+	checkMaxParams(t, tree)
+
+	var paths []string
+	tree.Walk(func(path string, handle interface{}) bool {
+		paths = append(paths, path)
+		if path != handle.(string) {
+			t.Errorf("wrong path of walk: %s != %s", path, handle)
+		}
+		return true
+	})
+	if len(paths) != len(routes) {
+		t.Errorf("walk %d routes, expect %d", len(paths), len(routes))
+	}
+}
+
+func TestTreeInvalidConstraint(t *testing.T) {
+	routes := [...]string{
+		"/user/:id<int",
+		"/user/:id<[0-9>",
+		"/user/:<int>",
+		"/src/*filepath<int>",
+	}
+	for _, route := range routes {
+		tree := &Node{}
+		recv := catchPanic(func() {
+			tree.AddHandle(route, nil)
+		})
+		if recv == nil {
+			t.Errorf("no panic while inserting route with invalid constraint '%s'", route)
+		}
+	}
+}
+
+func TestSplitWildcard(t *testing.T) {
+	tests := []struct {
+		wildcard, name, constraint string
+	}{
+		{":id", "id", ""},
+		{":id<int>", "id", "int"},
+		{":uuid<[0-9a-f]{24}>", "uuid", "[0-9a-f]{24}"},
+		{"*filepath", "filepath", ""},
+	}
+	for _, test := range tests {
+		name, constraint := SplitWildcard(test.wildcard)
+		if name != test.name || constraint != test.constraint {
+			t.Errorf("SplitWildcard(%q) = %q, %q", test.wildcard, name, constraint)
+		}
+	}
+}
+
+func TestParamTypes(t *testing.T) {
+	tests := []struct {
+		typ   string
+		value string
+		ok    bool
+	}{
+		{"int", "123", true},
+		{"int", "-1", true},
+		{"int", "-", false},
+		{"int", "1a", false},
+		{"uint", "0", true},
+		{"uint", "-1", false},
+		{"uint", "", false},
+		{"alpha", "abcXYZ", true},
+		{"alpha", "ab1", false},
+		{"hex", "09afAF", true},
+		{"hex", "0x1", false},
+		{"uuid", "123E4567-e89b-12d3-a456-426614174000", true},
+		{"uuid", "123e4567e89b12d3a456426614174000", false},
+	}
+	for _, test := range tests {
+		if ParamTypes[test.typ](test.value) != test.ok {
+			t.Errorf("%s(%q) should be %t", test.typ, test.value, test.ok)
+		}
+	}
+}
+
+func TestReverse(t *testing.T) {
+	tests := []struct {
+		path   string
+		values []string
+		url    string
+		ok     bool
+	}{
+		{"/", nil, "/", true},
+		{"/user/:name", []string{"gopher"}, "/user/gopher", true},
+		{"/user/:name", []string{"go pher/1"}, "/user/go%20pher%2F1", true},
+		{"/user/:id<int>/files/*filepath", []string{"12", "/a b/c.txt"}, "/user/12/files/a%20b/c.txt", true},
+		{"/src/*filepath", []string{"a/b"}, "/src/a/b", true},
+		{"/src/*filepath", []string{""}, "/src/", true},
+		{"/doc/:uuid<[0-9a-f]{4}>/go", []string{"12ab"}, "/doc/12ab/go", true},
+		{"/doc/:uuid<[0-9a-f]{4}>/go", []string{"12abc"}, "", false},
+		{"/user/:id<int>", []string{"gopher"}, "", false},
+		{"/user/:name", []string{""}, "", false},
+		{"/user/:name", nil, "", false},
+		{"/user", []string{"gopher"}, "", false},
+		{"/user/:name/:id", []string{"gopher"}, "", false},
+	}
+	for _, test := range tests {
+		url, err := Reverse(test.path, test.values...)
+		if (err == nil) != test.ok || url != test.url {
+			t.Errorf("Reverse(%q, %q) = %q, %v", test.path, test.values, url, err)
+		}
+	}
+}
+
 func TestUnescapeParameters(t *testing.T) {
 	tree := &Node{}
 
@@ -266,20 +453,34 @@ func testRoutes(t *testing.T, routes []testRoute) {
 func TestTreeWildcardConflict(t *testing.T) {
 	routes := []testRoute{
 		{"/cmd/:tool/:sub", false},
-		{"/cmd/vet", true},
+		{"/cmd/vet", false},
+		{"/foo/bar", false},
+		{"/foo/:name", false},
+		{"/foo/:names", true},
+		{"/foo/:name<int>", true},
+		{"/cmd/*path", true},
+		{"/cmd/:badvar", true},
+		{"/cmd/:tool/names", false},
+		{"/cmd/:tool/:badsub/details", true},
 		{"/src/*filepath", false},
+		{"/src/:file", true},
+		{"/src/static.json", true},
 		{"/src/*filepathx", true},
 		{"/src/", true},
 		{"/src1/", false},
 		{"/src1/*filepath", true},
 		{"/src2*filepath", true},
+		{"/src2/*filepath", false},
 		{"/search/:query", false},
-		{"/search/invalid", true},
+		{"/search/valid", false},
 		{"/user_:name", false},
-		{"/user_x", true},
+		{"/user_x", false},
 		{"/user_:name", false},
 		{"/id:id", false},
-		{"/id/:id", true},
+		{"/id/:id", false},
+		{"/num/:id<int>", false},
+		{"/num/:id<int>/detail", false},
+		{"/num/:id<uint>/detail", true},
 	}
 	testRoutes(t, routes)
 }
@@ -287,14 +488,17 @@ func TestTreeWildcardConflict(t *testing.T) {
 func TestTreeChildConflict(t *testing.T) {
 	routes := []testRoute{
 		{"/cmd/vet", false},
-		{"/cmd/:tool/:sub", true},
+		{"/cmd/:tool", false},
+		{"/cmd/:tool/:sub", false},
+		{"/cmd/:tool/misc", false},
+		{"/cmd/:tool/:othersub", true},
 		{"/src/AUTHORS", false},
 		{"/src/*filepath", true},
 		{"/user_x", false},
-		{"/user_:name", true},
+		{"/user_:name", false},
 		{"/id/:id", false},
-		{"/id:id", true},
-		{"/:id", true},
+		{"/id:id", false},
+		{"/:id", false},
 		{"/*filepath", true},
 	}
 	testRoutes(t, routes)
@@ -357,6 +561,19 @@ func TestEmptyWildcardName(t *testing.T) {
 	}
 }
 
+// Static segments only coexist with params, not catch-alls.
+func TestTreeStaticCatchAllConflict(t *testing.T) {
+	testRoutes(t, []testRoute{
+		{"/files/*path", false},
+		{"/files/new", true},
+	})
+	testRoutes(t, []testRoute{
+		{"/files/new", false},
+		{"/files/*path", true},
+		{"/files/:name", false},
+	})
+}
+
 func TestTreeCatchAllConflict(t *testing.T) {
 	routes := []testRoute{
 		{"/src/*filepath/x", true},
@@ -708,6 +925,36 @@ func Benchmark_Trees(b *testing.B) {
 	}
 }
 
+func Benchmark_GetHandle(b *testing.B) {
+	tree := &Node{}
+	routes := [...]string{
+		"/user/new",
+		"/user/:id<int>",
+		"/user/:id<int>/posts/:pid",
+		"/doc/:uuid<[0-9a-f]{24}>",
+		"/src/*filepath",
+	}
+	for _, route := range routes {
+		tree.AddHandle(route, fakeHandler(route))
+	}
+	requests := [...]string{
+		"/user/new",
+		"/user/12",
+		"/user/12/posts/3",
+		"/doc/5d2ec3bbd1e8b4f2a1c0e9f7",
+		"/src/some/file.png",
+	}
+	ps := make(Params, 0, 2)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, path := range requests {
+			tree.GetHandle(path, ps)
+		}
+	}
+}
+
 func Benchmark_Map(b *testing.B) {
 	mp := map[string]int{
 		"GET":     0,
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+
+	"github.com/zltgo/api/tree"
+)
+
+// URL builds the path of the route named name, the params fill the
+// :param and *catchAll segments in order. For example:
+//	serv.GET("/user/:id<int>/files/*filepath", m.GetFile).Named("file")
+//	serv.URL("file", "12", "a/b.txt") // "/user/12/files/a/b.txt"
+// An error is returned if the name is not found, the number of params
+// mismatched or a param does not match its constraint.
+func (serv *Server) URL(name string, params ...string) (string, error) {
+	r, ok := serv.names[name]
+	if !ok {
+		return "", errors.New("api: route named '" + name + "' not found")
+	}
+	return tree.Reverse(r.Url, params...)
+}
+
+// FuncMap returns the template functions of serv, which should be added to the
+// templates of render.HTMLRender, such as HTMLDebug.FuncMap:
+//	url: calls URL, the params are formatted by fmt.Sprint, such as
+//	     <a href="{{url "file" .Uid .Path}}">
+func (serv *Server) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"url": func(name string, params ...interface{}) (string, error) {
+			ss := make([]string, len(params))
+			for i, p := range params {
+				ss[i] = fmt.Sprint(p)
+			}
+			return serv.URL(name, ss...)
+		},
+	}
+}
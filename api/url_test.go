@@ -0,0 +1,87 @@
+package api
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zltgo/api/render"
+)
+
+func TestURL(t *testing.T) {
+	router := New()
+	router.GET("/user/:id<int>", func(c *Context) {}).Named("user")
+	router.GET("/static/*filepath", func(c *Context) {}).Named("static")
+	admin := router.Group("/admin")
+	admin.POST("/user/:id/role/:role", func(c *Context) {}).Named("role")
+	router.GET("/about", func(c *Context) {})
+
+	url, err := router.URL("user", "42")
+	assert.NoError(t, err)
+	assert.Equal(t, "/user/42", url)
+
+	url, err = router.URL("static", "css/main.css")
+	assert.NoError(t, err)
+	assert.Equal(t, "/static/css/main.css", url)
+
+	url, err = router.URL("role", "42", "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, "/admin/user/42/role/admin", url)
+
+	_, err = router.URL("user", "manu")
+	assert.Error(t, err)
+	_, err = router.URL("user")
+	assert.Error(t, err)
+	_, err = router.URL("role", "42")
+	assert.Error(t, err)
+	_, err = router.URL("about")
+	assert.Error(t, err)
+
+	// names are kept by GetRoutes and AddRoutes
+	names := make(map[string]string)
+	for _, r := range router.GetRoutes() {
+		names[r.Url] = r.Name
+	}
+	assert.Equal(t, "user", names["/user/:id<int>"])
+	assert.Equal(t, "role", names["/admin/user/:id/role/:role"])
+	assert.Equal(t, "", names["/about"])
+
+	router2 := New()
+	router2.AddRoutes(router.GetRoutes())
+	url, err = router2.URL("role", "1", "guest")
+	assert.NoError(t, err)
+	assert.Equal(t, "/admin/user/1/role/guest", url)
+
+	// names are unique, except for the routes of the same url.
+	assert.Panics(t, func() { router.GET("/other", func(c *Context) {}).Named("user") })
+	router.HEAD("/user/:id<int>", func(c *Context) {}).Named("user")
+	router.ANY("/echo", func(c *Context) {}).Named("echo")
+	router3 := New()
+	assert.NotPanics(t, func() { router3.AddRoutes(router.GetRoutes()) })
+	url, err = router3.URL("echo")
+	assert.NoError(t, err)
+	assert.Equal(t, "/echo", url)
+}
+
+func TestURLFuncMap(t *testing.T) {
+	router := New()
+	router.GET("/user/:id<int>/files/*filepath", func(c *Context) {}).Named("file")
+	router.GET("/redirect", func(c *Context) {
+		url, _ := c.serv.URL("file", "7", "a.txt")
+		c.Reply(http.StatusFound, render.Redirect{Code: http.StatusFound, Request: c.Request, Location: url})
+	})
+
+	templ := template.Must(template.New("t").Funcs(router.FuncMap()).Parse(`<a href="{{url "file" .ID .Path}}">`))
+	var buf bytes.Buffer
+	assert.NoError(t, templ.Execute(&buf, map[string]interface{}{"ID": 12, "Path": "a b.txt"}))
+	assert.Equal(t, `<a href="/user/12/files/a%20b.txt">`, buf.String())
+
+	buf.Reset()
+	assert.Error(t, templ.Execute(&buf, map[string]interface{}{"ID": "manu", "Path": "a.txt"}))
+
+	w := performRequest(router, "GET", "/redirect")
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/user/7/files/a.txt", w.Header().Get("Location"))
+}
@@ -0,0 +1,294 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ugorji/go/codec"
+	"github.com/zltgo/reflectx"
+)
+
+// WSOptions configures Context.Upgrade, zero fields are set to default values.
+type WSOptions struct {
+	// Codec is the name of the WSCodecs encoding messages of Send and Receive.
+	// A subprotocol negotiated with the client is used instead if it is
+	// the name of a codec.
+	Codec string `default:"json"`
+
+	// Subprotocols are the server's supported protocols in order of preference.
+	Subprotocols []string
+
+	// ReadLimit is the maximum size in bytes of a message read from the peer,
+	// the connection is closed with 1009 if a message exceeds it.
+	ReadLimit int `default:"1048576"`
+
+	// PingPeriod is the interval in milliseconds of sending pings,
+	// PongWait is the timeout in milliseconds of reading a pong or any other
+	// message, it must be greater than PingPeriod. -1 disables keepalive.
+	PingPeriod int `default:"30000"`
+	PongWait   int `default:"60000"`
+
+	// WriteWait is the timeout in milliseconds of writing a message.
+	WriteWait int `default:"10000"`
+
+	// CheckOrigin returns true if the Origin header is acceptable, default
+	// rejects cross-origin requests, see websocket.Upgrader.
+	CheckOrigin func(r *http.Request) bool
+
+	EnableCompression bool
+}
+
+// WSCodec encodes and decodes the messages of WSConn.
+type WSCodec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	// MessageType returns websocket.TextMessage or websocket.BinaryMessage.
+	MessageType() int
+}
+
+// WSCodecs are the codecs of WSOptions.Codec by name.
+// Add codecs before serving, Context.Upgrade reads the map without locks.
+var WSCodecs = map[string]WSCodec{
+	"json":    jsonCodec{},
+	"msgpack": msgpackCodec{},
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonCodec) MessageType() int                           { return websocket.TextMessage }
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v interface{}) (b []byte, err error) {
+	err = codec.NewEncoderBytes(&b, new(codec.MsgpackHandle)).Encode(v)
+	return
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return codec.NewDecoderBytes(data, new(codec.MsgpackHandle)).Decode(v)
+}
+
+func (msgpackCodec) MessageType() int { return websocket.BinaryMessage }
+
+// Upgrade upgrades the request to a WebSocket connection, the pending handlers
+// are not called. The headers set by the previous middleware, including the
+// cookies saved by Defer functions such as sessions, are sent with the handshake.
+// If the upgrade fails, an HTTP error has been replied to the client.
+// The connection is closed with 1001 (going away) when the server is shutting
+// down, for example:
+//	serv.GET("/ws", provider.SessionHandler, func(ctx *api.Context) {
+//		conn, err := ctx.Upgrade(api.WSOptions{})
+//		if err != nil {
+//			return
+//		}
+//		defer conn.Close(websocket.CloseNormalClosure, "")
+//		for {
+//			var msg Message
+//			if err := conn.Receive(&msg); err != nil {
+//				return
+//			}
+//			conn.Send(reply(msg))
+//		}
+//	})
+func (ctx *Context) Upgrade(opts WSOptions) (*WSConn, error) {
+	reflectx.SetDefault(&opts)
+	codec, ok := WSCodecs[opts.Codec]
+	if !ok {
+		panic("api: websocket codec '" + opts.Codec + "' not found")
+	}
+	ctx.index = abortIndex
+
+	// the headers of Defer functions are written with the handshake.
+	ctx.writermem.runDefers()
+	up := websocket.Upgrader{
+		Subprotocols:      opts.Subprotocols,
+		CheckOrigin:       opts.CheckOrigin,
+		EnableCompression: opts.EnableCompression,
+	}
+	conn, err := up.Upgrade(ctx.Writer, ctx.Request, ctx.Writer.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	if c, ok := WSCodecs[conn.Subprotocol()]; ok {
+		codec = c
+	}
+	c := &WSConn{
+		conn:      conn,
+		codec:     codec,
+		serv:      ctx.serv,
+		writeWait: time.Duration(opts.WriteWait) * time.Millisecond,
+		done:      make(chan struct{}),
+	}
+	conn.SetReadLimit(int64(opts.ReadLimit))
+	if opts.PingPeriod > 0 && opts.PongWait > 0 {
+		pongWait := time.Duration(opts.PongWait) * time.Millisecond
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(pongWait))
+		})
+		go c.keepalive(time.Duration(opts.PingPeriod) * time.Millisecond)
+	}
+
+	if c.serv != nil && !c.serv.trackWS(c, true) {
+		c.Close(websocket.CloseGoingAway, "server shutting down")
+		return nil, http.ErrServerClosed
+	}
+	return c, nil
+}
+
+var ErrWSClosed = errors.New("api: websocket connection is closed")
+
+// WSConn is a WebSocket connection created by Context.Upgrade.
+// Send and Close can be called concurrently, Receive must be called by
+// one goroutine.
+type WSConn struct {
+	conn      *websocket.Conn
+	codec     WSCodec
+	serv      *Server
+	writeWait time.Duration
+
+	wmu       sync.Mutex
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// Send writes v as a message encoded by the codec.
+func (c *WSConn) Send(v interface{}) error {
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(c.codec.MessageType(), data)
+}
+
+// Receive reads the next message and decodes it into v by the codec.
+// The connection is closed if reading fails, websocket.IsCloseError reports
+// whether the peer closed it.
+func (c *WSConn) Receive(v interface{}) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return c.codec.Unmarshal(data, v)
+}
+
+// WriteMessage writes a raw message of websocket.TextMessage or websocket.BinaryMessage.
+func (c *WSConn) WriteMessage(messageType int, data []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	select {
+	case <-c.done:
+		return ErrWSClosed
+	default:
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(c.writeWait))
+	return c.conn.WriteMessage(messageType, data)
+}
+
+// ReadMessage reads a raw message, the connection is closed if it fails.
+func (c *WSConn) ReadMessage() (messageType int, data []byte, err error) {
+	messageType, data, err = c.conn.ReadMessage()
+	if err != nil {
+		c.close()
+	}
+	return
+}
+
+// Subprotocol returns the subprotocol negotiated with the client.
+func (c *WSConn) Subprotocol() string {
+	return c.conn.Subprotocol()
+}
+
+// Conn returns the underlying connection, writing it directly is not
+// synchronized with Send.
+func (c *WSConn) Conn() *websocket.Conn {
+	return c.conn
+}
+
+// Done returns a channel that is closed when the connection is closed.
+func (c *WSConn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close sends a close message of code and text to the peer and closes the
+// connection, code is usually websocket.CloseNormalClosure.
+func (c *WSConn) Close(code int, text string) error {
+	err := c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, text), time.Now().Add(c.writeWait))
+	if e := c.close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// close closes the underlying connection and stops tracking it.
+func (c *WSConn) close() (err error) {
+	err = ErrWSClosed
+	c.closeOnce.Do(func() {
+		close(c.done)
+		err = c.conn.Close()
+		if c.serv != nil {
+			c.serv.trackWS(c, false)
+		}
+	})
+	return
+}
+
+// keepalive sends pings until the connection is closed.
+func (c *WSConn) keepalive(period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			// WriteControl can be called concurrently with WriteMessage.
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.writeWait)); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+// trackWS adds or removes c, it returns false if the server is shutting down.
+func (serv *Server) trackWS(c *WSConn, add bool) bool {
+	serv.mu.Lock()
+	defer serv.mu.Unlock()
+	if !add {
+		delete(serv.wsConns, c)
+		return true
+	}
+	select {
+	case <-serv.done:
+		return false
+	default:
+	}
+	if serv.wsConns == nil {
+		serv.wsConns = make(map[*WSConn]struct{})
+	}
+	serv.wsConns[c] = struct{}{}
+	return true
+}
+
+// closeWS closes all the WebSocket connections with 1001 (going away).
+func (serv *Server) closeWS() {
+	serv.mu.Lock()
+	conns := make([]*WSConn, 0, len(serv.wsConns))
+	for c := range serv.wsConns {
+		conns = append(conns, c)
+	}
+	serv.mu.Unlock()
+
+	for _, c := range conns {
+		c.Close(websocket.CloseGoingAway, "server shutting down")
+	}
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+type wsMessage struct {
+	Name  string
+	Count int
+}
+
+func echoWS(opts WSOptions) Handler {
+	return func(ctx *Context) {
+		conn, err := ctx.Upgrade(opts)
+		if err != nil {
+			return
+		}
+		defer conn.Close(websocket.CloseNormalClosure, "")
+		for {
+			var msg wsMessage
+			if err := conn.Receive(&msg); err != nil {
+				return
+			}
+			msg.Count++
+			if conn.Send(msg) != nil {
+				return
+			}
+		}
+	}
+}
+
+func dialWS(t *testing.T, ts *httptest.Server, path string, subprotocols ...string) (*websocket.Conn, *http.Response) {
+	dialer := websocket.Dialer{Subprotocols: subprotocols}
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+path, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return conn, resp
+}
+
+func TestUpgrade(t *testing.T) {
+	var after int32
+	serv := New()
+	serv.GET("/ws", func(ctx *Context) {
+		ctx.Writer.Header().Set("X-Test", "1")
+		ctx.Writer.Defer(func() {
+			http.SetCookie(ctx.Writer, &http.Cookie{Name: "sid", Value: "abc"})
+		})
+	}, echoWS(WSOptions{Subprotocols: []string{"msgpack"}}), func(ctx *Context) {
+		atomic.AddInt32(&after, 1)
+	})
+	ts := httptest.NewServer(serv)
+	defer ts.Close()
+
+	// json
+	conn, resp := dialWS(t, ts, "/ws")
+	assert.Equal(t, "1", resp.Header.Get("X-Test"))
+	assert.Equal(t, "sid=abc", resp.Header.Get("Set-Cookie"))
+	assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"Name":"a","Count":1}`)))
+	mt, data, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, websocket.TextMessage, mt)
+	assert.Equal(t, `{"Name":"a","Count":2}`, string(data))
+	conn.Close()
+
+	// msgpack negotiated by subprotocol
+	conn, _ = dialWS(t, ts, "/ws", "msgpack")
+	assert.Equal(t, "msgpack", conn.Subprotocol())
+	data, err = msgpackCodec{}.Marshal(wsMessage{"b", 5})
+	assert.NoError(t, err)
+	assert.NoError(t, conn.WriteMessage(websocket.BinaryMessage, data))
+	mt, data, err = conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, websocket.BinaryMessage, mt)
+	var msg wsMessage
+	assert.NoError(t, msgpackCodec{}.Unmarshal(data, &msg))
+	assert.Equal(t, wsMessage{"b", 6}, msg)
+
+	// graceful close
+	assert.NoError(t, conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye")))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure))
+	conn.Close()
+
+	// not a websocket request
+	resp, err = http.Get(ts.URL + "/ws")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&after))
+}
+
+func TestUpgradeReadLimit(t *testing.T) {
+	serv := New()
+	serv.GET("/ws", echoWS(WSOptions{ReadLimit: 16}))
+	ts := httptest.NewServer(serv)
+	defer ts.Close()
+
+	conn, _ := dialWS(t, ts, "/ws")
+	defer conn.Close()
+	assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"Name":"too long message"}`)))
+	_, _, err := conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseMessageTooBig), "%v", err)
+}
+
+func TestUpgradeKeepalive(t *testing.T) {
+	serv := New()
+	serv.GET("/ws", echoWS(WSOptions{PingPeriod: 10, PongWait: 50}))
+	ts := httptest.NewServer(serv)
+	defer ts.Close()
+
+	// pings are answered with pongs while reading.
+	conn, _ := dialWS(t, ts, "/ws")
+	var pings int32
+	conn.SetPingHandler(func(data string) error {
+		atomic.AddInt32(&pings, 1)
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	time.Sleep(120 * time.Millisecond)
+	assert.True(t, atomic.LoadInt32(&pings) >= 3)
+	assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{}`)))
+	conn.Close()
+
+	// the connection is closed without pongs.
+	conn, _ = dialWS(t, ts, "/ws")
+	defer conn.Close()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 0, countWS(serv))
+}
+
+func TestUpgradeShutdown(t *testing.T) {
+	serv := New()
+	closed := make(chan struct{}, 2)
+	serv.GET("/ws", func(ctx *Context) {
+		echoWS(WSOptions{})(ctx)
+		closed <- struct{}{}
+	})
+	ts := httptest.NewServer(serv)
+	defer ts.Close()
+
+	conn, _ := dialWS(t, ts, "/ws")
+	defer conn.Close()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 1, countWS(serv))
+
+	assert.NoError(t, serv.Shutdown(context.Background()))
+	_, _, err := conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "%v", err)
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("handler should return after shutdown")
+	}
+	assert.Equal(t, 0, countWS(serv))
+
+	// upgrade is refused after shutdown.
+	conn, _ = dialWS(t, ts, "/ws")
+	defer conn.Close()
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "%v", err)
+}
+
+func countWS(serv *Server) int {
+	serv.mu.Lock()
+	defer serv.mu.Unlock()
+	return len(serv.wsConns)
+}
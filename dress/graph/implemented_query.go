@@ -74,6 +74,12 @@ func (r *Resolver) GetAuthInfo(ctx context.Context, opName string) (m *model.Mod
 	return
 }
 
+// Create a token of af, the issued time is checked against the
+// revocations of deleted accounts and users.
+func newAuthToken(c *gin.Context, au *jwt.Auth, af *model.AuthInfo) (*jwt.AuthToken, error) {
+	af.IssuedAt = jwt.TimeNow()
+	return au.NewAuthToken(c.Request, af)
+}
 
 func (r *queryResolver) AdminToken(ctx context.Context, name string, password string) (*jwt.AuthToken, error) {
 	c, m, au, err := r.GetCtx(ctx, "Admin")
@@ -92,7 +98,7 @@ func (r *queryResolver) AdminToken(ctx context.Context, name string, password st
 	}
 
 	var tk *jwt.AuthToken
-	if tk, err = au.NewAuthToken(c.Request, vs); err != nil {
+	if tk, err = newAuthToken(c, au, vs); err != nil {
 		return nil, gm.NewError(http.StatusInternalServerError, err)
 	}
 	return tk, nil
@@ -130,7 +136,7 @@ func (r *queryResolver) AuthToken(ctx context.Context, input model.AuthForm) (*j
 	}
 
 	var tk *jwt.AuthToken
-	if tk, err = au.NewAuthToken(c.Request, vs); err != nil {
+	if tk, err = newAuthToken(c, au, vs); err != nil {
 		return nil, gm.NewError(http.StatusInternalServerError, err)
 	}
 	return tk, nil
@@ -159,9 +165,11 @@ func (r *queryResolver) RefreshToken(ctx context.Context) (*jwt.AuthToken, error
 		return nil, gm.NewError(code, err, message)
 	}
 
-	var tk *jwt.AuthToken
-	if tk, err = au.NewAuthToken(c.Request, vs); err != nil {
-		return nil, gm.NewError(http.StatusInternalServerError, err)
+	// the tokens refreshed are revoked with the login by Logout.
+	vs.IssuedAt = jwt.TimeNow()
+	tk, err := au.RefreshAuthToken(c.Request, vs)
+	if err != nil {
+		return nil, gm.NewError(http.StatusUnauthorized, err)
 	}
 	return tk, nil
 }
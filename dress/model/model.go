@@ -2,6 +2,7 @@ package model
 
 import (
 	"github.com/jinzhu/gorm"
+	apijwt "github.com/zltgo/api/jwt"
 	_ "github.com/jinzhu/gorm/dialects/sqlite"
 	"github.com/zltgo/reflectx"
 	"github.com/zltgo/webkit/cache"
@@ -70,6 +71,7 @@ type Model struct {
 	limitUser  *lru.Cache  //cache for rate limit user
 	ipRates   ratelimit.RateMap
 	userRates  ratelimit.RateMap
+	revoker   apijwt.Revoker //revoked tokens of deleted accounts and users
 }
 
 type Options struct {
@@ -109,6 +111,20 @@ func NewModel(opts Options) (*Model, error) {
 		userRates[k] = ratelimit.SecOpts(v...)
 	}
 
+	//the placeholder of postgres is $1, $2
+	placeholder := "?"
+	if opts.Driver == "postgres" {
+		placeholder = "$"
+	}
+	revoker, err := apijwt.NewSQLRevoker(db.DB(), apijwt.SQLRevokerOpts{
+		CreateTable: true,
+		Placeholder: placeholder,
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	return &Model{
 		validator: ginx.NewValidator(),
 		db:        db,
@@ -118,6 +134,7 @@ func NewModel(opts Options) (*Model, error) {
 		limitUser:lru.New(opts.UserCacheEntries),
 		ipRates: ipRates,
 		userRates:userRates,
+		revoker: revoker,
 	}, nil
 }
 
@@ -201,6 +218,13 @@ func (m *Model) CheckPermission(af *AuthInfo, clientIp, OpName string) (int, err
 		}
 	}
 
+	//tokens of deleted accounts and users
+	if af != nil {
+		if code, err := m.CheckRevoked(af); err != nil {
+			return code, err
+		}
+	}
+
 	//rate limit of user
 	if af != nil {
 		limiters := m.limitUser.Getsert(af.UserId, func()interface{}{
@@ -215,6 +239,25 @@ func (m *Model) CheckPermission(af *AuthInfo, clientIp, OpName string) (int, err
 	return http.StatusOK, nil
 }
 
+// CheckRevoked checks whether the account or user of af is deleted
+// after the token issued.
+// errors: 401,500
+func (m *Model) CheckRevoked(af *AuthInfo) (int, error) {
+	for _, id := range []gm.UUID{af.AccountID, af.UserId} {
+		if !id.IsValid() {
+			continue
+		}
+		revoked, err := m.revoker.Revoked(id.String(), af.IssuedAt)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if revoked {
+			return http.StatusUnauthorized, apijwt.ErrRevoked
+		}
+	}
+	return http.StatusOK, nil
+}
+
 //登录表单
 type AuthForm struct {
 	AccountID gm.UUID `json:"accountID" validate:"hexadecimal,len=24"`
@@ -230,6 +273,7 @@ type AuthInfo struct {
 	PasswordHash string    `json:",omitempty"`
 	Role         string    `json:",omitempty"`
 	ExpiresAt    time.Time `json:",omitempty"`
+	IssuedAt     time.Time `json:",omitempty"` //issued time of the token
 }
 
 // Account查询条件
@@ -97,6 +97,9 @@ func (m *Model) RefreshToken(af *AuthInfo) (*AuthInfo, int, error) {
 	}
 
 	//user
+	if code, err := m.CheckRevoked(af); err != nil {
+		return nil, code, err
+	}
 	var user *User
 	if err := m.GetFromCacheOrDB(af.UserId, &user); err != nil {
 		return nil, gm.ErrorCode(err), err
@@ -593,6 +596,11 @@ func (m *Model) DeleteAccount(af *AuthInfo, id gm.UUID) (code int, err error) {
 		return gm.ErrorCode(err), err
 	}
 
+	//revoke tokens of the account
+	if err := m.revoker.RevokeUser(id.String(), jwt.TimeNow()); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
 	//delete old value from cache
 	m.cacheDB.Remove(id)
 	return http.StatusOK, nil
@@ -612,6 +620,11 @@ func (m *Model) DeleteUser(af *AuthInfo, id gm.UUID) (code int, err error) {
 		return gm.ErrorCode(err), err
 	}
 
+	//revoke tokens of the user
+	if err := m.revoker.RevokeUser(id.String(), jwt.TimeNow()); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
 	//delete old value from cache
 	m.cacheDB.Remove(id)
 	return http.StatusOK, nil
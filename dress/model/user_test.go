@@ -918,6 +918,39 @@ func TestDeleteAndReuse(t *testing.T) {
 	})
 }
 
+func TestRevokeDeleted(t *testing.T) {
+	id, _, err := m.GetAccountIdByMobile("15987653275")
+	require.NoError(t, err)
+	defer m.ReuseAccount(af, id)
+
+	info, code, err := m.Login(&AuthForm{
+		AccountID: id,
+		Empno:     "000",
+		Password:  "000000000",
+	})
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	info.IssuedAt = time.Now().Add(-time.Second)
+	code, err = m.CheckRevoked(info)
+	require.NoError(t, err)
+
+	//tokens issued before deleting are revoked
+	_, err = m.DeleteAccount(af, id)
+	require.NoError(t, err)
+	code, err = m.CheckPermission(info, "", "GetAccountByID")
+	require.Equal(t, http.StatusUnauthorized, code)
+	require.Error(t, err)
+	_, code, err = m.RefreshToken(info)
+	require.Equal(t, http.StatusUnauthorized, code)
+	require.Error(t, err)
+
+	//tokens issued after deleting are not revoked
+	info.IssuedAt = time.Now().Add(time.Second)
+	code, err = m.CheckRevoked(info)
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+}
+
 func TestSearchAccounts(t *testing.T) {
 	t.Run("first and count", func(t *testing.T) {
 		cnt := 0
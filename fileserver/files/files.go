@@ -14,6 +14,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/zltgo/api/render"
 	"github.com/zltgo/archive"
 )
 
@@ -250,7 +251,7 @@ func (m *RootFiles) Download(w http.ResponseWriter, r *http.Request, path string
 	}
 	//设置http头为下载
 	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Header().Set("Content-Disposition", "attachment; filename="+fi.Name())
+	w.Header().Set("Content-Disposition", render.ContentDisposition("attachment", fi.Name()))
 
 	//不能用这个接口，会将/api/file/index.html重定向到/api/file/
 	//http.ServeFile(w, r, filepath.Join(m.rootPath, path))
@@ -288,7 +289,7 @@ func (m *RootFiles) GetArchive(w http.ResponseWriter, r *http.Request, path stri
 	//设置http头为下载
 	w.Header().Set("Last-Modified", time.Unix(fi.ModTime, 0).UTC().Format(http.TimeFormat))
 	w.Header().Set("Content-Type", "application/zip; charset=utf-8")
-	w.Header().Set("Content-Disposition", "attachment; filename="+archiveName)
+	w.Header().Set("Content-Disposition", render.ContentDisposition("attachment", archiveName))
 
 	//下载文件
 	//http.ServeFile(w, r, tmpDir)
@@ -277,6 +277,10 @@ func Login(se *session.Session, w http.ResponseWriter, r *http.Request, gf *Logi
 		err = fmt.Errorf("数据库Update失败，%s，数据详细信息：%v", err.Error(), tmp)
 	}
 
+	//登录后更换session id，防止会话固定攻击
+	if e := se.Regenerate(); e != nil {
+		return 500, e
+	}
 	se.Remove("LoginErrorTimes")
 	se.Remove("PwdErrorTimes")
 	se.Set("Uid", tmp.Uid)
@@ -1,8 +1,14 @@
 package jwt
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
+	apijwt "github.com/zltgo/api/jwt"
 	"github.com/zltgo/reflectx"
 )
 
@@ -17,6 +23,10 @@ type Auth struct {
 	// refresh is used to create a RefreshToken.
 	// Optional, default is NewParser(0, nil, nil).
 	refresh Token
+
+	// revoker stores the revoked tokens, tokens can not be revoked if nil.
+	// Optional, see SetRevoker.
+	revoker apijwt.Revoker
 }
 
 type AuthOpts struct {
@@ -59,12 +69,39 @@ type AuthToken struct {
 // Create a new auth token.
 // usrInfo can be any type you want stored in token.
 func (m *Auth) NewAuthToken(r *http.Request, meta interface{}) (*AuthToken, error) {
-	at, err := m.access.EncodeValues(r, meta)
+	var fid string
+	if m.revoker != nil {
+		// the tokens of a login are in the same family.
+		fid = RandString(16)
+	}
+	return m.newAuthToken(r, meta, fid)
+}
+
+// RefreshAuthToken creates a new auth token of meta by the refresh token in
+// http.Request, the new tokens are in the same family as the refresh token,
+// so they are revoked by Logout with any token of the login.
+func (m *Auth) RefreshAuthToken(r *http.Request, meta interface{}) (*AuthToken, error) {
+	var discard json.RawMessage
+	tk, tv, err := m.refresh.decodeValues(r, &discard)
 	if err != nil {
 		return nil, err
 	}
+	if err = m.checkRevoked(tk, tv.FamilyId); err != nil {
+		return nil, err
+	}
+	if tv.FamilyId == "" && m.revoker != nil {
+		tv.FamilyId = RandString(16)
+	}
+	return m.newAuthToken(r, meta, tv.FamilyId)
+}
 
-	rt, err := m.refresh.EncodeValues(r, meta)
+func (m *Auth) newAuthToken(r *http.Request, meta interface{}, fid string) (*AuthToken, error) {
+	at, err := m.access.encodeValues(r, meta, fid)
+	if err != nil {
+		return nil, err
+	}
+
+	rt, err := m.refresh.encodeValues(r, meta, fid)
 	if err != nil {
 		return nil, err
 	}
@@ -78,10 +115,87 @@ func (m *Auth) NewAuthToken(r *http.Request, meta interface{}) (*AuthToken, erro
 
 // Get values from token in http.Request.
 func (m *Auth) GetAccessInfo(r *http.Request, pMeta interface{}) error {
-	return m.access.DecodeValues(r, pMeta)
+	tk, tv, err := m.access.decodeValues(r, pMeta)
+	if err != nil {
+		return err
+	}
+	return m.checkRevoked(tk, tv.FamilyId)
 }
 
 // Get values from token in http.Request.
 func (m *Auth) GetRefreshInfo(r *http.Request, pMeta interface{}) error {
-	return m.refresh.DecodeValues(r, pMeta)
+	tk, tv, err := m.refresh.decodeValues(r, pMeta)
+	if err != nil {
+		return err
+	}
+	return m.checkRevoked(tk, tv.FamilyId)
+}
+
+// SetRevoker enables the revocation of tokens by rv, the tokens are revoked
+// by their sha256 hash, as they have no id, and by the family id shared by
+// the tokens of a login.
+// Both the access and refresh tokens must have a MaxAge, or the records of
+// the revoked tokens can never be purged.
+// Not thread-safe, set it before the tokens are created or checked.
+func (m *Auth) SetRevoker(rv apijwt.Revoker) {
+	if m.access.MaxAge() <= 0 || m.refresh.MaxAge() <= 0 {
+		panic("jwt: tokens must have a MaxAge to be revoked")
+	}
+	m.revoker = rv
+}
+
+// Logout revokes the access token and the refresh token in http.Request and
+// all the tokens of the same login, so the access token alone is enough.
+// The absent or invalid tokens are ignored.
+func (m *Auth) Logout(r *http.Request) error {
+	if m.revoker == nil {
+		return errors.New("jwt: revoker is not set")
+	}
+	// the tokens of the family are created before now, they expire in the
+	// MaxAge of the refresh token at most.
+	maxAge := m.refresh.MaxAge()
+	if m.access.MaxAge() > maxAge {
+		maxAge = m.access.MaxAge()
+	}
+	familyExp := TimeNow().Add(time.Duration(maxAge) * time.Second)
+
+	for _, t := range []Token{m.access, m.refresh} {
+		var discard json.RawMessage
+		tk, tv, err := t.decodeValues(r, &discard)
+		if err != nil {
+			continue
+		}
+		// the token expires in MaxAge at most.
+		exp := TimeNow().Add(time.Duration(t.MaxAge()) * time.Second)
+		if _, err = m.revoker.RevokeToken(tokenId(tk), exp); err != nil {
+			return err
+		}
+		if tv.FamilyId != "" {
+			if _, err = m.revoker.RevokeToken(tv.FamilyId, familyExp); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkRevoked checks the token string tk and its family id.
+func (m *Auth) checkRevoked(tk, fid string) error {
+	if m.revoker == nil {
+		return nil
+	}
+	revoked, err := m.revoker.Revoked("", time.Time{}, tokenId(tk), fid)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return apijwt.ErrRevoked
+	}
+	return nil
+}
+
+// tokenId returns the sha256 hash of tk.
+func tokenId(tk string) string {
+	sum := sha256.Sum256([]byte(tk))
+	return hex.EncodeToString(sum[:])
 }
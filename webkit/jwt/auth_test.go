@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
+	apijwt "github.com/zltgo/api/jwt"
 )
 
 type sub struct {
@@ -86,3 +87,53 @@ func TestAuth(t *testing.T) {
 		})
 	})
 }
+
+func TestAuthLogout(t *testing.T) {
+	TimeNow = timeFunc(1000, 1000)
+	au := NewAuth(AuthOpts{RefreshMaxAge: 86400})
+	au.SetRevoker(apijwt.NewMemRevoker())
+
+	Convey("revoked tokens are rejected", t, func() {
+		r, _ := http.NewRequest("GET", "/auth/myuid", nil)
+		tk, err := au.NewAuthToken(r, usr{Id: 1})
+		So(err, ShouldBeNil)
+		other, err := au.NewAuthToken(r, usr{Id: 2})
+		So(err, ShouldBeNil)
+
+		r.Header.Set("ACCESS-TOKEN", tk.AccessToken)
+		r.Header.Set("REFRESH-TOKEN", tk.RefreshToken)
+		output := usr{}
+		So(au.GetAccessInfo(r, &output), ShouldBeNil)
+		So(au.Logout(r), ShouldBeNil)
+		So(au.GetAccessInfo(r, &output), ShouldEqual, apijwt.ErrRevoked)
+		So(au.GetRefreshInfo(r, &output), ShouldEqual, apijwt.ErrRevoked)
+
+		r.Header.Set("ACCESS-TOKEN", other.AccessToken)
+		So(au.GetAccessInfo(r, &output), ShouldBeNil)
+	})
+
+	Convey("logout with the access token revokes the refresh token", t, func() {
+		r, _ := http.NewRequest("GET", "/auth/myuid", nil)
+		tk, err := au.NewAuthToken(r, usr{Id: 1})
+		So(err, ShouldBeNil)
+
+		// tokens refreshed are in the same family.
+		r.Header.Set("REFRESH-TOKEN", tk.RefreshToken)
+		next, err := au.RefreshAuthToken(r, usr{Id: 1})
+		So(err, ShouldBeNil)
+
+		r.Header.Del("REFRESH-TOKEN")
+		r.Header.Set("ACCESS-TOKEN", tk.AccessToken)
+		So(au.Logout(r), ShouldBeNil)
+
+		output := usr{}
+		r.Header.Del("ACCESS-TOKEN")
+		r.Header.Set("REFRESH-TOKEN", tk.RefreshToken)
+		So(au.GetRefreshInfo(r, &output), ShouldEqual, apijwt.ErrRevoked)
+		_, err = au.RefreshAuthToken(r, usr{Id: 1})
+		So(err, ShouldEqual, apijwt.ErrRevoked)
+
+		r.Header.Set("ACCESS-TOKEN", next.AccessToken)
+		So(au.GetAccessInfo(r, &output), ShouldEqual, apijwt.ErrRevoked)
+	})
+}
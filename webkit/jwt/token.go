@@ -42,12 +42,21 @@ type Token struct {
 type TokenValue struct {
 	AgentHash string `json:",omitempty"`
 	GrantType string `json:",omitempty"`
-	Metadata  interface{}
+	// FamilyId is shared by the access and refresh tokens of a login,
+	// it is set if Auth has a revoker.
+	FamilyId string `json:",omitempty"`
+	Metadata interface{}
 }
 
 // Get values stored in token.
 // pMeta supposed to have point type.
 func (t Token) DecodeValues(r *http.Request, pMeta interface{}) error {
+	_, _, err := t.decodeValues(r, pMeta)
+	return err
+}
+
+// decodeValues returns the token string and the values of the token in r.
+func (t Token) decodeValues(r *http.Request, pMeta interface{}) (string, TokenValue, error) {
 	if reflect.TypeOf(pMeta).Kind() != reflect.Ptr {
 		panic("input parameter supposed to have point type")
 	}
@@ -55,30 +64,35 @@ func (t Token) DecodeValues(r *http.Request, pMeta interface{}) error {
 	//get token string
 	token, err := t.GetToken(r)
 	if err != nil {
-		return err
+		return "", TokenValue{}, err
 	}
 	//parse token
 	tv := TokenValue{Metadata: pMeta}
 	if err = t.ParseToken(token, &tv); err != nil {
-		return err
+		return "", TokenValue{}, err
 	}
 
 	// validate grant type and user agent
 	if tv.GrantType != t.GrantType {
-		return fmt.Errorf("jwt: grant type mismatched: expected %s, got %s", t.GrantType, tv.GrantType)
+		return "", TokenValue{}, fmt.Errorf("jwt: grant type mismatched: expected %s, got %s", t.GrantType, tv.GrantType)
 	}
 	if tv.AgentHash != Hash64(r.UserAgent()) {
-		return fmt.Errorf("jwt: user agent mismatched: %s", r.UserAgent())
+		return "", TokenValue{}, fmt.Errorf("jwt: user agent mismatched: %s", r.UserAgent())
 	}
 
-	return nil
+	return token, tv, nil
 }
 
 // Set values to token string.
 func (t Token) EncodeValues(r *http.Request, meta interface{}) (string, error) {
+	return t.encodeValues(r, meta, "")
+}
+
+func (t Token) encodeValues(r *http.Request, meta interface{}, fid string) (string, error) {
 	return t.CreateToken(&TokenValue{
 		AgentHash: Hash64(r.UserAgent()),
 		GrantType: t.GrantType,
+		FamilyId:  fid,
 		Metadata:  meta,
 	})
 }